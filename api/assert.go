@@ -0,0 +1,408 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/oneclickvirt/ecs/internal/analysis"
+)
+
+// AssertionResult records one -assert expression evaluated against the
+// structured report. Actual lists every value the expression selected.
+type AssertionResult struct {
+	Expression string `json:"expression"`
+	Passed     bool   `json:"passed"`
+	Actual     string `json:"actual,omitempty"`
+	Reason     string `json:"reason,omitempty"`
+}
+
+func (result AssertionResult) String() string {
+	switch {
+	case result.Passed:
+		return fmt.Sprintf("PASS %s (actual %s)", result.Expression, result.Actual)
+	case result.Actual != "":
+		return fmt.Sprintf("FAIL %s (actual %s)", result.Expression, result.Actual)
+	default:
+		return fmt.Sprintf("FAIL %s: %s", result.Expression, result.Reason)
+	}
+}
+
+// assertionPresets are the expressions added by -assert-preset. baseline
+// holds the averages below which the post-test summary flags memory and
+// disk: about 10 GB/s memory bandwidth and 10 MB/s 4K disk reads.
+var assertionPresets = map[string][]string{
+	"baseline": {"memorytest.bandwidth_mbps>=10240", "disktest.read_mbps>=10"},
+}
+
+// assertionItemLists are the payload arrays a selector applied directly to
+// a component searches, so unlocktests.media[netflix] selects a result and
+// disktest[4k-q1-read] a scenario.
+var assertionItemLists = []string{"results", "metrics", "benchmarks", "private_benchmarks", "paths"}
+
+// assertionDerivedMetrics adds fields computed from a component payload the
+// way the post-test summary computes them.
+var assertionDerivedMetrics = map[string]map[string]func(json.RawMessage) (float64, bool){
+	"memorytest": {"bandwidth_mbps": memoryBandwidthMbps},
+	"disktest":   {"read_mbps": diskReadMbps, "randread_4k_iops": diskRandRead4KIOPS},
+}
+
+type assertionStep struct {
+	key      string
+	selector bool
+}
+
+type assertion struct {
+	expression string
+	steps      []assertionStep
+	operator   string
+	expected   string
+}
+
+// ValidateAssertions parses the -assert expressions of config so a typo is
+// rejected before any benchmark starts.
+func ValidateAssertions(config *Config) error {
+	if config == nil {
+		return nil
+	}
+	for _, expression := range assertionExpressions(config) {
+		if _, err := parseAssertion(expression); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// EvaluateAssertions evaluates expressions against report. An expression
+// is "path op value": path names a component, or tcp for the TCP targets,
+// followed by payload fields; [key] selects the items whose id, name or
+// scenario_id is key, and every selected item must satisfy the comparison.
+// Items with a status compare it when the path ends at the item. Numbers
+// support =, !=, <, <=, > and >=; text and booleans only = and !=, with
+// text compared case-insensitively.
+func EvaluateAssertions(report *StructuredReport, expressions []string) []AssertionResult {
+	if len(expressions) == 0 {
+		return nil
+	}
+	results := make([]AssertionResult, 0, len(expressions))
+	for _, expression := range expressions {
+		result := AssertionResult{Expression: strings.TrimSpace(expression)}
+		parsed, err := parseAssertion(expression)
+		if err == nil {
+			result.Actual, result.Passed, err = parsed.evaluate(report)
+		}
+		if err != nil {
+			result.Reason = err.Error()
+		}
+		results = append(results, result)
+	}
+	return results
+}
+
+// assertionExpressions returns the preset expressions followed by -assert.
+func assertionExpressions(config *Config) []string {
+	expressions := append([]string(nil), assertionPresets[config.AssertPreset]...)
+	return append(expressions, config.Assertions...)
+}
+
+func configAssertionResults(config *Config, report *StructuredReport) []AssertionResult {
+	return EvaluateAssertions(report, assertionExpressions(config))
+}
+
+// failedAssertions counts the assertions of report that did not hold.
+func failedAssertions(report *StructuredReport) int {
+	failed := 0
+	for _, result := range report.Assertions {
+		if !result.Passed {
+			failed++
+		}
+	}
+	return failed
+}
+
+func parseAssertion(expression string) (assertion, error) {
+	parsed := assertion{expression: strings.TrimSpace(expression)}
+	index, depth := -1, 0
+	for position, char := range parsed.expression {
+		switch {
+		case char == '[':
+			depth++
+		case char == ']':
+			depth--
+		case depth == 0 && strings.ContainsRune("<>=!", char):
+			index = position
+		}
+		if index >= 0 {
+			break
+		}
+	}
+	if index <= 0 {
+		return parsed, fmt.Errorf("assert %q: want path, operator and value", parsed.expression)
+	}
+	parsed.operator = parsed.expression[index : index+1]
+	if next := index + 1; next < len(parsed.expression) && parsed.expression[next] == '=' {
+		parsed.operator += "="
+	}
+	switch parsed.operator {
+	case "=", "==", "!=", "<", "<=", ">", ">=":
+	default:
+		return parsed, fmt.Errorf("assert %q: unknown operator %q", parsed.expression, parsed.operator)
+	}
+	parsed.expected = strings.TrimSpace(parsed.expression[index+len(parsed.operator):])
+	if parsed.expected == "" {
+		return parsed, fmt.Errorf("assert %q: missing value", parsed.expression)
+	}
+	if strings.ContainsAny(parsed.expected[:1], "<>=!") {
+		return parsed, fmt.Errorf("assert %q: unknown operator %q", parsed.expression, parsed.operator+parsed.expected[:1])
+	}
+	steps, err := parseAssertionPath(strings.TrimSpace(parsed.expression[:index]))
+	if err != nil {
+		return parsed, fmt.Errorf("assert %q: %w", parsed.expression, err)
+	}
+	parsed.steps = steps
+	return parsed, nil
+}
+
+func parseAssertionPath(path string) ([]assertionStep, error) {
+	var steps []assertionStep
+	for path != "" {
+		if strings.HasPrefix(path, "[") {
+			end := strings.IndexByte(path, ']')
+			key := ""
+			if end > 0 {
+				key = strings.TrimSpace(path[1:end])
+			}
+			if key == "" {
+				return nil, errors.New("selectors must be a non-empty [key]")
+			}
+			steps = append(steps, assertionStep{key: key, selector: true})
+			path = strings.TrimPrefix(path[end+1:], ".")
+			continue
+		}
+		end := strings.IndexAny(path, ".[")
+		if end < 0 {
+			end = len(path)
+		}
+		key := strings.TrimSpace(path[:end])
+		if key == "" || strings.ContainsAny(key, "]") {
+			return nil, errors.New("path must be dot-separated field names")
+		}
+		steps = append(steps, assertionStep{key: key})
+		path = path[end:]
+		if strings.HasPrefix(path, ".") {
+			path = path[1:]
+			if path == "" {
+				return nil, errors.New("path ends with a dot")
+			}
+		}
+	}
+	if len(steps) == 0 || steps[0].selector {
+		return nil, errors.New("path must start with a component name")
+	}
+	return steps, nil
+}
+
+func (parsed assertion) evaluate(report *StructuredReport) (string, bool, error) {
+	if report == nil {
+		return "", false, errors.New("no report")
+	}
+	name, root, steps, err := assertionSubject(report, parsed.steps)
+	if err != nil {
+		return "", false, err
+	}
+	values := []any{root}
+	for index, step := range steps {
+		next := make([]any, 0, len(values))
+		for _, value := range values {
+			selected, err := assertionSelect(name, value, step, index == 0)
+			if err != nil {
+				return "", false, err
+			}
+			next = append(next, selected...)
+		}
+		if len(next) == 0 {
+			return "", false, fmt.Errorf("no item of %s matches [%s]", name, step.key)
+		}
+		values = next
+	}
+	actual := make([]string, 0, len(values))
+	passed := true
+	for _, value := range values {
+		if item, ok := value.(map[string]any); ok {
+			if status, ok := item["status"].(string); ok {
+				value = status
+			}
+		}
+		text, holds, err := parsed.compare(value)
+		if err != nil {
+			return "", false, err
+		}
+		actual = append(actual, text)
+		passed = passed && holds
+	}
+	return strings.Join(actual, ", "), passed, nil
+}
+
+// assertionSubject resolves the longest leading path that names a
+// component of report, or tcp, and returns its decoded payload.
+func assertionSubject(report *StructuredReport, steps []assertionStep) (string, any, []assertionStep, error) {
+	components := componentsByName(report.Components)
+	keys := 0
+	for keys < len(steps) && !steps[keys].selector {
+		keys++
+	}
+	for length := keys; length > 0; length-- {
+		names := make([]string, length)
+		for index := range names {
+			names[index] = steps[index].key
+		}
+		name := strings.Join(names, ".")
+		if name == "tcp" {
+			if len(report.TCP) == 0 {
+				return "", nil, nil, errors.New("the report has no TCP results")
+			}
+			encoded, _ := json.Marshal(report.TCP)
+			var root any
+			_ = json.Unmarshal(encoded, &root)
+			return name, root, steps[length:], nil
+		}
+		component, exists := components[name]
+		if !exists {
+			continue
+		}
+		var root any
+		if len(component.Payload) == 0 || json.Unmarshal(component.Payload, &root) != nil || root == nil {
+			return "", nil, nil, fmt.Errorf("component %s has no results (%s)", name, joinNonEmpty(string(component.Status), component.Reason))
+		}
+		return name, root, steps[length:], nil
+	}
+	return "", nil, nil, fmt.Errorf("component %s is not in the report", steps[0].key)
+}
+
+func assertionSelect(component string, value any, step assertionStep, top bool) ([]any, error) {
+	if !step.selector {
+		object, ok := value.(map[string]any)
+		if !ok {
+			return nil, fmt.Errorf("%s: select an item with [key] before .%s", component, step.key)
+		}
+		if field, exists := object[step.key]; exists {
+			return []any{field}, nil
+		}
+		if derive := assertionDerivedMetrics[component][step.key]; top && derive != nil {
+			encoded, _ := json.Marshal(object)
+			if metric, ok := derive(encoded); ok {
+				return []any{metric}, nil
+			}
+		}
+		return nil, fmt.Errorf("%s has no field %q", component, step.key)
+	}
+	var items []any
+	switch typed := value.(type) {
+	case []any:
+		items = typed
+	case map[string]any:
+		for _, list := range assertionItemLists {
+			items = append(items, arrayValue(typed, list)...)
+		}
+	default:
+		return nil, fmt.Errorf("%s: [%s] needs a list of items", component, step.key)
+	}
+	selected := make([]any, 0, 1)
+	for _, item := range items {
+		if assertionItemMatches(item, step.key) {
+			selected = append(selected, item)
+		}
+	}
+	return selected, nil
+}
+
+func assertionItemMatches(item any, key string) bool {
+	object, ok := item.(map[string]any)
+	if !ok {
+		return false
+	}
+	for _, candidate := range []map[string]any{object, objectValue(object, "target")} {
+		for _, field := range []string{"id", "name", "scenario_id"} {
+			if value := stringValue(candidate, field); value != "" && strings.EqualFold(value, key) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func (parsed assertion) compare(value any) (string, bool, error) {
+	switch actual := value.(type) {
+	case float64:
+		expected, err := strconv.ParseFloat(parsed.expected, 64)
+		if err != nil {
+			return "", false, fmt.Errorf("%q is not a number", parsed.expected)
+		}
+		text := strconv.FormatFloat(actual, 'f', -1, 64)
+		switch parsed.operator {
+		case "<":
+			return text, actual < expected, nil
+		case "<=":
+			return text, actual <= expected, nil
+		case ">":
+			return text, actual > expected, nil
+		case ">=":
+			return text, actual >= expected, nil
+		case "!=":
+			return text, actual != expected, nil
+		default:
+			return text, actual == expected, nil
+		}
+	case string, bool:
+		text := fmt.Sprint(actual)
+		switch parsed.operator {
+		case "=", "==":
+			return text, strings.EqualFold(text, parsed.expected), nil
+		case "!=":
+			return text, !strings.EqualFold(text, parsed.expected), nil
+		default:
+			return "", false, fmt.Errorf("%q is not a number and only supports = and !=", text)
+		}
+	case nil:
+		return "", false, errors.New("the selected value is empty")
+	default:
+		return "", false, errors.New("the path selects an object or list; name one of its fields")
+	}
+}
+
+// memoryBandwidthMbps derives bandwidth_mbps the way the summary does.
+func memoryBandwidthMbps(payload json.RawMessage) (float64, bool) {
+	bandwidth := analysis.MemoryBandwidthMbps(payload)
+	return bandwidth, bandwidth > 0
+}
+
+// diskRandRead4KIOPS is the best IOPS of the 4K random read scenarios.
+func diskRandRead4KIOPS(payload json.RawMessage) (float64, bool) {
+	var matrix struct {
+		Metrics []analysis.DiskMetric `json:"metrics"`
+	}
+	if json.Unmarshal(payload, &matrix) != nil {
+		return 0, false
+	}
+	var iops float64
+	for _, metric := range matrix.Metrics {
+		if metric.Direction == "read" && strings.HasPrefix(strings.ToLower(metric.ScenarioID), "4k") {
+			iops = max(iops, metric.IOPS)
+		}
+	}
+	return iops, iops > 0
+}
+
+// diskReadMbps derives read_mbps the way the summary does.
+func diskReadMbps(payload json.RawMessage) (float64, bool) {
+	var matrix struct {
+		Metrics []analysis.DiskMetric `json:"metrics"`
+	}
+	if json.Unmarshal(payload, &matrix) != nil {
+		return 0, false
+	}
+	mbps := analysis.DiskReadMbps(matrix.Metrics)
+	return mbps, mbps > 0
+}
@@ -0,0 +1,115 @@
+package api
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func assertionReport(t *testing.T) *StructuredReport {
+	t.Helper()
+	payload := func(value any) json.RawMessage {
+		encoded, err := json.Marshal(value)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return encoded
+	}
+	return &StructuredReport{
+		Components: []ComponentReport{
+			{Name: "memorytest", Status: ReportStatusOK, Payload: payload(map[string]any{"sequential_read_mbps": 36000, "copy_mbps": 18000})},
+			{Name: "disktest", Status: ReportStatusOK, Payload: payload(map[string]any{"metrics": []map[string]any{
+				{"scenario_id": "4k-q1-read", "direction": "read", "iops": 6000, "bandwidth_bytes_per_second": 24 << 20},
+				{"scenario_id": "1m-q8-read", "direction": "read", "iops": 900, "bandwidth_bytes_per_second": 900 << 20},
+			}})},
+			{Name: "unlocktests.media", Status: ReportStatusOK, Payload: payload(map[string]any{"results": []map[string]any{
+				{"name": "Netflix", "status": "Yes", "ip_version": "ipv4"},
+				{"name": "Netflix", "status": "No", "ip_version": "ipv6"},
+				{"name": "YouTube", "status": "Yes", "ip_version": "ipv4"},
+			}})},
+			{Name: "speed.registry", Status: ReportStatusUnavailable, Reason: "no servers"},
+		},
+		TCP: []TCPReport{{Target: TCPTarget{ID: "github", Name: "GitHub"}, P95MS: 120.5, LossPercent: 0}},
+	}
+}
+
+func TestEvaluateAssertionsAgainstComponentPayloads(t *testing.T) {
+	report := assertionReport(t)
+	cases := []struct {
+		expression string
+		passed     bool
+		actual     string
+		reason     string
+	}{
+		{"memorytest.bandwidth_mbps>=10240", true, "36000", ""},
+		{"memorytest.copy_mbps > 20000", false, "18000", ""},
+		{"disktest.read_mbps>=10", true, "24", ""},
+		{"disktest.randread_4k_iops>=5000", true, "6000", ""},
+		{"disktest[4k-q1-read].iops>=5000", true, "6000", ""},
+		{"disktest.metrics[1M-Q8-READ].iops<500", false, "900", ""},
+		{"tcp[github].p95_ms<150", true, "120.5", ""},
+		{"tcp[GitHub].loss_percent=0", true, "0", ""},
+		{"unlocktests.media[youtube]=yes", true, "Yes", ""},
+		{"unlocktests.media[netflix]=yes", false, "Yes, No", ""},
+		{"unlocktests.media[hulu]=yes", false, "", "no item of unlocktests.media matches [hulu]"},
+		{"cputest.events_per_second>1", false, "", "component cputest is not in the report"},
+		{"speed.registry[a].download_mbps>1", false, "", "component speed.registry has no results (unavailable / no servers)"},
+		{"memorytest.random_latency_ns<100", false, "", `memorytest has no field "random_latency_ns"`},
+		{"unlocktests.media[youtube]>1", false, "", `"Yes" is not a number and only supports = and !=`},
+		{"memorytest.copy_mbps>=fast", false, "", `"fast" is not a number`},
+	}
+	results := EvaluateAssertions(report, func() []string {
+		expressions := make([]string, len(cases))
+		for index, tc := range cases {
+			expressions[index] = tc.expression
+		}
+		return expressions
+	}())
+	for index, tc := range cases {
+		result := results[index]
+		if result.Passed != tc.passed || result.Actual != tc.actual || result.Reason != tc.reason {
+			t.Errorf("%s = %#v", tc.expression, result)
+		}
+	}
+	if text := results[1].String(); text != "FAIL memorytest.copy_mbps > 20000 (actual 18000)" {
+		t.Fatalf("unexpected text: %q", text)
+	}
+}
+
+func TestValidateAssertionsRejectsMalformedExpressions(t *testing.T) {
+	for _, expression := range []string{"memorytest.copy_mbps", "memorytest.copy_mbps>=", ">=10", "tcp[].p95_ms<1", "memorytest..copy_mbps>1", "[x].y=1", "a=>1"} {
+		config := NewDefaultConfig()
+		config.Assertions = []string{expression}
+		if err := ValidateAssertions(config); err == nil || !strings.Contains(err.Error(), "assert") {
+			t.Errorf("%q was accepted: %v", expression, err)
+		}
+	}
+	config := NewDefaultConfig()
+	config.AssertPreset, config.Assertions = "baseline", []string{"tcp[github].p95_ms<150"}
+	if err := ValidateAssertions(config); err != nil {
+		t.Fatal(err)
+	}
+	if expressions := assertionExpressions(config); len(expressions) != 3 || expressions[0] != "memorytest.bandwidth_mbps>=10240" {
+		t.Fatalf("unexpected expressions: %v", expressions)
+	}
+}
+
+func TestFailedAssertionSetsExitCode(t *testing.T) {
+	config := NewDefaultConfig()
+	config.AssertPreset = "baseline"
+	report := assertionReport(t)
+	report.Status = ReportStatusOK
+	report.Assertions = configAssertionResults(config, report)
+	result := &RunResult{Report: report}
+	if code := RunExitCode(config, result, nil); code != ExitCodeOK {
+		t.Fatalf("passing baseline exited %d: %#v", code, report.Assertions)
+	}
+	report.Assertions = append(report.Assertions, AssertionResult{Expression: "tcp[github].p95_ms<100"})
+	if code := RunExitCode(config, result, nil); code != ExitCodeAssertion {
+		t.Fatalf("failed assertion exited %d", code)
+	}
+	report.Status = ReportStatusTimeout
+	if code := RunExitCode(config, result, nil); code != ExitCodeTimeout {
+		t.Fatalf("timed-out run with failed assertion exited %d", code)
+	}
+}
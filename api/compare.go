@@ -0,0 +1,487 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"net"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const ComparisonReportSchema = "goecs.compare/v1"
+
+// ComparedRun identifies one side of a comparison without repeating the
+// complete structured report.
+type ComparedRun struct {
+	ECSVersion string       `json:"ecs_version"`
+	Status     ReportStatus `json:"status"`
+	StartedAt  time.Time    `json:"started_at"`
+	FinishedAt time.Time    `json:"finished_at"`
+	DeepMode   bool         `json:"deep_mode"`
+}
+
+// MetricDelta is one numeric measurement present in either report. Presence
+// is "both", "baseline_only" or "current_only"; deltas are only meaningful
+// when both sides carry the metric.
+type MetricDelta struct {
+	Metric         string  `json:"metric"`
+	Target         string  `json:"target,omitempty"`
+	Unit           string  `json:"unit,omitempty"`
+	Baseline       float64 `json:"baseline"`
+	Current        float64 `json:"current"`
+	Delta          float64 `json:"delta"`
+	DeltaPercent   float64 `json:"delta_percent"`
+	HigherIsBetter bool    `json:"higher_is_better"`
+	Presence       string  `json:"presence"`
+}
+
+type ComponentComparison struct {
+	Name           string        `json:"name"`
+	BaselineStatus ReportStatus  `json:"baseline_status,omitempty"`
+	CurrentStatus  ReportStatus  `json:"current_status,omitempty"`
+	Metrics        []MetricDelta `json:"metrics,omitempty"`
+}
+
+type TCPDelta struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+	// Endpoint is the host:port of the target, preferring the current run.
+	// It tells apart targets that share an ID and is empty when redacted.
+	Endpoint            string  `json:"endpoint,omitempty"`
+	Presence            string  `json:"presence"`
+	BaselineP50MS       float64 `json:"baseline_p50_ms"`
+	CurrentP50MS        float64 `json:"current_p50_ms"`
+	P50DeltaMS          float64 `json:"p50_delta_ms"`
+	BaselineP95MS       float64 `json:"baseline_p95_ms"`
+	CurrentP95MS        float64 `json:"current_p95_ms"`
+	P95DeltaMS          float64 `json:"p95_delta_ms"`
+	BaselineLossPercent float64 `json:"baseline_loss_percent"`
+	CurrentLossPercent  float64 `json:"current_loss_percent"`
+}
+
+// MediaFlip records a platform whose unlock status differs between runs.
+type MediaFlip struct {
+	Platform  string `json:"platform"`
+	IPVersion string `json:"ip_version,omitempty"`
+	Baseline  string `json:"baseline"`
+	Current   string `json:"current"`
+}
+
+type ComparisonReport struct {
+	SchemaVersion string                `json:"schema_version"`
+	Baseline      ComparedRun           `json:"baseline"`
+	Current       ComparedRun           `json:"current"`
+	Components    []ComponentComparison `json:"components"`
+	TCP           []TCPDelta            `json:"tcp,omitempty"`
+	Media         []MediaFlip           `json:"media,omitempty"`
+}
+
+func (report *ComparisonReport) JSON() ([]byte, error) {
+	return json.MarshalIndent(report, "", "  ")
+}
+
+// LoadStructuredReport reads a report previously written with -json and
+// rejects files produced by an unknown schema generation.
+func LoadStructuredReport(path string) (*StructuredReport, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return DecodeStructuredReport(data)
+}
+
+func DecodeStructuredReport(data []byte) (*StructuredReport, error) {
+	var report StructuredReport
+	if err := json.Unmarshal(data, &report); err != nil {
+		return nil, fmt.Errorf("decode structured report: %w", err)
+	}
+	if report.SchemaVersion != StructuredReportSchema {
+		return nil, fmt.Errorf("unsupported structured report schema %q", report.SchemaVersion)
+	}
+	return &report, nil
+}
+
+// CompareReports produces per-component deltas between a saved baseline and
+// a current report. Only measurements are compared; host identity, text and
+// data snapshot versions are intentionally ignored.
+func CompareReports(baseline, current *StructuredReport) (*ComparisonReport, error) {
+	if baseline == nil || current == nil {
+		return nil, errors.New("baseline and current reports are required")
+	}
+	result := &ComparisonReport{
+		SchemaVersion: ComparisonReportSchema,
+		Baseline:      comparedRun(baseline), Current: comparedRun(current),
+		Components: make([]ComponentComparison, 0),
+	}
+	baselineComponents := componentsByName(baseline.Components)
+	currentComponents := componentsByName(current.Components)
+	for _, name := range comparedComponentNames {
+		oldComponent, oldExists := baselineComponents[name]
+		newComponent, newExists := currentComponents[name]
+		if !oldExists && !newExists {
+			continue
+		}
+		comparison := ComponentComparison{Name: name}
+		if oldExists {
+			comparison.BaselineStatus = oldComponent.Status
+		}
+		if newExists {
+			comparison.CurrentStatus = newComponent.Status
+		}
+		comparison.Metrics = diffMetrics(componentMetrics(oldComponent), componentMetrics(newComponent))
+		result.Components = append(result.Components, comparison)
+	}
+	result.TCP = diffTCPReports(baseline.TCP, current.TCP)
+	result.Media = diffMediaResults(baselineComponents["unlocktests.media"], currentComponents["unlocktests.media"])
+	return result, nil
+}
+
+// comparedComponentNames fixes the output order so two comparisons of the
+// same pair of reports are byte-identical.
+var comparedComponentNames = []string{"cputest", "memorytest", "disktest", "speed.registry"}
+
+type comparedMetric struct {
+	metric         string
+	target         string
+	unit           string
+	value          float64
+	higherIsBetter bool
+}
+
+func comparedRun(report *StructuredReport) ComparedRun {
+	return ComparedRun{
+		ECSVersion: report.ECSVersion, Status: report.Status,
+		StartedAt: report.StartedAt, FinishedAt: report.FinishedAt, DeepMode: report.DeepMode,
+	}
+}
+
+func componentsByName(components []ComponentReport) map[string]ComponentReport {
+	result := make(map[string]ComponentReport, len(components))
+	for _, component := range components {
+		if _, exists := result[component.Name]; !exists {
+			result[component.Name] = component
+		}
+	}
+	return result
+}
+
+func componentMetrics(component ComponentReport) []comparedMetric {
+	if len(component.Payload) == 0 {
+		return nil
+	}
+	root := payloadObject(component.Payload)
+	switch component.Name {
+	case "cputest":
+		return []comparedMetric{{metric: "events_per_second", unit: "events/s", value: floatValue(root, "events_per_second"), higherIsBetter: true}}
+	case "memorytest":
+		return []comparedMetric{
+			{metric: "sequential_read_mbps", unit: "MiB/s", value: floatValue(root, "sequential_read_mbps"), higherIsBetter: true},
+			{metric: "sequential_write_mbps", unit: "MiB/s", value: floatValue(root, "sequential_write_mbps"), higherIsBetter: true},
+			{metric: "copy_mbps", unit: "MiB/s", value: floatValue(root, "copy_mbps"), higherIsBetter: true},
+			{metric: "random_latency_ns", unit: "ns", value: floatValue(root, "random_latency_ns")},
+		}
+	case "disktest":
+		metrics := arrayValue(root, "metrics")
+		result := make([]comparedMetric, 0, len(metrics)*2)
+		for _, raw := range metrics {
+			metric, _ := raw.(map[string]any)
+			scenario := stringValue(metric, "scenario_id")
+			if scenario == "" {
+				continue
+			}
+			result = append(result,
+				comparedMetric{metric: "iops", target: scenario, value: floatValue(metric, "iops"), higherIsBetter: true},
+				comparedMetric{metric: "bandwidth_bytes_per_second", target: scenario, unit: "B/s", value: floatValue(metric, "bandwidth_bytes_per_second"), higherIsBetter: true},
+			)
+		}
+		return result
+	case "speed.registry":
+		benchmarks := append(arrayValue(root, "benchmarks"), arrayValue(root, "private_benchmarks")...)
+		result := make([]comparedMetric, 0, len(benchmarks)*3)
+		for _, raw := range benchmarks {
+			benchmark, _ := raw.(map[string]any)
+			target := fallback(stringValue(benchmark, "id"), stringValue(benchmark, "name"))
+			if source := stringValue(benchmark, "source"); source != "" {
+				target = source + ":" + target
+			}
+			result = append(result,
+				comparedMetric{metric: "download_mbps", target: target, unit: "Mbps", value: floatValue(benchmark, "download_mbps"), higherIsBetter: true},
+				comparedMetric{metric: "upload_mbps", target: target, unit: "Mbps", value: floatValue(benchmark, "upload_mbps"), higherIsBetter: true},
+				comparedMetric{metric: "latency_ms", target: target, unit: "ms", value: floatValue(benchmark, "latency_ms")},
+			)
+		}
+		return result
+	}
+	return nil
+}
+
+func diffMetrics(baseline, current []comparedMetric) []MetricDelta {
+	key := func(metric comparedMetric) string { return metric.target + "\x00" + metric.metric }
+	currentByKey := make(map[string]comparedMetric, len(current))
+	for _, metric := range current {
+		currentByKey[key(metric)] = metric
+	}
+	result := make([]MetricDelta, 0, max(len(baseline), len(current)))
+	seen := make(map[string]struct{}, len(baseline))
+	for _, oldMetric := range baseline {
+		seen[key(oldMetric)] = struct{}{}
+		delta := MetricDelta{
+			Metric: oldMetric.metric, Target: oldMetric.target, Unit: oldMetric.unit,
+			Baseline: oldMetric.value, HigherIsBetter: oldMetric.higherIsBetter, Presence: "baseline_only",
+		}
+		if newMetric, exists := currentByKey[key(oldMetric)]; exists {
+			delta.Current, delta.Presence = newMetric.value, "both"
+			delta.Delta = newMetric.value - oldMetric.value
+			delta.DeltaPercent = percentChange(oldMetric.value, newMetric.value)
+		}
+		result = append(result, delta)
+	}
+	for _, newMetric := range current {
+		if _, exists := seen[key(newMetric)]; exists {
+			continue
+		}
+		result = append(result, MetricDelta{
+			Metric: newMetric.metric, Target: newMetric.target, Unit: newMetric.unit,
+			Current: newMetric.value, HigherIsBetter: newMetric.higherIsBetter, Presence: "current_only",
+		})
+	}
+	return result
+}
+
+// diffTCPReports pairs targets by ID. The endpoint only tells apart reports
+// that share an ID: those pair by matching host:port first, and the rest pair
+// up in report order when an endpoint is unknown. A target whose host changed,
+// or a baseline saved in privacy mode, still pairs with its current report.
+func diffTCPReports(baseline, current []TCPReport) []TCPDelta {
+	if len(baseline) == 0 && len(current) == 0 {
+		return nil
+	}
+	pairs := pairTCPReports(baseline, current)
+	matched := make([]bool, len(current))
+	result := make([]TCPDelta, 0, max(len(baseline), len(current)))
+	for index, oldReport := range baseline {
+		delta := TCPDelta{
+			ID: oldReport.Target.ID, Name: oldReport.Target.Name, Endpoint: tcpTargetEndpoint(oldReport.Target), Presence: "baseline_only",
+			BaselineP50MS: oldReport.P50MS, BaselineP95MS: oldReport.P95MS, BaselineLossPercent: oldReport.LossPercent,
+		}
+		if match := pairs[index]; match >= 0 {
+			matched[match] = true
+			newReport := current[match]
+			if endpoint := tcpTargetEndpoint(newReport.Target); endpoint != "" {
+				delta.Endpoint = endpoint
+			}
+			delta.Presence = "both"
+			delta.CurrentP50MS, delta.CurrentP95MS, delta.CurrentLossPercent = newReport.P50MS, newReport.P95MS, newReport.LossPercent
+			delta.P50DeltaMS = newReport.P50MS - oldReport.P50MS
+			delta.P95DeltaMS = newReport.P95MS - oldReport.P95MS
+		}
+		result = append(result, delta)
+	}
+	for index, newReport := range current {
+		if matched[index] {
+			continue
+		}
+		result = append(result, TCPDelta{
+			ID: newReport.Target.ID, Name: newReport.Target.Name, Endpoint: tcpTargetEndpoint(newReport.Target), Presence: "current_only",
+			CurrentP50MS: newReport.P50MS, CurrentP95MS: newReport.P95MS, CurrentLossPercent: newReport.LossPercent,
+		})
+	}
+	sort.SliceStable(result, func(i, j int) bool {
+		if result[i].ID != result[j].ID {
+			return result[i].ID < result[j].ID
+		}
+		return result[i].Endpoint < result[j].Endpoint
+	})
+	return result
+}
+
+// pairTCPReports returns the index of the current report paired with each
+// baseline report, or -1. Two reports with known, different endpoints pair
+// only when their ID is unique on both sides.
+func pairTCPReports(baseline, current []TCPReport) []int {
+	baselineIDs := make(map[string]int, len(baseline))
+	for _, report := range baseline {
+		baselineIDs[report.Target.ID]++
+	}
+	currentByID := make(map[string][]int, len(current))
+	for index, report := range current {
+		currentByID[report.Target.ID] = append(currentByID[report.Target.ID], index)
+	}
+	pairs := make([]int, len(baseline))
+	taken := make([]bool, len(current))
+	for index, report := range baseline {
+		pairs[index] = -1
+		endpoint := tcpTargetEndpoint(report.Target)
+		if endpoint == "" {
+			continue
+		}
+		for _, candidate := range currentByID[report.Target.ID] {
+			if !taken[candidate] && tcpTargetEndpoint(current[candidate].Target) == endpoint {
+				pairs[index], taken[candidate] = candidate, true
+				break
+			}
+		}
+	}
+	for index, report := range baseline {
+		if pairs[index] >= 0 {
+			continue
+		}
+		id := report.Target.ID
+		unique := baselineIDs[id] == 1 && len(currentByID[id]) == 1
+		for _, candidate := range currentByID[id] {
+			if taken[candidate] {
+				continue
+			}
+			if unique || tcpTargetEndpoint(report.Target) == "" || tcpTargetEndpoint(current[candidate].Target) == "" {
+				pairs[index], taken[candidate] = candidate, true
+				break
+			}
+		}
+	}
+	return pairs
+}
+
+// tcpTargetEndpoint is "" when the host is unknown, including a host that
+// privacy mode redacted.
+func tcpTargetEndpoint(target TCPTarget) string {
+	if target.Host == "" || target.Host == privacyRedacted {
+		return ""
+	}
+	return net.JoinHostPort(target.Host, strconv.Itoa(target.Port))
+}
+
+func diffMediaResults(baseline, current ComponentReport) []MediaFlip {
+	statuses := func(component ComponentReport) map[string]MediaFlip {
+		result := make(map[string]MediaFlip)
+		for _, raw := range arrayValue(payloadObject(component.Payload), "results") {
+			item, _ := raw.(map[string]any)
+			name := stringValue(item, "name")
+			if name == "" {
+				continue
+			}
+			ipVersion := stringValue(item, "ip_version")
+			result[strings.ToLower(name)+"\x00"+ipVersion] = MediaFlip{Platform: name, IPVersion: ipVersion, Baseline: stringValue(item, "status")}
+		}
+		return result
+	}
+	oldStatuses, newStatuses := statuses(baseline), statuses(current)
+	result := make([]MediaFlip, 0)
+	for key, oldStatus := range oldStatuses {
+		newStatus, exists := newStatuses[key]
+		if !exists || newStatus.Baseline == oldStatus.Baseline {
+			continue
+		}
+		oldStatus.Current = newStatus.Baseline
+		result = append(result, oldStatus)
+	}
+	if len(result) == 0 {
+		return nil
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].Platform == result[j].Platform {
+			return result[i].IPVersion < result[j].IPVersion
+		}
+		return result[i].Platform < result[j].Platform
+	})
+	return result
+}
+
+func percentChange(baseline, current float64) float64 {
+	if baseline == 0 {
+		return 0
+	}
+	return math.Round((current-baseline)/math.Abs(baseline)*10000) / 100
+}
+
+// RenderComparisonText renders a comparison with the same layout and
+// language selection as the structured run text.
+func RenderComparisonText(config *Config, report *ComparisonReport) string {
+	renderer := newStructuredTextRenderer(config)
+	if report == nil {
+		return ""
+	}
+	renderer.comparison(report)
+	return renderer.builder.String()
+}
+
+func (renderer *structuredTextRenderer) comparison(report *ComparisonReport) {
+	renderer.section(renderer.pick("基准对比", "Baseline Comparison"))
+	renderer.row(renderer.pick("基准版本", "Baseline"), joinNonEmpty(report.Baseline.ECSVersion, report.Baseline.FinishedAt.Format(time.RFC3339), renderer.status(report.Baseline.Status)))
+	renderer.row(renderer.pick("当前版本", "Current"), joinNonEmpty(report.Current.ECSVersion, report.Current.FinishedAt.Format(time.RFC3339), renderer.status(report.Current.Status)))
+	for _, component := range report.Components {
+		renderer.section(fallback(renderer.componentTitle(component.Name), component.Name))
+		if component.BaselineStatus != component.CurrentStatus {
+			renderer.row(renderer.pick("状态", "Status"), renderer.status(component.BaselineStatus)+" -> "+renderer.status(component.CurrentStatus))
+		}
+		rows := make([][]string, 0, len(component.Metrics))
+		for _, metric := range component.Metrics {
+			rows = append(rows, []string{
+				joinNonEmpty(metric.Target, metric.Metric), comparisonValue(metric.Baseline, metric.Unit, metric.Presence != "current_only"),
+				comparisonValue(metric.Current, metric.Unit, metric.Presence != "baseline_only"), comparisonChange(metric),
+			})
+		}
+		renderer.table([]string{renderer.pick("指标", "Metric"), renderer.pick("基准", "Baseline"), renderer.pick("当前", "Current"), renderer.pick("变化", "Change")}, rows, []int{30, 16, 16, 12})
+	}
+	if len(report.TCP) > 0 {
+		renderer.section(renderer.pick("TCP连接质量", "TCP Connection Quality"))
+		rows := make([][]string, 0, len(report.TCP))
+		ids := make(map[string]int, len(report.TCP))
+		for _, delta := range report.TCP {
+			ids[delta.ID]++
+		}
+		for _, delta := range report.TCP {
+			target := fallback(delta.Name, delta.ID)
+			if ids[delta.ID] > 1 && delta.Endpoint != "" {
+				target += " (" + delta.Endpoint + ")"
+			}
+			rows = append(rows, []string{
+				target,
+				comparisonValue(delta.BaselineP50MS, "ms", delta.Presence != "current_only") + " -> " + comparisonValue(delta.CurrentP50MS, "ms", delta.Presence != "baseline_only"),
+				comparisonValue(delta.BaselineP95MS, "ms", delta.Presence != "current_only") + " -> " + comparisonValue(delta.CurrentP95MS, "ms", delta.Presence != "baseline_only"),
+				signedValue(delta.P95DeltaMS, "ms", delta.Presence == "both"),
+			})
+		}
+		renderer.table([]string{renderer.pick("目标", "Target"), "P50", "P95", "ΔP95"}, rows, []int{22, 22, 22, 12})
+	}
+	if len(report.Media) > 0 {
+		renderer.section(renderer.pick("解锁状态变化", "Unlock Status Changes"))
+		rows := make([][]string, 0, len(report.Media))
+		for _, flip := range report.Media {
+			rows = append(rows, []string{flip.Platform, flip.IPVersion, localizedValue(flip.Baseline, renderer.zh), localizedValue(flip.Current, renderer.zh)})
+		}
+		renderer.table([]string{renderer.pick("平台", "Platform"), renderer.pick("协议", "IP"), renderer.pick("基准", "Baseline"), renderer.pick("当前", "Current")}, rows, []int{24, 8, 18, 18})
+	}
+	renderer.section("")
+}
+
+func comparisonValue(value float64, unit string, present bool) string {
+	if !present {
+		return "-"
+	}
+	if unit == "B/s" {
+		return formatBytesPerSecond(int64(value))
+	}
+	return strings.TrimSpace(fmt.Sprintf("%.2f %s", value, unit))
+}
+
+func comparisonChange(metric MetricDelta) string {
+	if metric.Presence != "both" {
+		return "-"
+	}
+	if metric.Baseline == 0 {
+		return signedValue(metric.Delta, "", true)
+	}
+	return fmt.Sprintf("%+.2f%%", metric.DeltaPercent)
+}
+
+func signedValue(value float64, unit string, present bool) string {
+	if !present {
+		return "-"
+	}
+	return strings.TrimSpace(fmt.Sprintf("%+.2f %s", value, unit))
+}
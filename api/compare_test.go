@@ -0,0 +1,141 @@
+package api
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestCompareReportsComputesComponentAndTCPDeltas(t *testing.T) {
+	baseline := &StructuredReport{
+		SchemaVersion: StructuredReportSchema, ECSVersion: "v-old", Status: ReportStatusOK,
+		Components: []ComponentReport{
+			componentFixture(t, "cputest", ReportStatusOK, `{"events_per_second":1000}`),
+			componentFixture(t, "disktest", ReportStatusOK, `{"metrics":[{"scenario_id":"4k_randread","iops":2000,"bandwidth_bytes_per_second":8192000}]}`),
+			componentFixture(t, "unlocktests.media", ReportStatusOK, `{"results":[{"name":"Netflix","ip_version":"ipv4","status":"yes"},{"name":"Disney+","ip_version":"ipv4","status":"no"}]}`),
+		},
+		TCP: []TCPReport{
+			{Target: TCPTarget{ID: "cloudflare", Name: "Cloudflare"}, P50MS: 10, P95MS: 20},
+			{Target: TCPTarget{ID: "retired", Name: "Retired"}, P50MS: 5, P95MS: 6},
+		},
+	}
+	current := &StructuredReport{
+		SchemaVersion: StructuredReportSchema, ECSVersion: "v-new", Status: ReportStatusPartial,
+		Components: []ComponentReport{
+			componentFixture(t, "cputest", ReportStatusOK, `{"events_per_second":1100}`),
+			componentFixture(t, "disktest", ReportStatusOK, `{"metrics":[{"scenario_id":"4k_randread","iops":1000,"bandwidth_bytes_per_second":4096000},{"scenario_id":"1m_seqwrite","iops":50,"bandwidth_bytes_per_second":52428800}]}`),
+			componentFixture(t, "unlocktests.media", ReportStatusOK, `{"results":[{"name":"Netflix","ip_version":"ipv4","status":"no"},{"name":"Disney+","ip_version":"ipv4","status":"no"}]}`),
+		},
+		TCP: []TCPReport{{Target: TCPTarget{ID: "cloudflare", Name: "Cloudflare"}, P50MS: 12, P95MS: 35}},
+	}
+	comparison, err := CompareReports(baseline, current)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if comparison.SchemaVersion != ComparisonReportSchema || comparison.Current.Status != ReportStatusPartial {
+		t.Fatalf("unexpected comparison envelope: %+v", comparison)
+	}
+	if len(comparison.Components) != 2 || comparison.Components[0].Name != "cputest" || comparison.Components[1].Name != "disktest" {
+		t.Fatalf("unexpected compared components: %+v", comparison.Components)
+	}
+	cpu := comparison.Components[0].Metrics[0]
+	if cpu.Delta != 100 || cpu.DeltaPercent != 10 || !cpu.HigherIsBetter || cpu.Presence != "both" {
+		t.Fatalf("unexpected CPU delta: %+v", cpu)
+	}
+	disk := comparison.Components[1].Metrics
+	if len(disk) != 4 || disk[0].DeltaPercent != -50 || disk[2].Presence != "current_only" || disk[2].Target != "1m_seqwrite" {
+		t.Fatalf("unexpected disk deltas: %+v", disk)
+	}
+	if len(comparison.TCP) != 2 || comparison.TCP[0].ID != "cloudflare" || comparison.TCP[0].P95DeltaMS != 15 || comparison.TCP[1].Presence != "baseline_only" {
+		t.Fatalf("unexpected TCP deltas: %+v", comparison.TCP)
+	}
+	if len(comparison.Media) != 1 || comparison.Media[0].Platform != "Netflix" || comparison.Media[0].Baseline != "yes" || comparison.Media[0].Current != "no" {
+		t.Fatalf("unexpected media flips: %+v", comparison.Media)
+	}
+	config := NewConfig("v-test")
+	config.Language = "en"
+	text := RenderComparisonText(config, comparison)
+	for _, want := range []string{"Baseline Comparison", "CPU Benchmark", "+10.00%", "4k_randread", "Unlock Status Changes", "+15.00 ms"} {
+		if !strings.Contains(text, want) {
+			t.Fatalf("comparison text is missing %q:\n%s", want, text)
+		}
+	}
+}
+
+func TestDecodeStructuredReportRejectsUnknownSchema(t *testing.T) {
+	if _, err := DecodeStructuredReport([]byte(`{"schema_version":"goecs.report/v0"}`)); err == nil {
+		t.Fatal("expected unknown report schema to be rejected")
+	}
+	if _, err := CompareReports(nil, &StructuredReport{}); err == nil {
+		t.Fatal("expected missing baseline to be rejected")
+	}
+}
+
+func TestCompareReportsKeepsTCPTargetsWithSharedIDs(t *testing.T) {
+	report := func(p95a, p95b float64) *StructuredReport {
+		return &StructuredReport{SchemaVersion: StructuredReportSchema, Status: ReportStatusOK, TCP: []TCPReport{
+			{Target: TCPTarget{ID: "db", Name: "DB", Host: "db-a.test", Port: 5432}, P95MS: p95a},
+			{Target: TCPTarget{ID: "db", Name: "DB", Host: "db-b.test", Port: 5432}, P95MS: p95b},
+		}}
+	}
+	baseline := report(10, 100)
+	current := report(12, 90)
+	current.TCP[0], current.TCP[1] = current.TCP[1], current.TCP[0]
+	comparison, err := CompareReports(baseline, current)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(comparison.TCP) != 2 || comparison.TCP[0].Endpoint != "db-a.test:5432" || comparison.TCP[0].P95DeltaMS != 2 ||
+		comparison.TCP[1].Endpoint != "db-b.test:5432" || comparison.TCP[1].P95DeltaMS != -10 {
+		t.Fatalf("targets sharing an ID were merged: %+v", comparison.TCP)
+	}
+	config := NewConfig("v-test")
+	config.Language = "en"
+	if text := RenderComparisonText(config, comparison); !strings.Contains(text, "DB (db-b.test:5432)") {
+		t.Fatalf("comparison text does not tell the targets apart:\n%s", text)
+	}
+}
+
+func TestCompareReportsPairsRedactedAndMovedTCPTargets(t *testing.T) {
+	report := func(hostA, hostB string) *StructuredReport {
+		return &StructuredReport{SchemaVersion: StructuredReportSchema, Status: ReportStatusOK, TCP: []TCPReport{
+			{Target: TCPTarget{ID: "edge", Name: "Edge", Host: hostA, Port: 443}, P95MS: 20},
+			{Target: TCPTarget{ID: "db", Name: "DB", Host: hostB, Port: 5432}, P95MS: 40},
+			{Target: TCPTarget{ID: "db", Name: "DB", Host: hostB, Port: 5433}, P95MS: 60},
+		}}
+	}
+	baseline := report("edge.test", "db.test")
+	applyStructuredPrivacy(baseline)
+	current := report("edge-new.test", "db.test")
+	current.TCP[1].P95MS, current.TCP[2].P95MS = 45, 50
+	comparison, err := CompareReports(baseline, current)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(comparison.TCP) != 3 {
+		t.Fatalf("redacted baseline did not pair: %+v", comparison.TCP)
+	}
+	for _, delta := range comparison.TCP {
+		if delta.Presence != "both" {
+			t.Fatalf("redacted baseline did not pair: %+v", comparison.TCP)
+		}
+	}
+	if comparison.TCP[0].Endpoint != "db.test:5432" || comparison.TCP[0].P95DeltaMS != 5 || comparison.TCP[1].P95DeltaMS != -10 ||
+		comparison.TCP[2].Endpoint != "edge-new.test:443" {
+		t.Fatalf("unexpected pairing: %+v", comparison.TCP)
+	}
+
+	// Known hosts under a shared ID never pair across different endpoints.
+	moved := report("edge.test", "db.test")
+	moved.TCP[2].Target.Host = "db-b.test"
+	comparison, err = CompareReports(report("edge.test", "db.test"), moved)
+	if err != nil {
+		t.Fatal(err)
+	}
+	presence := make(map[string]string)
+	for _, delta := range comparison.TCP {
+		presence[delta.Endpoint] = delta.Presence
+	}
+	if len(comparison.TCP) != 4 || presence["db.test:5433"] != "baseline_only" || presence["db-b.test:5433"] != "current_only" {
+		t.Fatalf("unexpected pairing: %+v", comparison.TCP)
+	}
+}
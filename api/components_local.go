@@ -62,7 +62,7 @@ func collectPublishedComponentReports(ctx context.Context, config *Config, input
 		started := time.Now()
 		routes, err := nt3model.ParseProvinceRoutes(inputs.ProvinceRoutes)
 		if err != nil {
-			result = append(result, componentPayload("nt3.province_latency", "goecs.nt3/province-latency-v1", ReportStatusError, started, nil, err))
+			result = append(result, componentPayload("nt3.province_latency", ProvinceLatencySchema, ReportStatusError, started, nil, err))
 		} else {
 			targets := nt3model.BuildProvinceLatencyTargets(routes, config.Nt3CheckType)
 			probeConfig := nt3.StandardProvinceLatencyConfig()
@@ -81,7 +81,7 @@ func collectPublishedComponentReports(ctx context.Context, config *Config, input
 					status = ReportStatusCanceled
 				}
 			}
-			result = append(result, componentPayload("nt3.province_latency", "goecs.nt3/province-latency-v1", status, started, probes, nil))
+			result = append(result, componentPayload("nt3.province_latency", ProvinceLatencySchema, status, started, newProvinceLatencyPayload(probes), nil))
 			if config.DeepMode && ctx.Err() == nil {
 				routeStarted := time.Now()
 				routeCtx, routeCancel := context.WithTimeout(ctx, 3*time.Minute)
@@ -91,7 +91,7 @@ func collectPublishedComponentReports(ctx context.Context, config *Config, input
 				routesReport, routeErr := nt3.RunDetailedProvinceRoutes(routeCtx, routes, routeConfig)
 				routeStatus := detailedRouteComponentStatus(routeCtx, routesReport, routeErr)
 				routeCancel()
-				result = append(result, componentPayload("nt3.province_routes", "goecs.nt3/province-routes-v1", routeStatus, routeStarted, routesReport, routeErr))
+				result = append(result, componentPayload("nt3.province_routes", "goecs.nt3/province-routes-v1", routeStatus, routeStarted, classifyProvinceRoutes(routesReport), routeErr))
 			}
 		}
 		routeStatus, routeReason := aggregateComponentSectionStatus(result[firstRouteComponent:])
@@ -135,6 +135,18 @@ func collectPublishedComponentReports(ctx context.Context, config *Config, input
 			return report
 		}))
 	}
+	if config.QUICTestStatus && inputs.Network {
+		result = append(result, collectComponentStep(ctx, "quic", func() ComponentReport {
+			const budget = 60 * time.Second
+			targets := websiteQUICTargets()
+			// Blocked UDP makes every QUIC handshake wait out its timeout, so
+			// the plan is trimmed to fit the component budget.
+			probe := fitQUICProbe(len(targets), budget, quicProbeConfig{attempts: 3, timeout: 5 * time.Second, concurrency: 8})
+			quicCtx, cancel := componentContext(ctx, budget)
+			defer cancel()
+			return runQUICProbeComponent(quicCtx, targets, probe)
+		}))
+	}
 	if config.UtTestStatus && inputs.Network {
 		result = append(result, collectComponentStep(ctx, "media", func() ComponentReport {
 			started := time.Now()
@@ -275,21 +287,48 @@ func structuredIdentity(ctx context.Context) (string, string) {
 	return ipv4, ipv6
 }
 
-func mergeComponentTCPTargets(targets []TCPTarget) []TCPTarget {
-	merged := append([]TCPTarget(nil), targets...)
-	seen := make(map[string]struct{}, len(merged))
+// mergeComponentTCPTargets appends custom targets and the local ping
+// registry to the snapshot targets, deduplicated by endpoint and by ID. A
+// custom target replaces a snapshot entry for the same endpoint or ID so the
+// operator's naming and the custom category win, and a registry entry is
+// dropped when an earlier target already uses its endpoint or ID.
+func mergeComponentTCPTargets(targets []TCPTarget, custom ...TCPTarget) []TCPTarget {
+	endpoint := func(host string, port int) string {
+		return strings.ToLower(strings.TrimSuffix(strings.TrimSpace(host), ".")) + fmt.Sprintf(":%d", port)
+	}
+	id := func(value string) string {
+		return strings.ToLower(strings.TrimSpace(value))
+	}
+	seen := make(map[string]struct{}, len(targets)+len(custom))
+	ids := make(map[string]struct{}, len(targets)+len(custom))
+	for _, target := range custom {
+		seen[endpoint(target.Host, target.Port)] = struct{}{}
+		ids[id(target.ID)] = struct{}{}
+	}
+	merged := make([]TCPTarget, 0, len(targets)+len(custom))
+	for _, target := range targets {
+		_, endpointExists := seen[endpoint(target.Host, target.Port)]
+		_, idExists := ids[id(target.ID)]
+		if !endpointExists && !idExists {
+			merged = append(merged, target)
+		}
+	}
+	merged = append(merged, custom...)
 	for _, target := range merged {
-		key := strings.ToLower(strings.TrimSuffix(strings.TrimSpace(target.Host), ".")) + fmt.Sprintf(":%d", target.Port)
-		seen[key] = struct{}{}
+		seen[endpoint(target.Host, target.Port)] = struct{}{}
+		ids[id(target.ID)] = struct{}{}
 	}
 	for _, target := range pingmodel.AllTCPTargets() {
-		key := strings.ToLower(strings.TrimSuffix(strings.TrimSpace(target.Host), ".")) + fmt.Sprintf(":%d", target.Port)
+		key, targetKey := endpoint(target.Host, target.Port), targetID(target.Name, target.Host, target.Port)
 		if _, exists := seen[key]; exists {
 			continue
 		}
-		seen[key] = struct{}{}
+		if _, exists := ids[targetKey]; exists {
+			continue
+		}
+		seen[key], ids[targetKey] = struct{}{}, struct{}{}
 		merged = append(merged, TCPTarget{
-			ID: targetID(target.Name, target.Host, target.Port), Name: target.Name,
+			ID: targetKey, Name: target.Name,
 			Host: target.Host, Port: target.Port, Category: target.Category,
 		})
 	}
@@ -419,6 +458,8 @@ func collectHardwareComponentReports(parent context.Context, config *Config, run
 	if config == nil || shouldSkipStructuredHardware(parent) || (!config.CpuTestStatus && !config.MemoryTestStatus && !config.DiskTestStatus && !hasExplicitDeepHardware(config)) {
 		return nil
 	}
+	release := acquireHardwareStage(parent)
+	defer release()
 	hardwareCtx, cancel := hardwareStageContext(parent, config.HardwareBudget)
 	defer cancel()
 	reports := make([]ComponentReport, 0, 3)
@@ -641,6 +682,26 @@ func hardwareStageContext(parent context.Context, budget time.Duration) (context
 	return context.WithTimeout(parent, budget)
 }
 
+// hardwareStageSlot allows one hardware stage per process. Concurrent runs
+// (for example several served API requests) would otherwise benchmark each
+// other instead of the host.
+var hardwareStageSlot = make(chan struct{}, 1)
+
+// acquireHardwareStage waits for the hardware slot without consuming the
+// stage budget. When ctx ends first the returned release is a no-op and the
+// stage reports its components as canceled or timed out.
+func acquireHardwareStage(ctx context.Context) func() {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	select {
+	case hardwareStageSlot <- struct{}{}:
+		return func() { <-hardwareStageSlot }
+	case <-ctx.Done():
+		return func() {}
+	}
+}
+
 // canceledHardwareComponent records components that were enabled but could
 // not start because the shared stage budget or parent context had expired.
 // The second return value tells the caller to skip the underlying benchmark.
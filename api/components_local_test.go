@@ -153,12 +153,18 @@ func TestLocalComponentAdapterBuildsProvinceLatencyPayload(t *testing.T) {
 	if provinceComponent == nil || provinceComponent.Status != ReportStatusCanceled {
 		t.Fatalf("province component missing or not canceled: component=%#v count=%d", provinceComponent, len(components))
 	}
-	var payload []json.RawMessage
+	var payload struct {
+		Results  []json.RawMessage          `json:"results"`
+		Carriers []ProvinceLatencyAggregate `json:"carriers"`
+	}
 	if err := json.Unmarshal(provinceComponent.Payload, &payload); err != nil {
 		t.Fatal(err)
 	}
-	if len(payload) != 31*3 {
-		t.Fatalf("got %d province targets, want %d", len(payload), 31*3)
+	if provinceComponent.SchemaVersion != ProvinceLatencySchema || len(payload.Results) != 31*3 {
+		t.Fatalf("got %d province targets, want %d", len(payload.Results), 31*3)
+	}
+	if len(payload.Carriers) != 3 || payload.Carriers[0].Targets != 31 || payload.Carriers[0].IPVersion != "ipv4" {
+		t.Fatalf("unexpected carrier aggregates: %+v", payload.Carriers)
 	}
 }
 
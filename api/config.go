@@ -9,6 +9,18 @@ import (
 // Config 配置接口，导出用于外部调用
 type Config = params.Config
 
+// ConfigIssue is one invalid or out-of-range value that validation replaced.
+type ConfigIssue = params.ConfigIssue
+
+// ConfigIssues is the list of corrections returned by ApplyOptionsChecked.
+type ConfigIssues = params.ConfigIssues
+
+// Severities of a ConfigIssue.
+const (
+	ConfigIssueError   = params.IssueError
+	ConfigIssueWarning = params.IssueWarning
+)
+
 // NewConfig 创建默认配置
 // version: 版本号字符串
 func NewConfig(version string) *Config {
@@ -160,12 +172,79 @@ func WithTCPProbe(enable bool) ConfigOption {
 	}
 }
 
+// WithTCPProbeSettings sets the handshakes per target, the per-handshake
+// timeout, the number of targets probed at once and the pause between
+// handshakes to one target. Zero attempts selects the mode default.
+func WithTCPProbeSettings(attempts int, timeout time.Duration, concurrency int, interval time.Duration) ConfigOption {
+	return func(c *Config) {
+		c.TCPAttempts = attempts
+		c.TCPTimeout = timeout
+		c.TCPConcurrency = concurrency
+		c.TCPInterval = interval
+	}
+}
+
+func WithQUICTest(enable bool) ConfigOption {
+	return func(c *Config) {
+		c.QUICTestStatus = enable
+	}
+}
+
+func WithTLSProbe(enable bool) ConfigOption {
+	return func(c *Config) {
+		c.TLSProbeStatus = enable
+	}
+}
+
+func WithTCPTargetsFile(path string) ConfigOption {
+	return func(c *Config) {
+		c.TCPTargetsFile = path
+	}
+}
+
+func WithTCPTarget(target string) ConfigOption {
+	return func(c *Config) {
+		c.TCPTargets = append(c.TCPTargets, target)
+	}
+}
+
+// WithUploadEndpoint sends results to a self-hosted collector instead of the
+// public paste service. token is sent as a bearer token when set, field is the
+// dotted JSON path of the result URL in the response (empty for a plain-text
+// URL body), and format is text, json or both.
+func WithUploadEndpoint(endpoint, token, field, format string) ConfigOption {
+	return func(c *Config) {
+		c.UploadEndpoint = endpoint
+		c.UploadToken = token
+		c.UploadResultField = field
+		c.UploadFormat = format
+	}
+}
+
 func WithJSONPath(path string) ConfigOption {
 	return func(c *Config) {
 		c.JSONPath = path
 	}
 }
 
+func WithComparePath(path string) ConfigOption {
+	return func(c *Config) {
+		c.ComparePath = path
+	}
+}
+
+func WithCompareJSONPath(path string) ConfigOption {
+	return func(c *Config) {
+		c.CompareJSONPath = path
+	}
+}
+
+func WithMetricsPath(path string) ConfigOption {
+	return func(c *Config) {
+		c.MetricsPath = path
+	}
+}
+
 func WithDataCDNBase(base string) ConfigOption {
 	return func(c *Config) {
 		c.DataCDNBase = base
@@ -178,6 +257,23 @@ func WithDataOffline(enable bool) ConfigOption {
 	}
 }
 
+// WithDataCache caches verified snapshot files in dir, reusing them for
+// maxAge before revalidating. An empty dir disables the cache.
+func WithDataCache(dir string, maxAge time.Duration) ConfigOption {
+	return func(c *Config) {
+		c.DataCacheDir = dir
+		c.DataCacheMaxAge = maxAge
+	}
+}
+
+// WithDataDir overlays the snapshot files listed in dir/manifest.json on the
+// normal CDN, cache and embedded chain.
+func WithDataDir(dir string) ConfigOption {
+	return func(c *Config) {
+		c.DataOverlayDir = dir
+	}
+}
+
 // WithEnableLogger 设置是否启用日志
 func WithEnableLogger(enable bool) ConfigOption {
 	return func(c *Config) {
@@ -361,16 +457,24 @@ func WithChoice(choice string) ConfigOption {
 	}
 }
 
-// ApplyOptions 应用配置选项
+// ApplyOptions 应用配置选项. Invalid values are replaced by their defaults
+// and the corrections are recorded in config.ConfigWarnings.
 func ApplyOptions(config *Config, options ...ConfigOption) *Config {
+	config, _ = ApplyOptionsChecked(config, options...)
+	return config
+}
+
+// ApplyOptionsChecked applies options like ApplyOptions and also returns
+// the corrections validation made. The error is a ConfigIssues, or nil
+// when every value was valid.
+func ApplyOptionsChecked(config *Config, options ...ConfigOption) (*Config, error) {
 	if config == nil {
-		return nil
+		return nil, nil
 	}
 	for _, opt := range options {
 		if opt != nil {
 			opt(config)
 		}
 	}
-	config.ValidateParams()
-	return config
+	return config, config.ValidateParams().Err()
 }
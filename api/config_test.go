@@ -1,6 +1,12 @@
 package api
 
-import "testing"
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
 
 func TestApplyOptionsValidatesConfig(t *testing.T) {
 	cfg := NewDefaultConfig()
@@ -32,3 +38,32 @@ func TestApplyOptionsAllowsNilConfig(t *testing.T) {
 		t.Fatalf("nil config should stay nil")
 	}
 }
+
+func TestApplyOptionsCheckedReportsCorrections(t *testing.T) {
+	cfg := NewDefaultConfig()
+	cfg.EnableUpload, cfg.UploadEndpoint = true, "ftp://collector.example/secret"
+	cfg, err := ApplyOptionsChecked(cfg, WithCpuTestMethod("bogus"))
+	var issues ConfigIssues
+	if !errors.As(err, &issues) || len(issues) != 2 {
+		t.Fatalf("unexpected error: %#v", err)
+	}
+	if issues[0].Flag != "upload-url" || cfg.EnableUpload || len(cfg.ConfigWarnings) != 2 {
+		t.Fatalf("unexpected upload issue: %#v %#v", issues[0], cfg)
+	}
+	if issues[1].Flag != "cpum" || issues[1].Value != "bogus" || issues[1].Applied != "sysbench" || issues[1].Severity != ConfigIssueError {
+		t.Fatalf("unexpected CPU method issue: %#v", issues[1])
+	}
+	if _, err := ApplyOptionsChecked(cfg); err != nil {
+		t.Fatalf("corrected config reported again: %v", err)
+	}
+
+	cfg.DataOffline, cfg.TCPProbeStatus, cfg.PrivacyMode = true, false, true
+	report := CollectStructuredReport(context.Background(), NetCheckResult{Connected: false, StackType: "None"}, cfg, "", time.Now(), time.Now())
+	encoded, err := report.JSON()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(report.ConfigWarnings) != 2 || !strings.Contains(string(encoded), `"config_warnings"`) || strings.Contains(string(encoded), "collector.example") {
+		t.Fatalf("unexpected config warnings: %s", encoded)
+	}
+}
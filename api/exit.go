@@ -0,0 +1,129 @@
+package api
+
+// Process exit codes of goecs. A run exits with the code of its outcome
+// only when the outcome is at least as severe as Config.FailOn; failed
+// assertions and finalization failures always exit non-zero.
+const (
+	ExitCodeOK        = 0
+	ExitCodeError     = 1
+	ExitCodeUsage     = 2
+	ExitCodePartial   = 3
+	ExitCodeTimeout   = 4
+	ExitCodeCanceled  = 5
+	ExitCodeFinalize  = 6
+	ExitCodeAssertion = 7
+)
+
+// statusSeverity orders statuses for -fail-on: a partial run is less severe
+// than one cut short by a deadline or interrupt, which is less severe than
+// an error.
+func statusSeverity(status ReportStatus) int {
+	switch status {
+	case ReportStatusPartial, ReportStatusUnavailable:
+		return 1
+	case ReportStatusTimeout, ReportStatusCanceled:
+		return 2
+	case ReportStatusError:
+		return 3
+	default:
+		return 0
+	}
+}
+
+// Outcome is the most severe of the report status and the statuses of its
+// enabled sections, so a section that failed outright is an error even
+// though the aggregate status only records the run as partial.
+func (report *StructuredReport) Outcome() ReportStatus {
+	if report == nil {
+		return ReportStatusError
+	}
+	outcome := report.Status
+	for _, section := range report.Sections {
+		if section.Enabled && statusSeverity(section.Status) > statusSeverity(outcome) {
+			outcome = section.Status
+		}
+	}
+	return outcome
+}
+
+// StatusExitCode returns the exit code of a run with status, or ExitCodeOK
+// when status is less severe than failOn. An unknown failOn fails on
+// timeouts and errors, like the -fail-on default.
+func StatusExitCode(status, failOn ReportStatus) int {
+	threshold := statusSeverity(failOn)
+	if threshold == 0 {
+		threshold = statusSeverity(ReportStatusTimeout)
+	}
+	if statusSeverity(status) < threshold {
+		return ExitCodeOK
+	}
+	switch status {
+	case ReportStatusPartial, ReportStatusUnavailable:
+		return ExitCodePartial
+	case ReportStatusTimeout:
+		return ExitCodeTimeout
+	case ReportStatusCanceled:
+		return ExitCodeCanceled
+	default:
+		return ExitCodeError
+	}
+}
+
+// exitCodeRank orders exit codes for WorstExitCode: a failed status
+// outranks a failed assertion, which outranks a failed finalization. A usage
+// error ends a run before anything is measured.
+func exitCodeRank(code int) int {
+	switch code {
+	case ExitCodeOK:
+		return 0
+	case ExitCodeFinalize:
+		return 1
+	case ExitCodeAssertion:
+		return 2
+	case ExitCodePartial:
+		return 3
+	case ExitCodeTimeout, ExitCodeCanceled:
+		return 4
+	case ExitCodeUsage:
+		return 6
+	default:
+		return 5
+	}
+}
+
+// WorstExitCode returns the most severe of codes, so repeated runs exit
+// with the worst result any of them had rather than the last one. Of two
+// equally severe codes the first wins.
+func WorstExitCode(codes ...int) int {
+	worst := ExitCodeOK
+	for _, code := range codes {
+		if exitCodeRank(code) > exitCodeRank(worst) {
+			worst = code
+		}
+	}
+	return worst
+}
+
+// RunExitCode returns the exit code of result after FinalizeRunResultContext
+// returned finalizeErr. A run without a report is an error. Otherwise a run
+// that passes -fail-on exits with ExitCodeAssertion when an assertion
+// failed, then with ExitCodeFinalize when finalization failed.
+func RunExitCode(config *Config, result *RunResult, finalizeErr error) int {
+	if result == nil || result.Report == nil {
+		return ExitCodeError
+	}
+	failOn := ReportStatusTimeout
+	if config != nil {
+		failOn = ReportStatus(config.FailOn)
+	}
+	if code := StatusExitCode(result.Report.Outcome(), failOn); code != ExitCodeOK {
+		return code
+	}
+	if failedAssertions(result.Report) > 0 {
+		return ExitCodeAssertion
+	}
+	if finalizeErr != nil {
+		return ExitCodeFinalize
+	}
+	return ExitCodeOK
+}
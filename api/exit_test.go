@@ -0,0 +1,78 @@
+package api
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestStatusExitCodeHonorsFailOn(t *testing.T) {
+	cases := []struct {
+		status, failOn ReportStatus
+		want           int
+	}{
+		{ReportStatusOK, ReportStatusPartial, ExitCodeOK},
+		{ReportStatusPartial, ReportStatusPartial, ExitCodePartial},
+		{ReportStatusUnavailable, ReportStatusPartial, ExitCodePartial},
+		{ReportStatusPartial, ReportStatusTimeout, ExitCodeOK},
+		{ReportStatusTimeout, ReportStatusTimeout, ExitCodeTimeout},
+		{ReportStatusCanceled, ReportStatusTimeout, ExitCodeCanceled},
+		{ReportStatusTimeout, ReportStatusError, ExitCodeOK},
+		{ReportStatusError, ReportStatusError, ExitCodeError},
+		{ReportStatusPartial, "", ExitCodeOK},
+		{ReportStatusTimeout, "", ExitCodeTimeout},
+	}
+	for _, tc := range cases {
+		if got := StatusExitCode(tc.status, tc.failOn); got != tc.want {
+			t.Errorf("StatusExitCode(%q, %q) = %d, want %d", tc.status, tc.failOn, got, tc.want)
+		}
+	}
+}
+
+func TestRunExitCodeUsesSectionStatusesAndFinalization(t *testing.T) {
+	config := NewDefaultConfig()
+	report := &StructuredReport{Status: ReportStatusPartial, Sections: []SectionReport{
+		{Name: "cpu", Enabled: true, Status: ReportStatusOK},
+		{Name: "speed", Enabled: false, Status: ReportStatusError},
+		{Name: "disk", Enabled: true, Status: ReportStatusUnavailable},
+	}}
+	result := &RunResult{Report: report}
+	if outcome := report.Outcome(); outcome != ReportStatusPartial {
+		t.Fatalf("outcome = %q, want partial", outcome)
+	}
+	if code := RunExitCode(config, result, nil); code != ExitCodeOK {
+		t.Fatalf("partial run failed the default threshold: %d", code)
+	}
+	if code := RunExitCode(config, result, errors.New("upload result: refused")); code != ExitCodeFinalize {
+		t.Fatalf("failed finalization exited %d", code)
+	}
+	config.FailOn = "partial"
+	if code := RunExitCode(config, result, errors.New("upload result: refused")); code != ExitCodePartial {
+		t.Fatalf("partial run with -fail-on partial exited %d", code)
+	}
+	report.Sections[2].Status = ReportStatusError
+	config.FailOn = "error"
+	if code := RunExitCode(config, result, nil); code != ExitCodeError {
+		t.Fatalf("failed section exited %d", code)
+	}
+	if code := RunExitCode(config, &RunResult{}, nil); code != ExitCodeError {
+		t.Fatalf("run without report exited %d", code)
+	}
+}
+
+func TestWorstExitCodeKeepsMostSevereRun(t *testing.T) {
+	cases := []struct {
+		codes []int
+		want  int
+	}{
+		{nil, ExitCodeOK},
+		{[]int{ExitCodePartial, ExitCodeOK}, ExitCodePartial},
+		{[]int{ExitCodeAssertion, ExitCodeFinalize}, ExitCodeAssertion},
+		{[]int{ExitCodeTimeout, ExitCodeAssertion, ExitCodeOK}, ExitCodeTimeout},
+		{[]int{ExitCodePartial, ExitCodeError, ExitCodeCanceled}, ExitCodeError},
+	}
+	for _, tc := range cases {
+		if got := WorstExitCode(tc.codes...); got != tc.want {
+			t.Errorf("WorstExitCode(%v) = %d, want %d", tc.codes, got, tc.want)
+		}
+	}
+}
@@ -17,10 +17,15 @@ var ansiOutputPattern = regexp.MustCompile("\x1b\\[[0-9;]+[A-Za-z]")
 var uploadTextContext = utils.UploadTextContext
 
 type FinalizeResult struct {
-	TextPath string `json:"text_path,omitempty"`
-	JSONPath string `json:"json_path,omitempty"`
-	HTTPURL  string `json:"http_url,omitempty"`
-	HTTPSURL string `json:"https_url,omitempty"`
+	TextPath    string `json:"text_path,omitempty"`
+	JSONPath    string `json:"json_path,omitempty"`
+	MetricsPath string `json:"metrics_path,omitempty"`
+	HTTPURL     string `json:"http_url,omitempty"`
+	HTTPSURL    string `json:"https_url,omitempty"`
+	// UploadURL and JSONUploadURL are set when the reports went to
+	// Config.UploadEndpoint rather than the public paste service.
+	UploadURL     string `json:"upload_url,omitempty"`
+	JSONUploadURL string `json:"json_upload_url,omitempty"`
 }
 
 // FinalizeRunResultContext performs explicitly requested file and upload side
@@ -44,6 +49,14 @@ func FinalizeRunResultContext(ctx context.Context, preCheck NetCheckResult, conf
 		}
 	}
 
+	if config.MetricsPath != "" && result.Report != nil {
+		if err := writeMetricsFile(ctx, config.MetricsPath, result.Report); err != nil {
+			finalErr = errors.Join(finalErr, fmt.Errorf("write metrics: %w", err))
+		} else {
+			finalized.MetricsPath = config.MetricsPath
+		}
+	}
+
 	textPath := strings.TrimSpace(config.FilePath)
 	if textPath != "" && !config.PrivacyMode && result.Output != "" {
 		cleaned := ansiOutputPattern.ReplaceAllString(result.Output, "")
@@ -57,6 +70,11 @@ func FinalizeRunResultContext(ctx context.Context, preCheck NetCheckResult, conf
 	if !uploadAllowed(ctx, preCheck, config, result) {
 		return finalized, finalErr
 	}
+	if config.UploadEndpoint != "" {
+		// A configured collector replaces the paste service entirely; its
+		// failure never falls back to sending the report to a third party.
+		return finalized, errors.Join(finalErr, uploadToEndpoint(ctx, config, result, &finalized))
+	}
 	if finalized.TextPath == "" {
 		finalErr = errors.Join(finalErr, errors.New("upload requested but no text report was written"))
 		return finalized, finalErr
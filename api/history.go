@@ -0,0 +1,349 @@
+package api
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	historyFilePrefix       = "goecs-"
+	historyFileSuffix       = ".jsonl"
+	historyDayLayout        = "2006-01-02"
+	defaultHistoryFileBytes = 16 << 20
+	defaultHistoryMaxBytes  = 256 << 20
+	historyMaxLineBytes     = 64 << 20
+)
+
+// HistoryStore appends structured reports to one JSONL file per UTC day under
+// Dir. A day file that grows past MaxFileBytes rotates to name.1.jsonl,
+// name.2.jsonl, and so on; the oldest files are removed once the directory
+// holds more than MaxBytes of history.
+type HistoryStore struct {
+	Dir          string
+	MaxBytes     int64
+	MaxFileBytes int64
+}
+
+type historyFile struct {
+	path  string
+	day   string
+	index int
+	size  int64
+}
+
+func NewHistoryStore(dir string, maxBytes int64) *HistoryStore {
+	if maxBytes <= 0 {
+		maxBytes = defaultHistoryMaxBytes
+	}
+	fileBytes := min(int64(defaultHistoryFileBytes), max(maxBytes/4, 1<<20))
+	return &HistoryStore{Dir: dir, MaxBytes: maxBytes, MaxFileBytes: fileBytes}
+}
+
+// Append stores report without its rendered text, which is large and can be
+// regenerated from the components.
+func (store *HistoryStore) Append(report *StructuredReport) error {
+	if store == nil || report == nil {
+		return errors.New("history store and report are required")
+	}
+	record := *report
+	record.Text = ""
+	line, err := json.Marshal(record)
+	if err != nil {
+		return err
+	}
+	line = append(line, '\n')
+	if err := os.MkdirAll(store.Dir, 0o700); err != nil {
+		return err
+	}
+	finished := report.FinishedAt
+	if finished.IsZero() {
+		finished = time.Now()
+	}
+	path, err := store.appendPath(finished.UTC().Format(historyDayLayout), int64(len(line)))
+	if err != nil {
+		return err
+	}
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0o600)
+	if err != nil {
+		return err
+	}
+	if _, err := file.Write(line); err != nil {
+		_ = file.Close()
+		return err
+	}
+	if err := file.Close(); err != nil {
+		return err
+	}
+	return store.prune(path)
+}
+
+// Load returns every stored report that finished at or after since, oldest
+// first. Lines that cannot be decoded, such as a record truncated by a crash,
+// are counted and skipped.
+func (store *HistoryStore) Load(since time.Time) ([]StructuredReport, int, error) {
+	files, err := store.files()
+	if err != nil {
+		return nil, 0, err
+	}
+	firstDay := since.UTC().Format(historyDayLayout)
+	var reports []StructuredReport
+	skipped := 0
+	for _, file := range files {
+		if !since.IsZero() && file.day < firstDay {
+			continue
+		}
+		handle, err := os.Open(file.path)
+		if err != nil {
+			return nil, skipped, err
+		}
+		scanner := bufio.NewScanner(handle)
+		scanner.Buffer(make([]byte, 0, 64<<10), historyMaxLineBytes)
+		for scanner.Scan() {
+			var report StructuredReport
+			if json.Unmarshal(scanner.Bytes(), &report) != nil || report.SchemaVersion != StructuredReportSchema {
+				skipped++
+				continue
+			}
+			if report.FinishedAt.Before(since) {
+				continue
+			}
+			reports = append(reports, report)
+		}
+		err = scanner.Err()
+		_ = handle.Close()
+		if err != nil {
+			return nil, skipped, fmt.Errorf("read %s: %w", file.path, err)
+		}
+	}
+	sort.SliceStable(reports, func(i, j int) bool { return reports[i].FinishedAt.Before(reports[j].FinishedAt) })
+	return reports, skipped, nil
+}
+
+func (store *HistoryStore) appendPath(day string, lineBytes int64) (string, error) {
+	files, err := store.files()
+	if err != nil {
+		return "", err
+	}
+	var latest *historyFile
+	for index := range files {
+		if files[index].day == day {
+			latest = &files[index]
+		}
+	}
+	if latest == nil {
+		return filepath.Join(store.Dir, historyFileName(day, 0)), nil
+	}
+	if latest.size > 0 && latest.size+lineBytes > store.MaxFileBytes {
+		return filepath.Join(store.Dir, historyFileName(day, latest.index+1)), nil
+	}
+	return latest.path, nil
+}
+
+func (store *HistoryStore) prune(keep string) error {
+	files, err := store.files()
+	if err != nil {
+		return err
+	}
+	var total int64
+	for _, file := range files {
+		total += file.size
+	}
+	for _, file := range files {
+		if total <= store.MaxBytes || file.path == keep {
+			break
+		}
+		if err := os.Remove(file.path); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return err
+		}
+		total -= file.size
+	}
+	return nil
+}
+
+// files lists history files oldest first by day and rotation index.
+func (store *HistoryStore) files() ([]historyFile, error) {
+	entries, err := os.ReadDir(store.Dir)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	files := make([]historyFile, 0, len(entries))
+	for _, entry := range entries {
+		day, index, ok := parseHistoryFileName(entry.Name())
+		if !ok || !entry.Type().IsRegular() {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil {
+			continue
+		}
+		files = append(files, historyFile{path: filepath.Join(store.Dir, entry.Name()), day: day, index: index, size: info.Size()})
+	}
+	sort.Slice(files, func(i, j int) bool {
+		if files[i].day == files[j].day {
+			return files[i].index < files[j].index
+		}
+		return files[i].day < files[j].day
+	})
+	return files, nil
+}
+
+func historyFileName(day string, index int) string {
+	if index == 0 {
+		return historyFilePrefix + day + historyFileSuffix
+	}
+	return historyFilePrefix + day + "." + strconv.Itoa(index) + historyFileSuffix
+}
+
+func parseHistoryFileName(name string) (string, int, bool) {
+	rest, ok := strings.CutPrefix(name, historyFilePrefix)
+	if !ok {
+		return "", 0, false
+	}
+	rest, ok = strings.CutSuffix(rest, historyFileSuffix)
+	if !ok || len(rest) < len(historyDayLayout) {
+		return "", 0, false
+	}
+	day := rest[:len(historyDayLayout)]
+	if _, err := time.Parse(historyDayLayout, day); err != nil {
+		return "", 0, false
+	}
+	rest = rest[len(historyDayLayout):]
+	if rest == "" {
+		return day, 0, true
+	}
+	index, err := strconv.Atoi(strings.TrimPrefix(rest, "."))
+	if !strings.HasPrefix(rest, ".") || err != nil || index <= 0 {
+		return "", 0, false
+	}
+	return day, index, true
+}
+
+// HistoryTargetSummary aggregates one metric of one target across stored
+// runs.
+type HistoryTargetSummary struct {
+	Component string    `json:"component"`
+	Target    string    `json:"target"`
+	Metric    string    `json:"metric"`
+	Samples   int       `json:"samples"`
+	Min       float64   `json:"min"`
+	Median    float64   `json:"median"`
+	Max       float64   `json:"max"`
+	First     time.Time `json:"first"`
+	Last      time.Time `json:"last"`
+}
+
+// SummarizeHistory computes min/median/max per target for the latency and
+// throughput measurements that vary with time of day: ping-style RTT, TCP
+// handshake medians and speed test throughput.
+func SummarizeHistory(reports []StructuredReport) []HistoryTargetSummary {
+	type key struct{ component, target, metric string }
+	values := make(map[key][]float64)
+	bounds := make(map[key][2]time.Time)
+	record := func(k key, value float64, at time.Time) {
+		if value <= 0 || k.target == "" {
+			return
+		}
+		values[k] = append(values[k], value)
+		span, exists := bounds[k]
+		if !exists || at.Before(span[0]) {
+			span[0] = at
+		}
+		if !exists || at.After(span[1]) {
+			span[1] = at
+		}
+		bounds[k] = span
+	}
+	for _, report := range reports {
+		at := report.FinishedAt
+		for _, tcp := range report.TCP {
+			if tcp.Successful > 0 {
+				record(key{"tcp", tcp.Target.ID, "p50_ms"}, tcp.P50MS, at)
+			}
+		}
+		for _, component := range report.Components {
+			switch component.Name {
+			case "ping.icmp", "ping.telegram", "ping.web_tcp", "nt3.province_latency":
+				for _, result := range latencyResults(component.Payload) {
+					record(key{component.Name, latencyTargetID(result), "mean_ms"}, floatValue(result, "mean")/float64(time.Millisecond), at)
+				}
+			case "speed.registry":
+				root := payloadObject(component.Payload)
+				for _, raw := range append(arrayValue(root, "benchmarks"), arrayValue(root, "private_benchmarks")...) {
+					benchmark, _ := raw.(map[string]any)
+					target := fallback(stringValue(benchmark, "id"), stringValue(benchmark, "name"))
+					record(key{component.Name, target, "download_mbps"}, floatValue(benchmark, "download_mbps"), at)
+					record(key{component.Name, target, "upload_mbps"}, floatValue(benchmark, "upload_mbps"), at)
+				}
+			}
+		}
+	}
+	summaries := make([]HistoryTargetSummary, 0, len(values))
+	for k, samples := range values {
+		sort.Float64s(samples)
+		span := bounds[k]
+		summaries = append(summaries, HistoryTargetSummary{
+			Component: k.component, Target: k.target, Metric: k.metric, Samples: len(samples),
+			Min: samples[0], Median: percentileFloat(samples, 0.5), Max: samples[len(samples)-1],
+			First: span[0], Last: span[1],
+		})
+	}
+	sort.Slice(summaries, func(i, j int) bool {
+		left, right := summaries[i], summaries[j]
+		if left.Component != right.Component {
+			return left.Component < right.Component
+		}
+		if left.Target != right.Target {
+			return left.Target < right.Target
+		}
+		return left.Metric < right.Metric
+	})
+	return summaries
+}
+
+// RenderHistoryText renders history summaries grouped by component.
+func RenderHistoryText(config *Config, runs int, since time.Time, summaries []HistoryTargetSummary) string {
+	renderer := newStructuredTextRenderer(config)
+	renderer.section(renderer.pick("历史统计", "History Summary"))
+	renderer.row(renderer.pick("运行次数", "Runs"), strconv.Itoa(runs))
+	if !since.IsZero() {
+		renderer.row(renderer.pick("起始时间", "Since"), since.Format(time.RFC3339))
+	}
+	current := ""
+	var rows [][]string
+	flush := func() {
+		if current == "" {
+			return
+		}
+		title := fallback(renderer.componentTitle(current), current)
+		if current == "tcp" {
+			title = renderer.pick("TCP连接质量", "TCP Connection Quality")
+		}
+		renderer.section(title)
+		renderer.table([]string{renderer.pick("目标", "Target"), renderer.pick("指标", "Metric"), renderer.pick("次数", "Runs"), "Min", "Median", "Max"}, rows, []int{24, 14, 6, 10, 10, 10})
+		rows = nil
+	}
+	for _, summary := range summaries {
+		if summary.Component != current {
+			flush()
+			current = summary.Component
+		}
+		rows = append(rows, []string{
+			summary.Target, summary.Metric, strconv.Itoa(summary.Samples),
+			strconv.FormatFloat(summary.Min, 'f', 2, 64), strconv.FormatFloat(summary.Median, 'f', 2, 64), strconv.FormatFloat(summary.Max, 'f', 2, 64),
+		})
+	}
+	flush()
+	renderer.section("")
+	return renderer.builder.String()
+}
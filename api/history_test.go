@@ -0,0 +1,112 @@
+package api
+
+import (
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func historyFixtureReport(t *testing.T, finished time.Time, p50, mean float64) *StructuredReport {
+	t.Helper()
+	return &StructuredReport{
+		SchemaVersion: StructuredReportSchema, Status: ReportStatusOK, FinishedAt: finished, Text: strings.Repeat("x", 512),
+		Components: []ComponentReport{
+			componentFixture(t, "nt3.province_latency", ReportStatusOK, `[{"target":{"province_name":"Guangdong","carrier":"ct","ip_version":"ipv4"},"mean":`+
+				strconv.FormatInt(int64(mean*float64(time.Millisecond)), 10)+`}]`),
+		},
+		TCP: []TCPReport{{Target: TCPTarget{ID: "github"}, Attempts: 3, Successful: 3, P50MS: p50}},
+	}
+}
+
+func TestHistoryStoreAppendsLoadsAndSummarizesByTarget(t *testing.T) {
+	store := NewHistoryStore(t.TempDir(), 0)
+	day := time.Date(2026, 10, 18, 0, 0, 0, 0, time.UTC)
+	for index, sample := range []struct{ p50, mean float64 }{{10, 30}, {30, 90}, {20, 60}} {
+		if err := store.Append(historyFixtureReport(t, day.Add(time.Duration(index)*8*time.Hour), sample.p50, sample.mean)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := store.Append(historyFixtureReport(t, day.Add(-48*time.Hour), 500, 500)); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(filepath.Join(store.Dir, "goecs-2026-10-18.jsonl")); err != nil {
+		t.Fatalf("daily history file missing: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(store.Dir, "goecs-2026-10-18.1.jsonl"), []byte("{truncated\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	reports, skipped, err := store.Load(day.Add(-time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(reports) != 3 || skipped != 1 || reports[0].Text != "" || !reports[0].FinishedAt.Equal(day) {
+		t.Fatalf("unexpected history load: %d reports, %d skipped", len(reports), skipped)
+	}
+	summaries := SummarizeHistory(reports)
+	if len(summaries) != 2 {
+		t.Fatalf("unexpected summaries: %+v", summaries)
+	}
+	latency, tcp := summaries[0], summaries[1]
+	if latency.Component != "nt3.province_latency" || latency.Target != "Guangdong / ct / ipv4" || latency.Min != 30 || latency.Median != 60 || latency.Max != 90 {
+		t.Fatalf("unexpected latency summary: %+v", latency)
+	}
+	if tcp.Component != "tcp" || tcp.Target != "github" || tcp.Samples != 3 || tcp.Min != 10 || tcp.Median != 20 || tcp.Max != 30 {
+		t.Fatalf("unexpected TCP summary: %+v", tcp)
+	}
+	config := NewConfig("v-test")
+	config.Language = "en"
+	text := RenderHistoryText(config, len(reports), time.Time{}, summaries)
+	for _, want := range []string{"History Summary", "TCP Connection Quality", "Province Carrier Latency", "github", "20.00"} {
+		if !strings.Contains(text, want) {
+			t.Fatalf("history text is missing %q:\n%s", want, text)
+		}
+	}
+}
+
+func TestHistoryStoreRotatesAndBoundsDirectorySize(t *testing.T) {
+	store := NewHistoryStore(t.TempDir(), 4096)
+	store.MaxFileBytes = 1024
+	day := time.Date(2026, 10, 18, 0, 0, 0, 0, time.UTC)
+	for index := range 12 {
+		if err := store.Append(historyFixtureReport(t, day.Add(time.Duration(index)*time.Minute), 10, 10)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	files, err := store.files()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var total int64
+	for _, file := range files {
+		total += file.size
+	}
+	if len(files) < 2 || files[len(files)-1].index == 0 || total > store.MaxBytes {
+		t.Fatalf("history was not rotated and bounded: %+v total=%d", files, total)
+	}
+	if files[0].index == 0 {
+		t.Fatalf("oldest rotation was not pruned first: %+v", files)
+	}
+}
+
+func TestParseHistoryFileName(t *testing.T) {
+	for _, test := range []struct {
+		name  string
+		day   string
+		index int
+		ok    bool
+	}{
+		{name: "goecs-2026-10-18.jsonl", day: "2026-10-18", ok: true},
+		{name: "goecs-2026-10-18.3.jsonl", day: "2026-10-18", index: 3, ok: true},
+		{name: "goecs-2026-10-18.0.jsonl"},
+		{name: "goecs-2026-13-18.jsonl"},
+		{name: "other-2026-10-18.jsonl"},
+	} {
+		day, index, ok := parseHistoryFileName(test.name)
+		if day != test.day || index != test.index || ok != test.ok {
+			t.Fatalf("parseHistoryFileName(%q) = %q, %d, %v", test.name, day, index, ok)
+		}
+	}
+}
@@ -0,0 +1,271 @@
+package api
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var reportStatuses = []ReportStatus{
+	ReportStatusOK, ReportStatusPartial, ReportStatusUnavailable, ReportStatusTimeout,
+	ReportStatusCanceled, ReportStatusError, ReportStatusSkipped,
+}
+
+type metricSample struct {
+	labels []string
+	value  float64
+}
+
+type metricFamily struct {
+	name    string
+	help    string
+	samples []metricSample
+}
+
+// openMetricsSet keeps families in first-use order; OpenMetrics requires the
+// samples of one family to be contiguous.
+type openMetricsSet struct {
+	families []*metricFamily
+	index    map[string]*metricFamily
+}
+
+func (set *openMetricsSet) add(name, help string, value float64, labels ...string) {
+	if math.IsNaN(value) || math.IsInf(value, 0) {
+		return
+	}
+	family := set.index[name]
+	if family == nil {
+		family = &metricFamily{name: name, help: help}
+		set.index[name] = family
+		set.families = append(set.families, family)
+	}
+	family.samples = append(family.samples, metricSample{labels: labels, value: value})
+}
+
+// targetLabels makes target label values unique within one list of results.
+// Redacted targets all share "-" and a custom TCP target may reuse a built-in
+// ID; the second and later occurrences get a "#N" suffix so no two series of
+// a family share a label set.
+type targetLabels map[string]int
+
+func (labels targetLabels) unique(value string) string {
+	labels[value]++
+	if count := labels[value]; count > 1 {
+		return value + "#" + strconv.Itoa(count)
+	}
+	return value
+}
+
+func (set *openMetricsSet) status(name, help string, current ReportStatus, labels ...string) {
+	for _, status := range reportStatuses {
+		value := 0.0
+		if status == current {
+			value = 1
+		}
+		set.add(name, help, value, append(append([]string(nil), labels...), "status", string(status))...)
+	}
+}
+
+// WriteOpenMetrics flattens a structured report into OpenMetrics gauges
+// suitable for the node_exporter textfile collector. Values use base units
+// (seconds, bytes, bits per second). Status gauges follow the state-set
+// convention: one series per ReportStatus with the current one set to 1.
+func WriteOpenMetrics(w io.Writer, report *StructuredReport) error {
+	set := &openMetricsSet{index: make(map[string]*metricFamily)}
+	if report != nil {
+		collectReportMetrics(set, report)
+	}
+	buffered := bufio.NewWriter(w)
+	for _, family := range set.families {
+		buffered.WriteString("# TYPE " + family.name + " gauge\n")
+		buffered.WriteString("# HELP " + family.name + " " + family.help + "\n")
+		for _, sample := range family.samples {
+			buffered.WriteString(family.name)
+			if len(sample.labels) > 0 {
+				buffered.WriteByte('{')
+				for index := 0; index+1 < len(sample.labels); index += 2 {
+					if index > 0 {
+						buffered.WriteByte(',')
+					}
+					buffered.WriteString(sample.labels[index] + `="` + escapeMetricLabel(sample.labels[index+1]) + `"`)
+				}
+				buffered.WriteByte('}')
+			}
+			buffered.WriteString(" " + strconv.FormatFloat(sample.value, 'g', -1, 64) + "\n")
+		}
+	}
+	buffered.WriteString("# EOF\n")
+	return buffered.Flush()
+}
+
+func collectReportMetrics(set *openMetricsSet, report *StructuredReport) {
+	set.add("goecs_report_info", "Report producer and schema.", 1, "ecs_version", report.ECSVersion, "schema_version", report.SchemaVersion)
+	set.status("goecs_report_status", "Overall report status.", report.Status)
+	set.add("goecs_report_duration_seconds", "Wall-clock duration of the run.", float64(report.DurationMS)/1000)
+	if !report.FinishedAt.IsZero() {
+		set.add("goecs_report_finished_timestamp_seconds", "Unix time the run finished.", float64(report.FinishedAt.UnixMilli())/1000)
+	}
+	for _, section := range report.Sections {
+		if section.Enabled {
+			set.status("goecs_section_status", "Section status.", section.Status, "section", section.Name)
+		}
+	}
+	for _, component := range report.Components {
+		set.status("goecs_component_status", "Component status.", component.Status, "component", component.Name)
+		if component.DurationMS > 0 {
+			set.add("goecs_component_duration_seconds", "Component runtime.", float64(component.DurationMS)/1000, "component", component.Name)
+		}
+		collectComponentMetrics(set, component)
+	}
+	tcpTargets := targetLabels{}
+	for _, tcp := range report.TCP {
+		labels := []string{"component", "tcp", "target", tcpTargets.unique(tcp.Target.ID), "status", string(tcpReportStatus(tcp))}
+		if tcp.Successful > 0 {
+			set.add("goecs_tcp_connect_p50_seconds", "Median TCP handshake time.", tcp.P50MS/1000, labels...)
+			set.add("goecs_tcp_connect_p95_seconds", "95th percentile TCP handshake time.", tcp.P95MS/1000, labels...)
+			set.add("goecs_tcp_connect_p99_seconds", "99th percentile TCP handshake time.", tcp.P99MS/1000, labels...)
+			set.add("goecs_tcp_jitter_seconds", "Mean difference between consecutive TCP handshakes.", tcp.JitterMS/1000, labels...)
+		}
+		set.add("goecs_tcp_loss_ratio", "Share of failed TCP handshakes.", tcp.LossPercent/100, labels...)
+	}
+}
+
+func collectComponentMetrics(set *openMetricsSet, component ComponentReport) {
+	if len(component.Payload) == 0 {
+		return
+	}
+	status := string(component.Status)
+	root := payloadObject(component.Payload)
+	switch component.Name {
+	case "cputest":
+		set.add("goecs_cpu_events_per_second", "CPU benchmark score.", floatValue(root, "events_per_second"), "component", component.Name, "status", status)
+	case "memorytest":
+		for _, operation := range []string{"sequential_read", "sequential_write", "copy"} {
+			set.add("goecs_memory_bandwidth_bytes_per_second", "Memory bandwidth.", floatValue(root, operation+"_mbps")*(1<<20),
+				"component", component.Name, "operation", operation, "status", status)
+		}
+		set.add("goecs_memory_random_latency_seconds", "Memory random access latency.", floatValue(root, "random_latency_ns")/1e9, "component", component.Name, "status", status)
+	case "disktest":
+		targets := targetLabels{}
+		for _, raw := range arrayValue(root, "metrics") {
+			metric, _ := raw.(map[string]any)
+			labels := []string{"component", component.Name, "target", targets.unique(stringValue(metric, "scenario_id")), "status", status}
+			set.add("goecs_disk_iops", "Disk operations per second.", floatValue(metric, "iops"), labels...)
+			set.add("goecs_disk_bandwidth_bytes_per_second", "Disk throughput.", floatValue(metric, "bandwidth_bytes_per_second"), labels...)
+			set.add("goecs_disk_latency_p95_seconds", "95th percentile disk completion latency.", floatValue(metric, "latency_p95_ns")/1e9, labels...)
+		}
+	case "ping.icmp", "ping.telegram", "nt3.province_latency":
+		targets := targetLabels{}
+		for _, result := range latencyResults(component.Payload) {
+			labels := []string{"component", component.Name, "target", targets.unique(latencyTargetID(result)), "status", fallback(stringValue(result, "status"), status)}
+			if mean := floatValue(result, "mean"); mean > 0 {
+				set.add("goecs_ping_rtt_mean_seconds", "Mean round-trip time.", time.Duration(mean).Seconds(), labels...)
+				set.add("goecs_ping_rtt_p95_seconds", "95th percentile round-trip time.", time.Duration(floatValue(result, "p95")).Seconds(), labels...)
+			}
+			set.add("goecs_ping_loss_ratio", "Share of lost probes.", floatValue(result, "loss_percent")/100, labels...)
+		}
+	case TLSProbeComponent:
+		targets := targetLabels{}
+		for _, raw := range arrayValue(root, "results") {
+			result, _ := raw.(map[string]any)
+			labels := []string{"component", component.Name, "target", targets.unique(stringValue(objectValue(result, "target"), "id")), "status", stringValue(result, "status")}
+			if handshake := floatValue(result, "tls_ms"); handshake > 0 {
+				set.add("goecs_tls_handshake_seconds", "TLS handshake time.", handshake/1000, labels...)
+			}
+			if ttfb := floatValue(result, "ttfb_ms"); ttfb > 0 {
+				set.add("goecs_tls_ttfb_seconds", "Time to the first HTTP response byte.", ttfb/1000, labels...)
+			}
+			if notAfter, err := time.Parse(time.RFC3339, stringValue(result, "cert_not_after")); err == nil {
+				set.add("goecs_tls_cert_not_after_timestamp_seconds", "Leaf certificate expiry as Unix time.", float64(notAfter.Unix()), labels...)
+			}
+		}
+	case QUICProbeComponent:
+		var values []map[string]any
+		if json.Unmarshal(component.Payload, &values) != nil {
+			return
+		}
+		targets := targetLabels{}
+		for _, result := range values {
+			target := targets.unique(stringValue(objectValue(result, "target"), "id"))
+			for _, protocol := range []string{"quic", "tcp_tls"} {
+				stats := objectValue(result, protocol)
+				labels := []string{"component", component.Name, "target", target, "protocol", protocol, "status", stringValue(result, "status")}
+				if mean := floatValue(stats, "mean_ms"); mean > 0 {
+					set.add("goecs_quic_handshake_mean_seconds", "Mean handshake time per transport.", mean/1000, labels...)
+				}
+				if attempts := floatValue(stats, "attempts"); attempts > 0 {
+					set.add("goecs_quic_handshake_loss_ratio", "Share of failed handshakes per transport.", 1-floatValue(stats, "successful")/attempts, labels...)
+				}
+			}
+		}
+	case "speed.registry":
+		targets := targetLabels{}
+		for _, raw := range append(arrayValue(root, "benchmarks"), arrayValue(root, "private_benchmarks")...) {
+			benchmark, _ := raw.(map[string]any)
+			labels := []string{
+				"component", component.Name, "target", targets.unique(fallback(stringValue(benchmark, "id"), stringValue(benchmark, "name"))),
+				"source", fallback(stringValue(benchmark, "source"), "speedtest"), "status", fallback(stringValue(benchmark, "status"), status),
+			}
+			set.add("goecs_speed_download_bits_per_second", "Download throughput.", floatValue(benchmark, "download_mbps")*1e6, labels...)
+			set.add("goecs_speed_upload_bits_per_second", "Upload throughput.", floatValue(benchmark, "upload_mbps")*1e6, labels...)
+			set.add("goecs_speed_latency_seconds", "Speed test server latency.", floatValue(benchmark, "latency_ms")/1000, labels...)
+		}
+	}
+}
+
+// latencyTargetID names a latency result stably across runs. Province
+// targets carry no ID, so their province, carrier and IP version stand in.
+func latencyTargetID(result map[string]any) string {
+	target := objectValue(result, "target")
+	return fallback(stringValue(target, "id"), stringValue(target, "name"),
+		joinNonEmpty(stringValue(target, "province_name"), stringValue(target, "carrier"), stringValue(target, "ip_version")))
+}
+
+func tcpReportStatus(report TCPReport) ReportStatus {
+	switch {
+	case report.Successful == 0:
+		return ReportStatusUnavailable
+	case report.Successful < report.Attempts:
+		return ReportStatusPartial
+	default:
+		return ReportStatusOK
+	}
+}
+
+func escapeMetricLabel(value string) string {
+	return strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`).Replace(value)
+}
+
+// writeMetricsFile replaces path atomically so a textfile collector never
+// scrapes a partially written file. The file is world-readable because the
+// collector usually runs as a different user.
+func writeMetricsFile(ctx context.Context, path string, report *StructuredReport) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	file, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".*")
+	if err != nil {
+		return err
+	}
+	temporary := file.Name()
+	defer os.Remove(temporary)
+	if err := WriteOpenMetrics(file, report); err != nil {
+		_ = file.Close()
+		return err
+	}
+	if err := file.Chmod(0o644); err != nil {
+		_ = file.Close()
+		return err
+	}
+	if err := file.Close(); err != nil {
+		return err
+	}
+	return os.Rename(temporary, path)
+}
@@ -0,0 +1,124 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func metricsFixtureReport(t *testing.T) *StructuredReport {
+	t.Helper()
+	return &StructuredReport{
+		SchemaVersion: StructuredReportSchema, ECSVersion: "v-test", Status: ReportStatusPartial,
+		DurationMS: 1500, FinishedAt: time.Unix(1700000000, 0),
+		Sections: []SectionReport{{Name: "cpu", Enabled: true, Status: ReportStatusOK}, {Name: "gpu", Enabled: false, Status: ReportStatusSkipped}},
+		Components: []ComponentReport{
+			componentFixture(t, "cputest", ReportStatusOK, `{"events_per_second":1234.5}`),
+			componentFixture(t, "memorytest", ReportStatusOK, `{"sequential_read_mbps":1,"sequential_write_mbps":2,"copy_mbps":3,"random_latency_ns":90}`),
+			componentFixture(t, "disktest", ReportStatusOK, `{"metrics":[{"scenario_id":"4k_randread","iops":2000,"bandwidth_bytes_per_second":8192000,"latency_p95_ns":500000}]}`),
+			componentFixture(t, "ping.icmp", ReportStatusOK, `[{"target":{"id":"cf\"dns","name":"Cloudflare"},"status":"ok","mean":12000000,"p95":20000000,"loss_percent":25}]`),
+			componentFixture(t, "speed.registry", ReportStatusPartial, `{"benchmarks":[{"id":"1234","name":"Node","status":"available","download_mbps":900.5,"upload_mbps":100,"latency_ms":8}]}`),
+		},
+		TCP: []TCPReport{{Target: TCPTarget{ID: "github", Host: "github.com", Port: 443}, Attempts: 3, Successful: 2, LossPercent: 33.33, P50MS: 10, P95MS: 30}},
+	}
+}
+
+func TestWriteOpenMetricsFlattensStructuredReport(t *testing.T) {
+	var output bytes.Buffer
+	if err := WriteOpenMetrics(&output, metricsFixtureReport(t)); err != nil {
+		t.Fatal(err)
+	}
+	text := output.String()
+	for _, want := range []string{
+		`goecs_report_status{status="partial"} 1`,
+		`goecs_report_status{status="ok"} 0`,
+		`goecs_section_status{section="cpu",status="ok"} 1`,
+		`goecs_component_status{component="speed.registry",status="partial"} 1`,
+		`goecs_cpu_events_per_second{component="cputest",status="ok"} 1234.5`,
+		`goecs_memory_bandwidth_bytes_per_second{component="memorytest",operation="copy",status="ok"} 3.145728e+06`,
+		`goecs_disk_iops{component="disktest",target="4k_randread",status="ok"} 2000`,
+		`goecs_ping_rtt_mean_seconds{component="ping.icmp",target="cf\"dns",status="ok"} 0.012`,
+		`goecs_ping_loss_ratio{component="ping.icmp",target="cf\"dns",status="ok"} 0.25`,
+		`goecs_tcp_connect_p95_seconds{component="tcp",target="github",status="partial"} 0.03`,
+		`goecs_speed_download_bits_per_second{component="speed.registry",target="1234",source="speedtest",status="available"} 9.005e+08`,
+		"# TYPE goecs_tcp_loss_ratio gauge\n",
+	} {
+		if !strings.Contains(text, want) {
+			t.Fatalf("metrics output is missing %q:\n%s", want, text)
+		}
+	}
+	if strings.Contains(text, `section="gpu"`) || strings.Contains(text, "github.com") {
+		t.Fatalf("metrics exposed a disabled section or TCP host:\n%s", text)
+	}
+	if !strings.HasSuffix(text, "# EOF\n") {
+		t.Fatalf("metrics output is not terminated:\n%s", text)
+	}
+	seen := make(map[string]bool)
+	previous := ""
+	for _, line := range strings.Split(text, "\n") {
+		name, ok := strings.CutPrefix(line, "# TYPE ")
+		if !ok {
+			continue
+		}
+		name = strings.Fields(name)[0]
+		if seen[name] && name != previous {
+			t.Fatalf("metric family %s is not contiguous", name)
+		}
+		seen[name], previous = true, name
+	}
+}
+
+func TestWriteOpenMetricsKeepsLabelSetsUnique(t *testing.T) {
+	report := metricsFixtureReport(t)
+	report.Components = append(report.Components, componentFixture(t, "nt3.province_latency", ReportStatusOK,
+		`[{"target":{"province_name":"Beijing","carrier":"telecom","ip_version":"ipv4"},"status":"ok","mean":1000000,"loss_percent":0},`+
+			`{"target":{"province_name":"Shanghai","carrier":"telecom","ip_version":"ipv4"},"status":"ok","mean":2000000,"loss_percent":0}]`))
+	report.TCP = append(report.TCP, TCPReport{Target: TCPTarget{ID: "github", Host: "10.0.0.1", Port: 443}, Attempts: 3, Successful: 2, LossPercent: 33.33, P50MS: 5, P95MS: 6})
+	applyStructuredPrivacy(report)
+	var output bytes.Buffer
+	if err := WriteOpenMetrics(&output, report); err != nil {
+		t.Fatal(err)
+	}
+	seen := make(map[string]bool)
+	for _, line := range strings.Split(output.String(), "\n") {
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		series := line[:strings.LastIndex(line, " ")]
+		if seen[series] {
+			t.Fatalf("series %s is repeated:\n%s", series, output.String())
+		}
+		seen[series] = true
+	}
+	if !strings.Contains(output.String(), `target="github#2"`) {
+		t.Fatalf("duplicate TCP target was not disambiguated:\n%s", output.String())
+	}
+}
+
+func TestFinalizeRunResultWritesReadableMetricsFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "goecs.prom")
+	config := NewDefaultConfig()
+	config.FilePath = ""
+	config.EnableUpload = false
+	config.MetricsPath = path
+	finalized, err := FinalizeRunResultContext(context.Background(), NetCheckResult{}, config, &RunResult{Report: metricsFixtureReport(t)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil || finalized.MetricsPath != path || !bytes.HasSuffix(data, []byte("# EOF\n")) {
+		t.Fatalf("unexpected metrics file: %q result=%+v err=%v", data, finalized, err)
+	}
+	info, err := os.Stat(path)
+	if err != nil || info.Mode().Perm() != 0o644 {
+		t.Fatalf("unexpected metrics permissions: %v err=%v", info.Mode().Perm(), err)
+	}
+	entries, _ := os.ReadDir(filepath.Dir(path))
+	if len(entries) != 1 {
+		t.Fatalf("temporary metrics file was left behind: %v", entries)
+	}
+}
@@ -1,6 +1,8 @@
 package api
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
 	"net"
 	"net/url"
@@ -22,6 +24,19 @@ func applyStructuredPrivacy(report *StructuredReport) {
 		return
 	}
 	report.Text = ""
+	for index := range report.ConfigWarnings {
+		// Rejected values can be collector URLs or local paths.
+		issue := &report.ConfigWarnings[index]
+		if issue.Value != "" {
+			issue.Message = strings.ReplaceAll(issue.Message, issue.Value, privacyRedacted)
+			issue.Value = privacyRedacted
+		}
+		issue.Message = redactSensitiveText(issue.Message)
+	}
+	for index := range report.Assertions {
+		report.Assertions[index].Expression = redactSensitiveText(report.Assertions[index].Expression)
+		report.Assertions[index].Reason = redactSensitiveText(report.Assertions[index].Reason)
+	}
 	for index := range report.Sections {
 		report.Sections[index].Reason = redactSensitiveText(report.Sections[index].Reason)
 	}
@@ -33,8 +48,17 @@ func applyStructuredPrivacy(report *StructuredReport) {
 		report.Components[index].Payload = redactJSONPayload(report.Components[index].Payload)
 	}
 	for index := range report.TCP {
-		report.TCP[index].Target.Host = privacyRedacted
-		report.TCP[index].Target.Name = redactSensitiveText(report.TCP[index].Target.Name)
+		target := &report.TCP[index].Target
+		target.Host = privacyRedacted
+		if target.Category == CustomTCPCategory {
+			// Custom names and IDs describe private infrastructure. A digest
+			// keeps IDs stable for comparisons across private reports.
+			digest := sha256.Sum256([]byte(target.ID))
+			target.ID = CustomTCPCategory + "-" + hex.EncodeToString(digest[:4])
+			target.Name = privacyRedacted
+			continue
+		}
+		target.Name = redactSensitiveText(target.Name)
 	}
 }
 
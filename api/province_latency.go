@@ -0,0 +1,149 @@
+package api
+
+import (
+	"encoding/json"
+	"sort"
+	"strings"
+	"time"
+
+	nt3 "github.com/oneclickvirt/nt3/nt"
+)
+
+const ProvinceLatencySchema = "goecs.nt3/province-latency-v2"
+
+// provinceLatencyPayload is the v2 nt3.province_latency payload. v1 was the
+// bare results array; latencyResults reads both.
+type provinceLatencyPayload struct {
+	SchemaVersion string                      `json:"schema_version"`
+	Results       []nt3.ProvinceLatencyResult `json:"results"`
+	Carriers      []ProvinceLatencyAggregate  `json:"carriers"`
+	Regions       []ProvinceLatencyAggregate  `json:"regions"`
+}
+
+// ProvinceLatencyAggregate summarizes the province targets of one carrier or
+// one region for one IP version. MedianRTTMS is the median of the per-target
+// mean handshake times of reachable targets; LossPercent counts every attempt.
+// Best and Worst are the reachable targets with the lowest and highest mean.
+type ProvinceLatencyAggregate struct {
+	Carrier     string                 `json:"carrier,omitempty"`
+	Region      string                 `json:"region,omitempty"`
+	IPVersion   string                 `json:"ip_version"`
+	Targets     int                    `json:"targets"`
+	Reachable   int                    `json:"reachable"`
+	MedianRTTMS float64                `json:"median_rtt_ms,omitempty"`
+	LossPercent float64                `json:"loss_percent"`
+	Best        *ProvinceLatencyTarget `json:"best,omitempty"`
+	Worst       *ProvinceLatencyTarget `json:"worst,omitempty"`
+}
+
+type ProvinceLatencyTarget struct {
+	ProvinceCode string  `json:"province_code"`
+	ProvinceName string  `json:"province_name"`
+	Carrier      string  `json:"carrier"`
+	RTTMS        float64 `json:"rtt_ms"`
+}
+
+// provinceRegions groups province codes into four regions. North follows
+// China Unicom's ten northern provinces, where its network is strongest; the
+// rest of the country is split along the usual east/south/west lines.
+var provinceRegions = map[string]string{
+	"BJ": "north", "TJ": "north", "HE": "north", "SX": "north", "NM": "north",
+	"LN": "north", "JL": "north", "HL": "north", "SD": "north", "HA": "north",
+	"SH": "east", "JS": "east", "ZJ": "east", "AH": "east", "FJ": "east", "JX": "east",
+	"GD": "south", "GX": "south", "HI": "south", "HB": "south", "HN": "south",
+	"CQ": "west", "SC": "west", "GZ": "west", "YN": "west", "XZ": "west",
+	"SN": "west", "GS": "west", "QH": "west", "NX": "west", "XJ": "west",
+}
+
+var (
+	provinceCarrierOrder = []string{"ct", "cu", "cm"}
+	provinceRegionOrder  = []string{"north", "east", "south", "west"}
+)
+
+func newProvinceLatencyPayload(results []nt3.ProvinceLatencyResult) provinceLatencyPayload {
+	payload := provinceLatencyPayload{SchemaVersion: ProvinceLatencySchema, Results: results}
+	if payload.Results == nil {
+		payload.Results = []nt3.ProvinceLatencyResult{}
+	}
+	byCarrier := make(map[[2]string][]nt3.ProvinceLatencyResult)
+	byRegion := make(map[[2]string][]nt3.ProvinceLatencyResult)
+	versions := make(map[string]bool)
+	for _, result := range results {
+		version := result.Target.IPVersion
+		versions[version] = true
+		carrierKey := [2]string{strings.ToLower(result.Target.Carrier), version}
+		byCarrier[carrierKey] = append(byCarrier[carrierKey], result)
+		if region := provinceRegions[strings.ToUpper(result.Target.ProvinceCode)]; region != "" {
+			regionKey := [2]string{region, version}
+			byRegion[regionKey] = append(byRegion[regionKey], result)
+		}
+	}
+	for _, version := range []string{"ipv4", "ipv6"} {
+		if !versions[version] {
+			continue
+		}
+		for _, carrier := range provinceCarrierOrder {
+			if group := byCarrier[[2]string{carrier, version}]; len(group) > 0 {
+				aggregate := aggregateProvinceLatency(group)
+				aggregate.Carrier, aggregate.IPVersion = carrier, version
+				payload.Carriers = append(payload.Carriers, aggregate)
+			}
+		}
+		for _, region := range provinceRegionOrder {
+			if group := byRegion[[2]string{region, version}]; len(group) > 0 {
+				aggregate := aggregateProvinceLatency(group)
+				aggregate.Region, aggregate.IPVersion = region, version
+				payload.Regions = append(payload.Regions, aggregate)
+			}
+		}
+	}
+	return payload
+}
+
+func aggregateProvinceLatency(results []nt3.ProvinceLatencyResult) ProvinceLatencyAggregate {
+	aggregate := ProvinceLatencyAggregate{Targets: len(results)}
+	var means []float64
+	attempts, failed := 0, 0
+	for _, result := range results {
+		attempts += result.Attempts
+		failed += result.Failed
+		if result.Successful == 0 || result.Mean <= 0 {
+			continue
+		}
+		aggregate.Reachable++
+		mean := float64(result.Mean) / float64(time.Millisecond)
+		means = append(means, mean)
+		target := &ProvinceLatencyTarget{ProvinceCode: result.Target.ProvinceCode, ProvinceName: result.Target.ProvinceName, Carrier: result.Target.Carrier, RTTMS: mean}
+		if aggregate.Best == nil || mean < aggregate.Best.RTTMS {
+			aggregate.Best = target
+		}
+		if aggregate.Worst == nil || mean > aggregate.Worst.RTTMS {
+			aggregate.Worst = target
+		}
+	}
+	if len(means) > 0 {
+		sort.Float64s(means)
+		aggregate.MedianRTTMS = percentileFloat(means, 0.5)
+	}
+	if attempts > 0 {
+		aggregate.LossPercent = float64(failed) * 100 / float64(attempts)
+	}
+	return aggregate
+}
+
+// latencyResults returns the per-target results of a latency component,
+// accepting bare arrays (ping components, v1 province latency) and objects
+// with a "results" array (v2 province latency).
+func latencyResults(payload json.RawMessage) []map[string]any {
+	var values []map[string]any
+	if json.Unmarshal(payload, &values) == nil {
+		return values
+	}
+	var wrapped struct {
+		Results []map[string]any `json:"results"`
+	}
+	if json.Unmarshal(payload, &wrapped) == nil {
+		return wrapped.Results
+	}
+	return nil
+}
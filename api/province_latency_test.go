@@ -0,0 +1,63 @@
+package api
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	nt3model "github.com/oneclickvirt/nt3/model"
+	nt3 "github.com/oneclickvirt/nt3/nt"
+)
+
+func provinceLatencyFixture(code, name, carrier, version string, mean time.Duration, successful int) nt3.ProvinceLatencyResult {
+	return nt3.ProvinceLatencyResult{
+		Target:   nt3model.ProvinceLatencyTarget{ProvinceCode: code, ProvinceName: name, Carrier: carrier, IPVersion: version},
+		Attempts: 2, Successful: successful, Failed: 2 - successful, Mean: mean,
+	}
+}
+
+func TestProvinceLatencyPayloadAggregatesCarriersAndRegions(t *testing.T) {
+	payload := newProvinceLatencyPayload([]nt3.ProvinceLatencyResult{
+		provinceLatencyFixture("GD", "广东省", "ct", "ipv4", 30*time.Millisecond, 2),
+		provinceLatencyFixture("BJ", "北京市", "ct", "ipv4", 50*time.Millisecond, 2),
+		provinceLatencyFixture("SC", "四川省", "ct", "ipv4", 0, 0),
+		provinceLatencyFixture("GD", "广东省", "cu", "ipv4", 70*time.Millisecond, 1),
+		provinceLatencyFixture("GD", "广东省", "ct", "ipv6", 40*time.Millisecond, 2),
+	})
+	if len(payload.Carriers) != 3 || len(payload.Regions) != 4 {
+		t.Fatalf("unexpected aggregate groups: carriers=%+v regions=%+v", payload.Carriers, payload.Regions)
+	}
+	ct := payload.Carriers[0]
+	if ct.Carrier != "ct" || ct.IPVersion != "ipv4" || ct.Targets != 3 || ct.Reachable != 2 || ct.MedianRTTMS != 40 {
+		t.Fatalf("unexpected ct aggregate: %+v", ct)
+	}
+	if ct.LossPercent < 33.3 || ct.LossPercent > 33.4 || ct.Best.ProvinceCode != "GD" || ct.Worst.ProvinceCode != "BJ" {
+		t.Fatalf("unexpected ct loss or extremes: %+v best=%+v worst=%+v", ct, ct.Best, ct.Worst)
+	}
+	if payload.Carriers[2].IPVersion != "ipv6" || payload.Regions[3].IPVersion != "ipv6" || payload.Regions[3].Region != "south" {
+		t.Fatalf("IPv6 aggregates should follow IPv4: carriers=%+v regions=%+v", payload.Carriers, payload.Regions)
+	}
+	south := payload.Regions[1]
+	if south.Region != "south" || south.Targets != 2 || south.Best.Carrier != "ct" || south.Worst.Carrier != "cu" {
+		t.Fatalf("unexpected south aggregate: %+v", south)
+	}
+
+	encoded, err := json.Marshal(payload)
+	if err != nil {
+		t.Fatal(err)
+	}
+	component := ComponentReport{Name: "nt3.province_latency", SchemaVersion: ProvinceLatencySchema, Status: ReportStatusOK, Payload: encoded}
+	if results := latencyResults(component.Payload); len(results) != 5 {
+		t.Fatalf("latencyResults read %d v2 results", len(results))
+	}
+	config := NewConfig("v-test")
+	config.Language = "en"
+	config.Width = 120
+	text := renderStructuredRunText(config, nil, []ComponentReport{component}, nil)
+	for _, want := range []string{"广东省 / CT / ipv6", "CT / ipv4", "40.0 ms", "South / ipv6", "北京市 CT 50.0 ms"} {
+		if !strings.Contains(text, want) {
+			t.Fatalf("render missing %q:\n%s", want, text)
+		}
+	}
+}
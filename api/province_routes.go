@@ -0,0 +1,136 @@
+package api
+
+import (
+	"net/netip"
+	"strings"
+
+	nt3 "github.com/oneclickvirt/nt3/nt"
+)
+
+// Route classes inferred for nt3.province_routes results, from premium
+// international lines down to the carriers' ordinary backbones.
+const (
+	RouteClassCN2GIA  = "cn2_gia"
+	RouteClassCN2GT   = "cn2_gt"
+	RouteClass163     = "chinanet_163"
+	RouteClass9929    = "cu_9929"
+	RouteClass10099   = "cu_10099"
+	RouteClass4837    = "cu_4837"
+	RouteClassCMIN2   = "cm_cmin2"
+	RouteClassCMI     = "cm_cmi"
+	RouteClass9808    = "cm_9808"
+	RouteClassUnknown = "unknown"
+)
+
+// provinceRouteResult is one nt3.province_routes entry with the line type
+// inferred from its hops.
+type provinceRouteResult struct {
+	nt3.DetailedProvinceRouteResult
+	RouteClass string `json:"route_class"`
+}
+
+// routeClassPrefixes attributes well-known backbone ranges to their AS, for
+// hops whose geo lookup returned no ASN.
+var routeClassPrefixes = []struct {
+	asn    string
+	prefix netip.Prefix
+}{
+	{"4809", netip.MustParsePrefix("59.43.0.0/16")},
+	{"4134", netip.MustParsePrefix("202.97.0.0/16")},
+	{"9929", netip.MustParsePrefix("218.105.0.0/16")},
+	{"9929", netip.MustParsePrefix("210.51.0.0/16")},
+	{"58453", netip.MustParsePrefix("223.120.0.0/15")},
+}
+
+func classifyProvinceRoutes(results []nt3.DetailedProvinceRouteResult) []provinceRouteResult {
+	if results == nil {
+		return nil
+	}
+	classified := make([]provinceRouteResult, len(results))
+	for index, result := range results {
+		classified[index] = provinceRouteResult{DetailedProvinceRouteResult: result, RouteClass: classifyRoute(result.Target.Carrier, result.Hops)}
+	}
+	return classified
+}
+
+// classifyRoute infers the line type of a route towards one carrier. The
+// destination province always sits in the carrier's domestic network, so
+// only the premium or international AS a path crosses tells lines apart:
+// 59.43.x without 202.97.x is CN2 GIA and with it CN2 GT.
+func classifyRoute(carrier string, hops []nt3.ProvinceRouteHop) string {
+	seen := make(map[string]bool)
+	// 202.97.x is the 163 backbone; AS4134 alone also covers the provincial
+	// hops every China Telecom route ends in.
+	backbone163 := false
+	for _, hop := range hops {
+		if asn := strings.TrimPrefix(strings.ToUpper(strings.TrimSpace(hop.ASN)), "AS"); asn != "" {
+			seen[asn] = true
+		}
+		address, err := netip.ParseAddr(hop.Address)
+		if err != nil {
+			continue
+		}
+		for _, known := range routeClassPrefixes {
+			if known.prefix.Contains(address) {
+				seen[known.asn] = true
+				backbone163 = backbone163 || known.asn == "4134"
+			}
+		}
+	}
+	switch strings.ToLower(carrier) {
+	case "ct":
+		switch {
+		case seen["4809"] && !backbone163:
+			return RouteClassCN2GIA
+		case seen["4809"]:
+			return RouteClassCN2GT
+		case seen["4134"]:
+			return RouteClass163
+		}
+	case "cu":
+		switch {
+		case seen["9929"]:
+			return RouteClass9929
+		case seen["10099"]:
+			return RouteClass10099
+		case seen["4837"]:
+			return RouteClass4837
+		}
+	case "cm":
+		switch {
+		case seen["58807"]:
+			return RouteClassCMIN2
+		case seen["58453"]:
+			return RouteClassCMI
+		case seen["9808"]:
+			return RouteClass9808
+		}
+	}
+	return RouteClassUnknown
+}
+
+// routeClassLabel is the conventional name of a route class.
+func routeClassLabel(class string) string {
+	switch class {
+	case RouteClassCN2GIA:
+		return "CN2 GIA"
+	case RouteClassCN2GT:
+		return "CN2 GT"
+	case RouteClass163:
+		return "163"
+	case RouteClass9929:
+		return "9929"
+	case RouteClass10099:
+		return "10099"
+	case RouteClass4837:
+		return "4837"
+	case RouteClassCMIN2:
+		return "CMIN2"
+	case RouteClassCMI:
+		return "CMI"
+	case RouteClass9808:
+		return "9808"
+	default:
+		return class
+	}
+}
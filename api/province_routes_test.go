@@ -0,0 +1,75 @@
+package api
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	nt3model "github.com/oneclickvirt/nt3/model"
+	nt3 "github.com/oneclickvirt/nt3/nt"
+)
+
+func TestClassifyRouteRecognizesCarrierLines(t *testing.T) {
+	hops := func(values ...string) []nt3.ProvinceRouteHop {
+		result := make([]nt3.ProvinceRouteHop, 0, len(values))
+		for index, value := range values {
+			hop := nt3.ProvinceRouteHop{Hop: index + 1}
+			if strings.HasPrefix(strings.ToUpper(value), "AS") {
+				hop.ASN = value
+			} else {
+				hop.Address = value
+			}
+			result = append(result, hop)
+		}
+		return result
+	}
+	for _, test := range []struct {
+		name, carrier string
+		hops          []nt3.ProvinceRouteHop
+		want          string
+	}{
+		{"cn2 gia by prefix", "ct", hops("203.0.113.1", "59.43.246.1", "59.43.180.1", "AS4134"), RouteClassCN2GIA},
+		{"cn2 gt", "ct", hops("59.43.246.1", "202.97.12.1", "AS4134"), RouteClassCN2GT},
+		{"163", "ct", hops("202.97.12.1", "AS4134"), RouteClass163},
+		{"cn2 by asn", "ct", hops("AS4809", "AS4134"), RouteClassCN2GIA},
+		{"9929 by prefix", "cu", hops("218.105.2.1", "AS4837"), RouteClass9929},
+		{"4837", "cu", hops("as4837"), RouteClass4837},
+		{"10099", "cu", hops("AS10099", "AS4837"), RouteClass10099},
+		{"cmin2", "cm", hops("AS58807", "AS9808"), RouteClassCMIN2},
+		{"cmi by prefix", "cm", hops("223.120.2.1", "AS9808"), RouteClassCMI},
+		{"9808", "cm", hops("AS9808"), RouteClass9808},
+		{"no hops", "ct", nil, RouteClassUnknown},
+		{"foreign carrier asn", "cm", hops("AS4809"), RouteClassUnknown},
+	} {
+		if got := classifyRoute(test.carrier, test.hops); got != test.want {
+			t.Errorf("%s: classifyRoute = %q, want %q", test.name, got, test.want)
+		}
+	}
+}
+
+func TestRoutePayloadRendersRouteClassSummary(t *testing.T) {
+	route := func(province, carrier string, hops ...nt3.ProvinceRouteHop) nt3.DetailedProvinceRouteResult {
+		return nt3.DetailedProvinceRouteResult{Target: nt3model.ProvinceLatencyTarget{ProvinceName: province, Carrier: carrier, IPVersion: "ipv4"}, Status: "ok", Hops: hops}
+	}
+	encoded, err := json.Marshal(classifyProvinceRoutes([]nt3.DetailedProvinceRouteResult{
+		route("广东", "ct", nt3.ProvinceRouteHop{Hop: 1, Address: "59.43.1.1"}),
+		route("上海", "ct", nt3.ProvinceRouteHop{Hop: 1, Address: "59.43.1.2"}),
+		route("北京", "ct", nt3.ProvinceRouteHop{Hop: 1, Address: "202.97.1.1"}),
+		route("北京", "cu"),
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(encoded), `"route_class":"cn2_gia"`) || !strings.Contains(string(encoded), `"hops":[{"hop":1`) {
+		t.Fatalf("route_class must sit beside the nt3 fields: %s", encoded)
+	}
+	config := NewConfig("v-test")
+	config.Language = "en"
+	config.Width = 120
+	text := renderStructuredRunText(config, nil, []ComponentReport{{Name: "nt3.province_routes", Status: ReportStatusOK, Payload: encoded}}, nil)
+	for _, want := range []string{"CN2 GIA ×2, 163 ×1", "CU / ipv4", "Unknown ×1"} {
+		if !strings.Contains(text, want) {
+			t.Fatalf("render missing %q:\n%s", want, text)
+		}
+	}
+}
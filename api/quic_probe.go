@@ -0,0 +1,283 @@
+package api
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net"
+	"sort"
+	"strconv"
+	"time"
+
+	pingmodel "github.com/oneclickvirt/pingtest/model"
+	"github.com/quic-go/quic-go"
+)
+
+const (
+	QUICProbeComponent = "quic.http3"
+	QUICProbeSchema    = "goecs.quic/http3-v1"
+)
+
+// HandshakeStats summarizes repeated handshakes over one transport.
+type HandshakeStats struct {
+	Attempts   int            `json:"attempts"`
+	Successful int            `json:"successful"`
+	MinMS      float64        `json:"min_ms,omitempty"`
+	MeanMS     float64        `json:"mean_ms,omitempty"`
+	P50MS      float64        `json:"p50_ms,omitempty"`
+	Errors     map[string]int `json:"errors,omitempty"`
+}
+
+// QUICProbeResult compares an HTTP/3 QUIC handshake on UDP with a TCP+TLS
+// handshake to the same host. Status is ok, partial, udp_blocked when only
+// TCP+TLS succeeds, or unreachable when neither does. DeltaMS is the QUIC
+// mean minus the TCP+TLS mean and is set only when both succeeded.
+type QUICProbeResult struct {
+	Target  TCPTarget      `json:"target"`
+	Status  string         `json:"status"`
+	QUIC    HandshakeStats `json:"quic"`
+	TCPTLS  HandshakeStats `json:"tcp_tls"`
+	DeltaMS *float64       `json:"quic_minus_tcp_tls_ms,omitempty"`
+}
+
+type quicProbeConfig struct {
+	attempts    int
+	timeout     time.Duration
+	concurrency int
+	rootCAs     *x509.CertPool
+	dialQUIC    func(context.Context, string, *tls.Config, time.Duration) error
+	dialTCPTLS  func(context.Context, string, *tls.Config) error
+}
+
+// websiteQUICTargets returns the ping.web_tcp host set as HTTPS targets.
+func websiteQUICTargets() []TCPTarget {
+	websites := pingmodel.WebsiteTCPTargets()
+	targets := make([]TCPTarget, 0, len(websites))
+	for _, website := range websites {
+		targets = append(targets, TCPTarget{
+			ID: targetID(website.Name, website.Host, 443), Name: website.Name,
+			Host: website.Host, Port: 443, Category: website.Category,
+		})
+	}
+	return targets
+}
+
+const (
+	quicMaxConcurrency = 16
+	quicMinTimeout     = 3 * time.Second
+)
+
+// quicProbeBudget is the worst-case runtime of probing targets when every
+// handshake times out, as happens on both transports to unreachable hosts
+// and on QUIC wherever UDP is blocked, plus a little slack for scheduling.
+func quicProbeBudget(targets int, config quicProbeConfig) time.Duration {
+	concurrency := max(config.concurrency, 1)
+	waves := (max(targets, 0) + concurrency - 1) / concurrency
+	return time.Duration(waves*max(config.attempts, 1))*2*config.timeout + 5*time.Second
+}
+
+// fitQUICProbe trims the plan until its worst case fits budget: more workers
+// first, then shorter handshake timeouts down to quicMinTimeout, then fewer
+// attempts. A plan that still does not fit is returned as trimmed as it gets
+// and the component reports partial when the budget runs out.
+func fitQUICProbe(targets int, budget time.Duration, config quicProbeConfig) quicProbeConfig {
+	for quicProbeBudget(targets, config) > budget {
+		switch {
+		case config.concurrency < min(targets, quicMaxConcurrency):
+			config.concurrency = min(config.concurrency*2, targets, quicMaxConcurrency)
+		case config.timeout > quicMinTimeout:
+			config.timeout = max(config.timeout-time.Second, quicMinTimeout)
+		case config.attempts > 1:
+			config.attempts--
+		default:
+			return config
+		}
+	}
+	return config
+}
+
+func runQUICProbeComponent(ctx context.Context, targets []TCPTarget, config quicProbeConfig) ComponentReport {
+	started := time.Now()
+	if config.attempts <= 0 {
+		config.attempts = 1
+	}
+	if config.timeout <= 0 {
+		config.timeout = 5 * time.Second
+	}
+	if config.dialQUIC == nil {
+		config.dialQUIC = dialQUICHandshake
+	}
+	if config.dialTCPTLS == nil {
+		config.dialTCPTLS = dialTCPTLSHandshake
+	}
+	results := make([]QUICProbeResult, len(targets))
+	forEachProbeTarget(ctx, len(targets), config.concurrency, func(index int) {
+		results[index] = runOneQUICProbe(ctx, targets[index], config)
+	})
+	unprobed := "canceled"
+	if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		unprobed = "timeout"
+	}
+	quicReachable, tcpReachable := 0, 0
+	for index := range results {
+		if results[index].Status == "" {
+			results[index] = QUICProbeResult{Target: targets[index], Status: unprobed}
+			continue
+		}
+		if results[index].QUIC.Successful > 0 {
+			quicReachable++
+		}
+		if results[index].TCPTLS.Successful > 0 {
+			tcpReachable++
+		}
+	}
+	status := quicComponentStatus(ctx, results)
+	report := componentPayload(QUICProbeComponent, QUICProbeSchema, status, started, results, nil)
+	if status != ReportStatusOK {
+		report.Reason = fmt.Sprintf("QUIC reached %d/%d targets; TCP+TLS reached %d/%d", quicReachable, len(targets), tcpReachable, len(targets))
+		if unprobed == "timeout" {
+			report.Reason += "; the time budget ran out"
+		}
+	}
+	return report
+}
+
+// quicComponentStatus reports partial rather than timeout when the budget
+// ran out after at least one target answered on either transport.
+func quicComponentStatus(ctx context.Context, results []QUICProbeResult) ReportStatus {
+	if status, done := contextComponentStatus(ctx); done {
+		if status == ReportStatusTimeout {
+			for _, result := range results {
+				if result.QUIC.Successful > 0 || result.TCPTLS.Successful > 0 {
+					return ReportStatusPartial
+				}
+			}
+		}
+		return status
+	}
+	if len(results) == 0 {
+		return ReportStatusUnavailable
+	}
+	ok, quicReachable := 0, 0
+	for _, result := range results {
+		if result.Status == "ok" {
+			ok++
+		}
+		if result.QUIC.Successful > 0 {
+			quicReachable++
+		}
+	}
+	switch {
+	case quicReachable == 0:
+		return ReportStatusUnavailable
+	case ok < len(results):
+		return ReportStatusPartial
+	default:
+		return ReportStatusOK
+	}
+}
+
+func runOneQUICProbe(ctx context.Context, target TCPTarget, config quicProbeConfig) QUICProbeResult {
+	address := net.JoinHostPort(target.Host, strconv.Itoa(target.Port))
+	result := QUICProbeResult{Target: target}
+	// Alternate the transports so slow drift on the path affects both.
+	var quicLatencies, tcpLatencies []float64
+	result.QUIC.Errors, result.TCPTLS.Errors = make(map[string]int), make(map[string]int)
+	for range config.attempts {
+		if ctx.Err() != nil {
+			break
+		}
+		quicLatencies = append(quicLatencies, measureHandshake(&result.QUIC, func() error {
+			return config.dialQUIC(ctx, address, &tls.Config{ServerName: target.Host, NextProtos: []string{"h3"}, RootCAs: config.rootCAs}, config.timeout)
+		})...)
+		tcpLatencies = append(tcpLatencies, measureHandshake(&result.TCPTLS, func() error {
+			handshakeCtx, cancel := context.WithTimeout(ctx, config.timeout)
+			defer cancel()
+			return config.dialTCPTLS(handshakeCtx, address, &tls.Config{ServerName: target.Host, NextProtos: []string{"h2", "http/1.1"}, RootCAs: config.rootCAs})
+		})...)
+	}
+	summarizeHandshakes(&result.QUIC, quicLatencies)
+	summarizeHandshakes(&result.TCPTLS, tcpLatencies)
+	switch {
+	case result.QUIC.Successful > 0 && result.TCPTLS.Successful > 0:
+		delta := result.QUIC.MeanMS - result.TCPTLS.MeanMS
+		result.DeltaMS = &delta
+		result.Status = "partial"
+		if result.QUIC.Successful == result.QUIC.Attempts && result.TCPTLS.Successful == result.TCPTLS.Attempts {
+			result.Status = "ok"
+		}
+	case result.TCPTLS.Successful > 0:
+		result.Status = "udp_blocked"
+	case result.QUIC.Successful > 0:
+		result.Status = "partial"
+	default:
+		result.Status = "unreachable"
+	}
+	return result
+}
+
+// measureHandshake runs one handshake, records its outcome in stats and
+// returns its latency when it succeeded.
+func measureHandshake(stats *HandshakeStats, handshake func() error) []float64 {
+	stats.Attempts++
+	started := time.Now()
+	err := handshake()
+	elapsed := float64(time.Since(started).Microseconds()) / 1000
+	if err != nil {
+		stats.Errors[classifyQUICError(err)]++
+		return nil
+	}
+	stats.Successful++
+	return []float64{elapsed}
+}
+
+func summarizeHandshakes(stats *HandshakeStats, latencies []float64) {
+	if len(stats.Errors) == 0 {
+		stats.Errors = nil
+	}
+	if len(latencies) == 0 {
+		return
+	}
+	sort.Float64s(latencies)
+	stats.MinMS = latencies[0]
+	for _, value := range latencies {
+		stats.MeanMS += value
+	}
+	stats.MeanMS /= float64(len(latencies))
+	stats.P50MS = percentileFloat(latencies, 0.50)
+}
+
+func classifyQUICError(err error) string {
+	var transportErr *quic.TransportError
+	var applicationErr *quic.ApplicationError
+	var versionErr *quic.VersionNegotiationError
+	var verifyErr *tls.CertificateVerificationError
+	switch {
+	case errors.As(err, &verifyErr):
+		return "cert_invalid"
+	case errors.As(err, &transportErr), errors.As(err, &applicationErr), errors.As(err, &versionErr):
+		return "quic_error"
+	}
+	return classifyTCPError(err)
+}
+
+func dialQUICHandshake(ctx context.Context, address string, tlsConfig *tls.Config, timeout time.Duration) error {
+	handshakeCtx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	conn, err := quic.DialAddr(handshakeCtx, address, tlsConfig, &quic.Config{HandshakeIdleTimeout: timeout})
+	if err != nil {
+		return err
+	}
+	return conn.CloseWithError(0, "")
+}
+
+func dialTCPTLSHandshake(ctx context.Context, address string, tlsConfig *tls.Config) error {
+	conn, err := (&net.Dialer{}).DialContext(ctx, "tcp", address)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	return tls.Client(conn, tlsConfig).HandshakeContext(ctx)
+}
@@ -0,0 +1,110 @@
+package api
+
+import (
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestQUICProbeComponentComparesTransportsPerTarget(t *testing.T) {
+	targets := []TCPTarget{
+		{ID: "both", Name: "Both", Host: "both.test", Port: 443},
+		{ID: "udp-blocked", Name: "UDP Blocked", Host: "udp-blocked.test", Port: 443},
+	}
+	report := runQUICProbeComponent(context.Background(), targets, quicProbeConfig{
+		attempts: 2, timeout: time.Second, concurrency: 2,
+		dialQUIC: func(_ context.Context, address string, config *tls.Config, _ time.Duration) error {
+			if config.NextProtos[0] != "h3" {
+				t.Errorf("QUIC ALPN = %v", config.NextProtos)
+			}
+			if strings.HasPrefix(address, "udp-blocked") {
+				return context.DeadlineExceeded
+			}
+			return nil
+		},
+		dialTCPTLS: func(context.Context, string, *tls.Config) error { return nil },
+	})
+	if report.Name != QUICProbeComponent || report.SchemaVersion != QUICProbeSchema || report.Status != ReportStatusPartial {
+		t.Fatalf("unexpected QUIC component: %+v", report)
+	}
+	if report.Reason != "QUIC reached 1/2 targets; TCP+TLS reached 2/2" {
+		t.Fatalf("reason = %q", report.Reason)
+	}
+	var results []QUICProbeResult
+	if err := json.Unmarshal(report.Payload, &results); err != nil {
+		t.Fatal(err)
+	}
+	both, blocked := results[0], results[1]
+	if both.Status != "ok" || both.QUIC.Successful != 2 || both.TCPTLS.Successful != 2 || both.DeltaMS == nil {
+		t.Fatalf("unexpected dual-stack result: %+v", both)
+	}
+	if blocked.Status != "udp_blocked" || blocked.QUIC.Errors["timeout"] != 2 || blocked.DeltaMS != nil {
+		t.Fatalf("unexpected blocked result: %+v", blocked)
+	}
+}
+
+func TestQUICProbeComponentHonorsCanceledContext(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	report := runQUICProbeComponent(ctx, []TCPTarget{{ID: "one", Host: "one.test", Port: 443}}, quicProbeConfig{
+		attempts: 3, concurrency: 1,
+		dialQUIC:   func(context.Context, string, *tls.Config, time.Duration) error { return errors.New("unexpected dial") },
+		dialTCPTLS: func(context.Context, string, *tls.Config) error { return errors.New("unexpected dial") },
+	})
+	if report.Status != ReportStatusCanceled {
+		t.Fatalf("canceled QUIC component status = %q", report.Status)
+	}
+}
+
+func TestQUICProbeBudgetCoversTimedOutHandshakes(t *testing.T) {
+	config := quicProbeConfig{attempts: 3, timeout: 5 * time.Second, concurrency: 8}
+	// 40 targets in 5 waves of 3 attempts, each waiting out both transports.
+	if budget := quicProbeBudget(40, config); budget < 150*time.Second {
+		t.Fatalf("QUIC budget %s cannot finish 40 blocked targets", budget)
+	}
+	if budget := quicProbeBudget(0, config); budget <= 0 || budget > 10*time.Second {
+		t.Fatalf("empty QUIC plan budget = %s", budget)
+	}
+	fitted := fitQUICProbe(40, 60*time.Second, config)
+	if budget := quicProbeBudget(40, fitted); budget > 60*time.Second || fitted.attempts < 1 || fitted.timeout < quicMinTimeout {
+		t.Fatalf("fitted plan %+v needs %s", fitted, budget)
+	}
+	if fitted := fitQUICProbe(4, 60*time.Second, config); fitted.attempts != 3 || fitted.timeout != 5*time.Second || fitted.concurrency != 8 {
+		t.Fatalf("a plan that fits was changed: %+v", fitted)
+	}
+}
+
+func TestQUICProbeComponentIsPartialWhenBudgetRunsOut(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	targets := []TCPTarget{
+		{ID: "fast", Host: "fast.test", Port: 443},
+		{ID: "slow", Host: "slow.test", Port: 443},
+		{ID: "late", Host: "late.test", Port: 443},
+	}
+	report := runQUICProbeComponent(ctx, targets, quicProbeConfig{
+		attempts: 1, timeout: time.Second, concurrency: 1,
+		dialQUIC: func(ctx context.Context, address string, _ *tls.Config, _ time.Duration) error {
+			if strings.HasPrefix(address, "fast") {
+				return nil
+			}
+			<-ctx.Done()
+			return ctx.Err()
+		},
+		dialTCPTLS: func(context.Context, string, *tls.Config) error { return nil },
+	})
+	if report.Status != ReportStatusPartial || !strings.HasSuffix(report.Reason, "the time budget ran out") {
+		t.Fatalf("exhausted QUIC budget: status=%q reason=%q", report.Status, report.Reason)
+	}
+	var results []QUICProbeResult
+	if err := json.Unmarshal(report.Payload, &results); err != nil {
+		t.Fatal(err)
+	}
+	if results[0].Status != "ok" || results[2].Status != "timeout" {
+		t.Fatalf("unexpected results: %+v", results)
+	}
+}
@@ -0,0 +1,162 @@
+package api
+
+import (
+	"fmt"
+	"html"
+	"strings"
+
+	"github.com/oneclickvirt/ecs/internal/analysis"
+)
+
+const (
+	RenderFormatText     = "text"
+	RenderFormatMarkdown = "markdown"
+	RenderFormatHTML     = "html"
+)
+
+// htmlReportStyle keeps the HTML report a single self-contained file.
+const htmlReportStyle = `body{font-family:system-ui,-apple-system,"Segoe UI",sans-serif;max-width:960px;margin:2rem auto;padding:0 1rem;color:#1f2328}
+details{border:1px solid #d0d7de;border-radius:6px;margin:.75rem 0;padding:.25rem .75rem}
+summary{cursor:pointer;font-weight:600;padding:.35rem 0}
+table{border-collapse:collapse;width:100%;margin:.5rem 0;font-size:.9rem}
+th,td{border-bottom:1px solid #eaeef2;padding:.3rem .5rem;text-align:left}
+.row{padding:.15rem 0}.label{display:inline-block;min-width:10rem;color:#57606a}
+.badge{border-radius:1rem;padding:.05rem .55rem;font-size:.8rem;font-weight:500;color:#fff;background:#6e7781}
+.badge-ok{background:#1a7f37}.badge-partial{background:#9a6700}.badge-skipped{background:#8c959f}
+.badge-error,.badge-unavailable,.badge-timeout,.badge-canceled{background:#cf222e}
+`
+
+// RenderStructuredReport re-renders a saved report without running any test.
+// The language comes from config, so a report collected in one language can
+// be rendered in the other, the stored summary included.
+func RenderStructuredReport(config *Config, report *StructuredReport, format string) (string, error) {
+	if report == nil {
+		return "", fmt.Errorf("report is required")
+	}
+	renderer := newStructuredTextRenderer(config)
+	switch strings.ToLower(strings.TrimSpace(format)) {
+	case "", RenderFormatText:
+	case RenderFormatMarkdown, "md":
+		renderer.format = renderMarkdown
+	case RenderFormatHTML:
+		renderer.format = renderHTML
+		title := renderer.pick("VPS融合怪测试", "VPS Fusion Monster Test")
+		lang := renderer.pick("zh", "en")
+		renderer.builder.WriteString(`<!DOCTYPE html>` + "\n" + `<html lang="` + lang + `"><head><meta charset="utf-8">` +
+			`<meta name="viewport" content="width=device-width,initial-scale=1"><title>` + html.EscapeString(title) + "</title>\n<style>\n" +
+			htmlReportStyle + "</style></head><body>\n")
+	default:
+		return "", fmt.Errorf("unsupported render format %q", format)
+	}
+	// The header names the version that collected the report, not the one
+	// rendering it.
+	if config != nil && report.ECSVersion != "" {
+		copied := *config
+		copied.EcsVersion = report.ECSVersion
+		config = &copied
+	}
+	renderer.report(config, report.DataFiles, report.Components, report.TCP)
+	renderer.configWarnings(report.ConfigWarnings)
+	renderer.assertions(report.Assertions)
+	if len(report.Summary) > 0 {
+		renderer.summary(analysis.RenderSummary(config, report.Summary))
+	}
+	if !report.StartedAt.IsZero() && !report.FinishedAt.IsZero() {
+		renderer.timing(report.StartedAt, report.FinishedAt)
+	}
+	if renderer.format == renderHTML {
+		renderer.closeSection()
+		renderer.builder.WriteString("</body></html>\n")
+	}
+	return renderer.builder.String(), nil
+}
+
+// configWarnings lists the values validation corrected before the run. The
+// Chinese messages are not saved, so both languages show the English ones.
+func (renderer *structuredTextRenderer) configWarnings(issues []ConfigIssue) {
+	if len(issues) == 0 {
+		return
+	}
+	renderer.section(renderer.pick("配置警告", "Configuration Warnings"))
+	for _, issue := range issues {
+		renderer.literalLine(issue.Message)
+	}
+}
+
+func (renderer *structuredTextRenderer) assertions(results []AssertionResult) {
+	if len(results) == 0 {
+		return
+	}
+	renderer.section(renderer.pick("断言", "Assertions"))
+	for _, result := range results {
+		renderer.literalLine(result.String())
+	}
+}
+
+// literalLine writes text that may contain Markdown syntax, such as the
+// comparison operators and field names of an assertion.
+func (renderer *structuredTextRenderer) literalLine(text string) {
+	if renderer.format == renderMarkdown {
+		text = markdownEscape(text)
+	}
+	renderer.line(text)
+}
+
+// summary writes the Markdown table of a summarizer as the run prints it;
+// HTML keeps it preformatted in its own section.
+func (renderer *structuredTextRenderer) summary(text string) {
+	text = strings.TrimRight(text, "\n")
+	if strings.TrimSpace(text) == "" {
+		return
+	}
+	if renderer.format == renderHTML {
+		renderer.section(renderer.pick("测试总结", "Summary"))
+		renderer.builder.WriteString("<pre>" + html.EscapeString(text) + "</pre>\n")
+		return
+	}
+	if renderer.format == renderMarkdown {
+		renderer.builder.WriteString("\n")
+	}
+	renderer.builder.WriteString(text + "\n")
+}
+
+func (renderer *structuredTextRenderer) markdownTable(headers []string, rows [][]string) {
+	cells := func(values []string) string {
+		escaped := make([]string, len(values))
+		for index, value := range values {
+			escaped[index] = markdownEscape(compactText(value))
+		}
+		return "| " + strings.Join(escaped, " | ") + " |\n"
+	}
+	renderer.builder.WriteString("\n" + cells(headers))
+	renderer.builder.WriteString("|" + strings.Repeat(" --- |", len(headers)) + "\n")
+	for _, row := range rows {
+		if len(row) == len(headers) {
+			renderer.builder.WriteString(cells(row))
+		}
+	}
+	renderer.builder.WriteString("\n")
+}
+
+func (renderer *structuredTextRenderer) htmlTable(headers []string, rows [][]string) {
+	renderer.builder.WriteString("<table><thead><tr>")
+	for _, header := range headers {
+		renderer.builder.WriteString("<th>" + html.EscapeString(header) + "</th>")
+	}
+	renderer.builder.WriteString("</tr></thead><tbody>\n")
+	for _, row := range rows {
+		if len(row) != len(headers) {
+			continue
+		}
+		renderer.builder.WriteString("<tr>")
+		for _, value := range row {
+			renderer.builder.WriteString("<td>" + html.EscapeString(compactText(value)) + "</td>")
+		}
+		renderer.builder.WriteString("</tr>\n")
+	}
+	renderer.builder.WriteString("</tbody></table>\n")
+}
+
+func markdownEscape(value string) string {
+	return strings.NewReplacer(`\`, `\\`, "|", `\|`, "*", `\*`, "_", `\_`, "`", "\\`", "<", "&lt;", ">", "&gt;").Replace(value)
+}
@@ -0,0 +1,109 @@
+package api
+
+import (
+	"encoding/json"
+	"html"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/oneclickvirt/ecs/internal/analysis"
+)
+
+func renderFixtureReport(t *testing.T) *StructuredReport {
+	t.Helper()
+	started := time.Date(2026, 10, 18, 8, 0, 0, 0, time.UTC)
+	return &StructuredReport{
+		SchemaVersion: StructuredReportSchema, ECSVersion: "v-saved", Status: ReportStatusPartial,
+		StartedAt: started, FinishedAt: started.Add(90 * time.Second),
+		Components: []ComponentReport{
+			componentFixture(t, "cputest", ReportStatusOK, `{"schema_version":"goecs.cpu/v1","status":"ok","events_per_second":1234.5}`),
+			componentFixture(t, "speed.registry", ReportStatusPartial, `{"schema_version":"goecs.speed/v1","benchmarks":[
+				{"name":"Node <A> | B","status":"available","latency_ms":5.2,"download_mbps":900.1,"upload_mbps":700.2}
+			]}`),
+		},
+		TCP: []TCPReport{{Target: TCPTarget{Name: "Example TCP"}, Attempts: 3, Successful: 3, MeanMS: 12.3}},
+	}
+}
+
+func TestRenderStructuredReportTextMatchesRunLayout(t *testing.T) {
+	config := NewConfig("v-binary")
+	config.Language = "en"
+	report := renderFixtureReport(t)
+	text, err := RenderStructuredReport(config, report, RenderFormatText)
+	if err != nil {
+		t.Fatal(err)
+	}
+	saved := *config
+	saved.EcsVersion = "v-saved"
+	if !strings.HasPrefix(text, renderStructuredRunText(&saved, nil, report.Components, report.TCP)) {
+		t.Fatalf("text render diverged from the run layout:\n%s", text)
+	}
+	if !strings.Contains(text, "CPU Benchmark") || !strings.Contains(text, "1 min 30 sec") || strings.Contains(text, "v-binary") {
+		t.Fatalf("text render is missing English sections or timing:\n%s", text)
+	}
+}
+
+func TestRenderStructuredReportMarkdownAndHTML(t *testing.T) {
+	config := NewConfig("v-binary")
+	config.Language = "zh"
+	report := renderFixtureReport(t)
+	markdown, err := RenderStructuredReport(config, report, RenderFormatMarkdown)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{"## VPS融合怪测试", "## CPU性能测试 `正常`", "## 就近节点测速 `部分可用`", `Node &lt;A&gt; \| B`, "| --- |"} {
+		if !strings.Contains(markdown, want) {
+			t.Fatalf("markdown is missing %q:\n%s", want, markdown)
+		}
+	}
+	page, err := RenderStructuredReport(config, report, RenderFormatHTML)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{"<!DOCTYPE html>", "<style>", `<span class="badge badge-partial">部分可用</span>`, "Node &lt;A&gt; | B", "</body></html>\n"} {
+		if !strings.Contains(page, want) {
+			t.Fatalf("HTML is missing %q:\n%s", want, page)
+		}
+	}
+	if strings.Count(page, "<details") != strings.Count(page, "</details>") || strings.Contains(page, "<script") {
+		t.Fatalf("HTML sections are unbalanced or not self-contained:\n%s", page)
+	}
+	if _, err := RenderStructuredReport(config, report, "pdf"); err == nil {
+		t.Fatal("unsupported format was accepted")
+	}
+}
+
+func TestRenderStructuredReportIncludesWarningsAssertionsAndSummary(t *testing.T) {
+	config := NewConfig("v-binary")
+	config.Language = "en"
+	report := renderFixtureReport(t)
+	report.ConfigWarnings = []ConfigIssue{{Flag: "tcp-timeout", Value: "1m0s", Applied: "30s", Severity: "warning", Message: "tcp-timeout 1m0s exceeds 30s, using 30s"}}
+	report.Assertions = []AssertionResult{
+		{Expression: "cputest.events_per_second>=1000", Passed: true, Actual: "1234.5"},
+		{Expression: "tcp[missing].mean_ms<50", Reason: "no tcp target matches missing"},
+	}
+	report.Summary = json.RawMessage(`{"schema_version":"` + analysis.SummarySchema + `","bandwidth":{"peak_mbps":1800}}`)
+	for _, format := range []string{RenderFormatText, RenderFormatMarkdown, RenderFormatHTML} {
+		rendered, err := RenderStructuredReport(config, report, format)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, want := range []string{"Configuration Warnings", "tcp-timeout 1m0s exceeds 30s", "Assertions", "PASS cputest.events_per_second>=1000 (actual 1234.5)", "FAIL tcp[missing].mean_ms<50: no tcp target matches missing", "| Peak Bandwidth | > 1.80Gbps |"} {
+			switch format {
+			case RenderFormatMarkdown:
+				if !strings.HasPrefix(want, "|") {
+					want = markdownEscape(want)
+				}
+			case RenderFormatHTML:
+				want = html.EscapeString(want)
+			}
+			if !strings.Contains(rendered, want) {
+				t.Fatalf("%s render is missing %q:\n%s", format, want, rendered)
+			}
+		}
+		if strings.Index(rendered, "| Peak Bandwidth |") > strings.Index(rendered, "Cost Time") {
+			t.Fatalf("%s render put the summary after the timing:\n%s", format, rendered)
+		}
+	}
+}
@@ -75,11 +75,6 @@ func RunAllTestsContext(parent context.Context, preCheck utils.NetCheckResult, c
 		extras := collectStructuredExtras(ctx, preCheck, &structuredConfig)
 		status, reason := structuredRunStatus(ctx, extras.err)
 		output = renderStructuredRunText(config, extras.dataFiles, extras.components, extras.tcp)
-		if ctx.Err() == nil && config.AnalyzeResult {
-			progressStarted(ctx, "analysis")
-			output = runner.AppendAnalysisSummary(config, output, "", &outputMutex)
-			progressCompleted(ctx, "analysis", ReportStatusOK, "")
-		}
 		endTime := time.Now()
 		sections := sectionReports(config, preCheck, extras, status, reason)
 		status = aggregateReportStatus(status, sections)
@@ -87,13 +82,22 @@ func RunAllTestsContext(parent context.Context, preCheck utils.NetCheckResult, c
 			SchemaVersion: StructuredReportSchema, ECSVersion: config.EcsVersion,
 			Status: status, StartedAt: startTime, FinishedAt: endTime,
 			DurationMS: endTime.Sub(startTime).Milliseconds(), DeepMode: config.DeepMode,
-			PrivacyMode: config.PrivacyMode, Data: extras.data, DataFiles: extras.dataFiles,
+			PrivacyMode: config.PrivacyMode, ConfigWarnings: configWarnings(config),
+			Data: extras.data, DataFiles: extras.dataFiles,
 			Components: extras.components, TCP: extras.tcp, Sections: sections, Text: output,
 		}
+		report.Assertions = configAssertionResults(config, report)
+		// The summary reads the unredacted payloads and carries no identity
+		// of its own, so it is kept in privacy mode as well.
+		summaryText := ""
+		if ctx.Err() == nil && config.AnalyzeResult {
+			summaryText = summarizeReport(ctx, config, report, summarizeComponents)
+		}
 		if config.PrivacyMode {
 			applyStructuredPrivacy(report)
 			output = renderStructuredRunText(config, report.DataFiles, report.Components, report.TCP)
 		}
+		output = appendSummaryText(output, summaryText)
 		output = appendStructuredTimeText(output, config, startTime, endTime)
 		if !config.PrivacyMode {
 			report.Text = output
@@ -142,10 +146,6 @@ func RunAllTestsContext(parent context.Context, preCheck utils.NetCheckResult, c
 	case <-ctx.Done():
 		workflowFinished = false
 	}
-	if workflowFinished && config.AnalyzeResult {
-		output = runner.AppendAnalysisSummary(config, output, tempOutput, &outputMutex)
-	}
-
 	var extras structuredExtras
 	if workflowFinished {
 		select {
@@ -185,10 +185,16 @@ func RunAllTestsContext(parent context.Context, preCheck utils.NetCheckResult, c
 		SchemaVersion: StructuredReportSchema, ECSVersion: config.EcsVersion,
 		Status: status, StartedAt: startTime, FinishedAt: endTime,
 		DurationMS: endTime.Sub(startTime).Milliseconds(), DeepMode: config.DeepMode,
-		PrivacyMode: config.PrivacyMode, Data: extras.data, DataFiles: extras.dataFiles,
+		PrivacyMode: config.PrivacyMode, ConfigWarnings: configWarnings(config),
+		Data: extras.data, DataFiles: extras.dataFiles,
 		Components: extras.components, TCP: extras.tcp,
 		Sections: sections, Text: output,
 	}
+	report.Assertions = configAssertionResults(config, report)
+	if workflowFinished && config.AnalyzeResult {
+		output = runner.AppendAnalysisSummary(summarizeReport(ctx, config, report, summarizeLegacyText), output, tempOutput, &outputMutex)
+		report.Text = output
+	}
 	if config.PrivacyMode {
 		applyStructuredPrivacy(report)
 		output = report.Text
@@ -0,0 +1,385 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+)
+
+type RunState string
+
+const (
+	RunStateRunning  RunState = "running"
+	RunStateFinished RunState = "finished"
+)
+
+// RunStatus is the body returned for runs that have not produced a report
+// yet and for run creation and cancellation requests.
+type RunStatus struct {
+	ID        string       `json:"id"`
+	State     RunState     `json:"state"`
+	Status    ReportStatus `json:"status,omitempty"`
+	Canceled  bool         `json:"canceled,omitempty"`
+	StartedAt time.Time    `json:"started_at"`
+	Events    string       `json:"events"`
+	Report    string       `json:"report"`
+}
+
+type serverRunFunc func(context.Context, NetCheckResult, *Config, ProgressObserver) *RunResult
+
+// Server exposes structured runs over HTTP for remote orchestration:
+//
+//	POST   /runs             start a run with a JSON Config body
+//	GET    /runs/{id}        final StructuredReport, or RunStatus while running
+//	GET    /runs/{id}/events ProgressEvent stream as Server-Sent Events
+//	DELETE /runs/{id}        cancel a run through its context
+//
+// Runs are kept in memory. A finished run is dropped an hour after it
+// finished, or once more than 32 runs have finished, oldest first; its ID
+// then returns 404. Running runs are never dropped. Results are never written
+// to disk or uploaded; callers fetch the report instead.
+type Server struct {
+	preCheck     NetCheckResult
+	version      string
+	run          serverRunFunc
+	mux          *http.ServeMux
+	ctx          context.Context
+	stop         context.CancelFunc
+	mu           sync.Mutex
+	runs         map[string]*serverRun
+	wg           sync.WaitGroup
+	keepFinished int
+	finishedTTL  time.Duration
+}
+
+const (
+	serverFinishedRunLimit = 32
+	serverFinishedRunTTL   = time.Hour
+)
+
+type serverRun struct {
+	id         string
+	startedAt  time.Time
+	finishedAt time.Time
+	cancel     context.CancelFunc
+	mu         sync.Mutex
+	events     []ProgressEvent
+	changed    chan struct{}
+	canceled   bool
+	result     *RunResult
+	done       chan struct{}
+}
+
+func NewServer(preCheck NetCheckResult, version string) *Server {
+	return newServerWithRunner(preCheck, version, RunAllTestsContextWithProgress)
+}
+
+func newServerWithRunner(preCheck NetCheckResult, version string, run serverRunFunc) *Server {
+	if version == "" {
+		version = DefaultVersion
+	}
+	ctx, stop := context.WithCancel(context.Background())
+	server := &Server{
+		preCheck: preCheck, version: version, run: run, mux: http.NewServeMux(),
+		ctx: ctx, stop: stop, runs: make(map[string]*serverRun),
+		keepFinished: serverFinishedRunLimit, finishedTTL: serverFinishedRunTTL,
+	}
+	server.mux.HandleFunc("POST /runs", server.createRun)
+	server.mux.HandleFunc("GET /runs/{id}", server.getRun)
+	server.mux.HandleFunc("GET /runs/{id}/events", server.streamRunEvents)
+	server.mux.HandleFunc("DELETE /runs/{id}", server.cancelRun)
+	return server
+}
+
+func (server *Server) ServeHTTP(writer http.ResponseWriter, request *http.Request) {
+	server.mux.ServeHTTP(writer, request)
+}
+
+// Close cancels every active run and waits for them to return.
+func (server *Server) Close() {
+	server.stop()
+	server.wg.Wait()
+}
+
+// StartRun starts a run outside the HTTP surface, for embedders that already
+// hold a Config.
+func (server *Server) StartRun(config *Config) (RunStatus, error) {
+	if config == nil {
+		config = NewConfig(server.version)
+	}
+	if err := server.ctx.Err(); err != nil {
+		return RunStatus{}, errors.New("server is shutting down")
+	}
+	id, err := newRunID()
+	if err != nil {
+		return RunStatus{}, err
+	}
+	// Interactive and file side effects belong to the CLI; a served run only
+	// produces the in-memory report.
+	config.MenuMode = false
+	config.JSONPath = ""
+	config.EcsVersion = server.version
+	ctx, cancel := context.WithCancel(server.ctx)
+	run := &serverRun{id: id, startedAt: time.Now(), cancel: cancel, changed: make(chan struct{}), done: make(chan struct{})}
+	server.mu.Lock()
+	server.pruneRuns(time.Now())
+	server.runs[id] = run
+	server.mu.Unlock()
+	server.wg.Add(1)
+	go func() {
+		defer server.wg.Done()
+		defer cancel()
+		result := server.run(ctx, server.preCheck, config, run.observe)
+		run.finish(result)
+		server.mu.Lock()
+		server.pruneRuns(time.Now())
+		server.mu.Unlock()
+	}()
+	return run.status(), nil
+}
+
+func (server *Server) createRun(writer http.ResponseWriter, request *http.Request) {
+	requested := NewConfig(server.version)
+	body, err := io.ReadAll(io.LimitReader(request.Body, 1<<20))
+	if err != nil {
+		writeServerError(writer, http.StatusBadRequest, err)
+		return
+	}
+	if len(bytes.TrimSpace(body)) > 0 {
+		if err := json.Unmarshal(body, requested); err != nil {
+			writeServerError(writer, http.StatusBadRequest, fmt.Errorf("decode config: %w", err))
+			return
+		}
+	}
+	config := serverRunConfig(server.version, requested)
+	if config.Strict {
+		if err := config.ValidateParams().Err(); err != nil {
+			writeServerError(writer, http.StatusBadRequest, err)
+			return
+		}
+	}
+	status, err := server.StartRun(config)
+	if err != nil {
+		writeServerError(writer, http.StatusServiceUnavailable, err)
+		return
+	}
+	writer.Header().Set("Location", status.Report)
+	writeServerJSON(writer, http.StatusAccepted, status)
+}
+
+// serverRunConfig copies the run parameters a client may choose into a
+// fresh Config. Everything else keeps the server's defaults: paths, devices
+// and proxies name resources on the server, and upload and data source
+// settings decide where the server connects or sends results.
+func serverRunConfig(version string, requested *Config) *Config {
+	config := NewConfig(version)
+	config.Language, config.OnlyChinaTest, config.OnlyIpInfoCheck = requested.Language, requested.OnlyChinaTest, requested.OnlyIpInfoCheck
+	config.CpuTestMethod, config.CpuTestThreadMode = requested.CpuTestMethod, requested.CpuTestThreadMode
+	config.MemoryTestMethod, config.DiskTestMethod = requested.MemoryTestMethod, requested.DiskTestMethod
+	config.DiskMultiCheck, config.AutoChangeDiskMethod = requested.DiskMultiCheck, requested.AutoChangeDiskMethod
+	config.Nt3CheckType, config.Nt3Location = requested.Nt3CheckType, requested.Nt3Location
+	config.SpNum, config.Width = requested.SpNum, requested.Width
+	config.BasicStatus, config.CpuTestStatus, config.MemoryTestStatus = requested.BasicStatus, requested.CpuTestStatus, requested.MemoryTestStatus
+	config.DiskTestStatus, config.UtTestStatus, config.SecurityTestStatus = requested.DiskTestStatus, requested.UtTestStatus, requested.SecurityTestStatus
+	config.EmailTestStatus, config.BacktraceStatus, config.Nt3Status = requested.EmailTestStatus, requested.BacktraceStatus, requested.Nt3Status
+	config.SpeedTestStatus, config.PingTestStatus, config.TgdcTestStatus = requested.SpeedTestStatus, requested.PingTestStatus, requested.TgdcTestStatus
+	config.WebTestStatus, config.QUICTestStatus = requested.WebTestStatus, requested.QUICTestStatus
+	config.TCPProbeStatus, config.TLSProbeStatus = requested.TCPProbeStatus, requested.TLSProbeStatus
+	config.TCPTargets, config.TCPAttempts, config.TCPTimeout = requested.TCPTargets, requested.TCPAttempts, requested.TCPTimeout
+	config.TCPConcurrency, config.TCPInterval = requested.TCPConcurrency, requested.TCPInterval
+	config.AnalyzeResult, config.DeepMode, config.PrivacyMode = requested.AnalyzeResult, requested.DeepMode, requested.PrivacyMode
+	config.MaxDuration, config.HardwareBudget, config.DeepBurnDuration = requested.MaxDuration, requested.HardwareBudget, requested.DeepBurnDuration
+	config.DataOffline = requested.DataOffline
+	config.UnlockTestRegion, config.UnlockTestShowIP = requested.UnlockTestRegion, requested.UnlockTestShowIP
+	config.UnlockTestIPVersion, config.UnlockTestConcurrency = requested.UnlockTestIPVersion, requested.UnlockTestConcurrency
+	config.Strict, config.Assertions, config.AssertPreset = requested.Strict, requested.Assertions, requested.AssertPreset
+	return config
+}
+
+func (server *Server) getRun(writer http.ResponseWriter, request *http.Request) {
+	run := server.lookup(writer, request)
+	if run == nil {
+		return
+	}
+	run.mu.Lock()
+	result := run.result
+	run.mu.Unlock()
+	if result == nil || len(result.JSON) == 0 {
+		writeServerJSON(writer, http.StatusAccepted, run.status())
+		return
+	}
+	writer.Header().Set("Content-Type", "application/json")
+	writer.WriteHeader(http.StatusOK)
+	_, _ = writer.Write(result.JSON)
+}
+
+func (server *Server) cancelRun(writer http.ResponseWriter, request *http.Request) {
+	run := server.lookup(writer, request)
+	if run == nil {
+		return
+	}
+	run.mu.Lock()
+	if run.result == nil {
+		run.canceled = true
+	}
+	run.mu.Unlock()
+	run.cancel()
+	writeServerJSON(writer, http.StatusAccepted, run.status())
+}
+
+// streamRunEvents replays the events recorded so far and then follows the
+// run until it finishes, so late subscribers see the complete history.
+func (server *Server) streamRunEvents(writer http.ResponseWriter, request *http.Request) {
+	run := server.lookup(writer, request)
+	if run == nil {
+		return
+	}
+	flusher, ok := writer.(http.Flusher)
+	if !ok {
+		writeServerError(writer, http.StatusInternalServerError, errors.New("streaming is not supported"))
+		return
+	}
+	writer.Header().Set("Content-Type", "text/event-stream")
+	writer.Header().Set("Cache-Control", "no-cache")
+	writer.WriteHeader(http.StatusOK)
+	flusher.Flush()
+	sent := 0
+	for {
+		run.mu.Lock()
+		pending := append([]ProgressEvent(nil), run.events[sent:]...)
+		changed := run.changed
+		finished := run.result != nil
+		run.mu.Unlock()
+		for _, event := range pending {
+			if err := writeServerEvent(writer, "progress", event); err != nil {
+				return
+			}
+		}
+		sent += len(pending)
+		if finished {
+			_ = writeServerEvent(writer, "done", run.status())
+			flusher.Flush()
+			return
+		}
+		flusher.Flush()
+		select {
+		case <-changed:
+		case <-request.Context().Done():
+			return
+		}
+	}
+}
+
+func (server *Server) lookup(writer http.ResponseWriter, request *http.Request) *serverRun {
+	id := request.PathValue("id")
+	server.mu.Lock()
+	run := server.runs[id]
+	server.mu.Unlock()
+	if run == nil {
+		writeServerError(writer, http.StatusNotFound, fmt.Errorf("run %q not found", id))
+	}
+	return run
+}
+
+// pruneRuns drops finished runs past the retention limits. The caller holds
+// server.mu.
+func (server *Server) pruneRuns(now time.Time) {
+	type finishedRun struct {
+		id string
+		at time.Time
+	}
+	var finished []finishedRun
+	for id, run := range server.runs {
+		run.mu.Lock()
+		at := run.finishedAt
+		run.mu.Unlock()
+		if at.IsZero() {
+			continue
+		}
+		if server.finishedTTL > 0 && now.Sub(at) >= server.finishedTTL {
+			delete(server.runs, id)
+			continue
+		}
+		finished = append(finished, finishedRun{id: id, at: at})
+	}
+	if server.keepFinished <= 0 || len(finished) <= server.keepFinished {
+		return
+	}
+	sort.Slice(finished, func(i, j int) bool { return finished[i].at.Before(finished[j].at) })
+	for _, run := range finished[:len(finished)-server.keepFinished] {
+		delete(server.runs, run.id)
+	}
+}
+
+func (run *serverRun) observe(event ProgressEvent) {
+	run.mu.Lock()
+	defer run.mu.Unlock()
+	run.events = append(run.events, event)
+	close(run.changed)
+	run.changed = make(chan struct{})
+}
+
+func (run *serverRun) finish(result *RunResult) {
+	if result == nil {
+		result = &RunResult{}
+	}
+	run.mu.Lock()
+	defer run.mu.Unlock()
+	run.result = result
+	run.finishedAt = time.Now()
+	close(run.changed)
+	run.changed = make(chan struct{})
+	close(run.done)
+}
+
+func (run *serverRun) status() RunStatus {
+	run.mu.Lock()
+	defer run.mu.Unlock()
+	status := RunStatus{
+		ID: run.id, State: RunStateRunning, Canceled: run.canceled, StartedAt: run.startedAt,
+		Events: "/runs/" + run.id + "/events", Report: "/runs/" + run.id,
+	}
+	if run.result != nil {
+		status.State = RunStateFinished
+		if run.result.Report != nil {
+			status.Status = run.result.Report.Status
+		}
+	}
+	return status
+}
+
+func newRunID() (string, error) {
+	var value [8]byte
+	if _, err := rand.Read(value[:]); err != nil {
+		return "", fmt.Errorf("generate run id: %w", err)
+	}
+	return hex.EncodeToString(value[:]), nil
+}
+
+func writeServerEvent(writer io.Writer, name string, value any) error {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(writer, "event: %s\ndata: %s\n\n", name, data)
+	return err
+}
+
+func writeServerJSON(writer http.ResponseWriter, code int, value any) {
+	writer.Header().Set("Content-Type", "application/json")
+	writer.WriteHeader(code)
+	_ = json.NewEncoder(writer).Encode(value)
+}
+
+func writeServerError(writer http.ResponseWriter, code int, err error) {
+	writeServerJSON(writer, code, map[string]string{"error": err.Error()})
+}
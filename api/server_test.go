@@ -0,0 +1,218 @@
+package api
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestServerStreamsProgressAndReturnsReport(t *testing.T) {
+	release := make(chan struct{})
+	var received *Config
+	server := newServerWithRunner(NetCheckResult{}, "v-test", func(ctx context.Context, _ NetCheckResult, config *Config, observer ProgressObserver) *RunResult {
+		received = config
+		observer(ProgressEvent{Section: "cpu", Phase: ProgressStarted, At: time.Now()})
+		<-release
+		observer(ProgressEvent{Section: "cpu", Phase: ProgressCompleted, Status: ReportStatusOK, At: time.Now()})
+		report := &StructuredReport{SchemaVersion: StructuredReportSchema, ECSVersion: config.EcsVersion, Status: ReportStatusOK}
+		data, _ := report.JSON()
+		return &RunResult{Report: report, JSON: data}
+	})
+	defer server.Close()
+	httpServer := httptest.NewServer(server)
+	defer httpServer.Close()
+
+	response, err := http.Post(httpServer.URL+"/runs", "application/json", strings.NewReader(`{"Language":"en","MenuMode":true,"JSONPath":"/tmp/out.json",`+
+		`"TCPTargetsFile":"/etc/shadow","DataCacheDir":"/tmp/cache","DataOverlayDir":"/etc","HistoryDir":"/tmp/history","MetricsPath":"/tmp/m.prom",`+
+		`"DiskTestPath":"/mnt/secret","DeepDiskPaths":"/dev/sda","DeepSMARTDevices":"/dev/nvme0","DeepGPUDevice":"/dev/dri/card0",`+
+		`"UnlockTestInterface":"eth1","UnlockTestDNSServers":"10.0.0.53","UnlockTestHTTPProxy":"http://10.0.0.1:3128","UnlockTestSOCKSProxy":"socks5://10.0.0.1:1080",`+
+		`"DataCDNBase":"https://data.example","UploadEndpoint":"https://collector.example","SpNum":5,"PingTestStatus":true}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var status RunStatus
+	if err := json.NewDecoder(response.Body).Decode(&status); err != nil {
+		t.Fatal(err)
+	}
+	response.Body.Close()
+	if response.StatusCode != http.StatusAccepted || status.ID == "" || status.State != RunStateRunning {
+		t.Fatalf("unexpected create response %d: %+v", response.StatusCode, status)
+	}
+
+	pending, err := http.Get(httpServer.URL + status.Report)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pending.Body.Close()
+	if pending.StatusCode != http.StatusAccepted {
+		t.Fatalf("running report returned %d", pending.StatusCode)
+	}
+
+	events, err := http.Get(httpServer.URL + status.Events)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer events.Body.Close()
+	if events.Header.Get("Content-Type") != "text/event-stream" {
+		t.Fatalf("unexpected event content type %q", events.Header.Get("Content-Type"))
+	}
+	close(release)
+	var names []string
+	scanner := bufio.NewScanner(events.Body)
+	for scanner.Scan() {
+		if name, ok := strings.CutPrefix(scanner.Text(), "event: "); ok {
+			names = append(names, name)
+		}
+	}
+	if strings.Join(names, ",") != "progress,progress,done" {
+		t.Fatalf("unexpected SSE events: %v", names)
+	}
+	defaults := NewConfig("v-test")
+	if received.Language != "en" || received.MenuMode || received.JSONPath != "" || received.EcsVersion != "v-test" {
+		t.Fatalf("served config was not sanitized: %+v", received)
+	}
+	if received.TCPTargetsFile != "" || received.DataCacheDir != defaults.DataCacheDir || received.DataOverlayDir != "" ||
+		received.HistoryDir != "" || received.MetricsPath != "" || received.DiskTestPath != "" || received.DeepDiskPaths != "" ||
+		received.DeepSMARTDevices != "" || received.DeepGPUDevice != "" {
+		t.Fatalf("served config kept client file paths: %+v", received)
+	}
+	if received.UnlockTestInterface != "" || received.UnlockTestDNSServers != "" || received.UnlockTestHTTPProxy != "" ||
+		received.UnlockTestSOCKSProxy != "" || received.DataCDNBase != defaults.DataCDNBase || received.UploadEndpoint != "" {
+		t.Fatalf("served config kept client network settings: %+v", received)
+	}
+	if received.SpNum != 5 || !received.PingTestStatus {
+		t.Fatalf("served config dropped run parameters: %+v", received)
+	}
+
+	final, err := http.Get(httpServer.URL + status.Report)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer final.Body.Close()
+	var report StructuredReport
+	if err := json.NewDecoder(final.Body).Decode(&report); err != nil {
+		t.Fatal(err)
+	}
+	if final.StatusCode != http.StatusOK || report.SchemaVersion != StructuredReportSchema || report.ECSVersion != "v-test" {
+		t.Fatalf("unexpected final report %d: %+v", final.StatusCode, report)
+	}
+}
+
+func TestServerDeleteCancelsRunContext(t *testing.T) {
+	server := newServerWithRunner(NetCheckResult{}, "v-test", func(ctx context.Context, _ NetCheckResult, _ *Config, _ ProgressObserver) *RunResult {
+		<-ctx.Done()
+		report := &StructuredReport{SchemaVersion: StructuredReportSchema, Status: ReportStatusCanceled}
+		data, _ := report.JSON()
+		return &RunResult{Report: report, JSON: data}
+	})
+	defer server.Close()
+	status, err := server.StartRun(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	request := httptest.NewRequest(http.MethodDelete, "/runs/"+status.ID, nil)
+	recorder := httptest.NewRecorder()
+	server.ServeHTTP(recorder, request)
+	if recorder.Code != http.StatusAccepted {
+		t.Fatalf("DELETE returned %d", recorder.Code)
+	}
+	server.mu.Lock()
+	run := server.runs[status.ID]
+	server.mu.Unlock()
+	select {
+	case <-run.done:
+	case <-time.After(2 * time.Second):
+		t.Fatal("DELETE did not cancel the run context")
+	}
+	if final := run.status(); final.State != RunStateFinished || final.Status != ReportStatusCanceled || !final.Canceled {
+		t.Fatalf("unexpected canceled status: %+v", final)
+	}
+	missing := httptest.NewRecorder()
+	server.ServeHTTP(missing, httptest.NewRequest(http.MethodGet, "/runs/unknown", nil))
+	if missing.Code != http.StatusNotFound {
+		t.Fatalf("unknown run returned %d", missing.Code)
+	}
+}
+
+func TestServerDropsFinishedRunsPastRetention(t *testing.T) {
+	release := make(chan struct{})
+	server := newServerWithRunner(NetCheckResult{}, "v-test", func(ctx context.Context, _ NetCheckResult, config *Config, _ ProgressObserver) *RunResult {
+		if config.Language == "hold" {
+			<-release
+		}
+		return &RunResult{}
+	})
+	defer server.Close()
+	defer close(release)
+	server.keepFinished = 2
+	held := NewConfig("v-test")
+	held.Language = "hold"
+	running, err := server.StartRun(held)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var ids []string
+	for range 4 {
+		status, err := server.StartRun(nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		server.mu.Lock()
+		run := server.runs[status.ID]
+		server.mu.Unlock()
+		<-run.done
+		ids = append(ids, status.ID)
+	}
+	// finish prunes right after closing done, so wait for it to settle.
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		server.mu.Lock()
+		count := len(server.runs)
+		server.mu.Unlock()
+		if count == 3 || time.Now().After(deadline) {
+			break
+		}
+		time.Sleep(time.Millisecond)
+	}
+	server.mu.Lock()
+	_, keptRunning := server.runs[running.ID]
+	_, droppedOldest := server.runs[ids[0]]
+	_, keptNewest := server.runs[ids[3]]
+	newest := server.runs[ids[3]]
+	newest.mu.Lock()
+	newest.finishedAt = time.Now().Add(-2 * serverFinishedRunTTL)
+	newest.mu.Unlock()
+	server.pruneRuns(time.Now())
+	_, expired := server.runs[ids[3]]
+	count := len(server.runs)
+	server.mu.Unlock()
+	if !keptRunning || droppedOldest || !keptNewest {
+		t.Fatalf("unexpected retained runs: running=%v oldest=%v newest=%v", keptRunning, droppedOldest, keptNewest)
+	}
+	if expired || count != 2 {
+		t.Fatalf("expired run was kept: %d runs left", count)
+	}
+	recorder := httptest.NewRecorder()
+	server.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/runs/"+ids[0], nil))
+	if recorder.Code != http.StatusNotFound {
+		t.Fatalf("dropped run returned %d", recorder.Code)
+	}
+}
+
+func TestHardwareStageSlotAllowsOneStage(t *testing.T) {
+	release := acquireHardwareStage(context.Background())
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	blocked := acquireHardwareStage(ctx)
+	if ctx.Err() == nil {
+		t.Fatal("second hardware stage acquired the slot while the first was running")
+	}
+	blocked()
+	release()
+	acquireHardwareStage(context.Background())()
+}
@@ -2,6 +2,7 @@ package api
 
 import (
 	"context"
+	"crypto/x509"
 	"encoding/json"
 	"errors"
 	"fmt"
@@ -43,7 +44,9 @@ type DataVersion struct {
 // DataFileVersion describes one validated Go ECS snapshot payload. DataVersion is
 // intentionally kept unchanged for callers that historically consumed the
 // primary TCP target file; StructuredReport.DataFiles carries the complete
-// manifest view for newer callers.
+// manifest view for newer callers. Signature is "verified" when the manifest
+// signature checked out, "unsigned" when the build carries no manifest key,
+// or "embedded" for the snapshot compiled into the binary.
 type DataFileVersion struct {
 	File        string       `json:"file"`
 	Schema      string       `json:"schema"`
@@ -51,6 +54,7 @@ type DataFileVersion struct {
 	Source      string       `json:"source"`
 	Fallback    string       `json:"fallback"`
 	Count       int          `json:"count"`
+	Signature   string       `json:"signature,omitempty"`
 	Status      ReportStatus `json:"status"`
 	Reason      string       `json:"reason,omitempty"`
 }
@@ -75,36 +79,55 @@ type TCPSample struct {
 	Status     string  `json:"status"`
 }
 
+// TCPHistogramBucket counts successful handshakes no slower than UpperMS
+// and slower than the previous bucket. The final overflow bucket has no
+// upper bound and omits UpperMS.
+type TCPHistogramBucket struct {
+	UpperMS float64 `json:"le_ms,omitempty"`
+	Count   int     `json:"count"`
+}
+
 type TCPReport struct {
-	Target             TCPTarget      `json:"target"`
-	Attempts           int            `json:"attempts"`
-	Successful         int            `json:"successful"`
-	SuccessRatePercent float64        `json:"success_rate_percent"`
-	LossPercent        float64        `json:"loss_percent"`
-	MinMS              float64        `json:"min_ms,omitempty"`
-	MaxMS              float64        `json:"max_ms,omitempty"`
-	MeanMS             float64        `json:"mean_ms,omitempty"`
-	P50MS              float64        `json:"p50_ms,omitempty"`
-	P95MS              float64        `json:"p95_ms,omitempty"`
-	Samples            []TCPSample    `json:"samples"`
-	Errors             map[string]int `json:"errors,omitempty"`
+	Target             TCPTarget            `json:"target"`
+	Attempts           int                  `json:"attempts"`
+	Successful         int                  `json:"successful"`
+	SuccessRatePercent float64              `json:"success_rate_percent"`
+	LossPercent        float64              `json:"loss_percent"`
+	MinMS              float64              `json:"min_ms,omitempty"`
+	MaxMS              float64              `json:"max_ms,omitempty"`
+	MeanMS             float64              `json:"mean_ms,omitempty"`
+	P50MS              float64              `json:"p50_ms,omitempty"`
+	P95MS              float64              `json:"p95_ms,omitempty"`
+	P99MS              float64              `json:"p99_ms,omitempty"`
+	StdDevMS           float64              `json:"stddev_ms,omitempty"`
+	JitterMS           float64              `json:"jitter_ms,omitempty"`
+	Histogram          []TCPHistogramBucket `json:"histogram,omitempty"`
+	Samples            []TCPSample          `json:"samples"`
+	Errors             map[string]int       `json:"errors,omitempty"`
 }
 
 type StructuredReport struct {
-	SchemaVersion string            `json:"schema_version"`
-	ECSVersion    string            `json:"ecs_version"`
-	Status        ReportStatus      `json:"status"`
-	StartedAt     time.Time         `json:"started_at"`
-	FinishedAt    time.Time         `json:"finished_at"`
-	DurationMS    int64             `json:"duration_ms"`
-	DeepMode      bool              `json:"deep_mode"`
-	PrivacyMode   bool              `json:"privacy_mode"`
-	Data          *DataVersion      `json:"data,omitempty"`
-	DataFiles     []DataFileVersion `json:"data_files,omitempty"`
-	Sections      []SectionReport   `json:"sections"`
-	Components    []ComponentReport `json:"components,omitempty"`
-	TCP           []TCPReport       `json:"tcp,omitempty"`
-	Text          string            `json:"text"`
+	SchemaVersion string       `json:"schema_version"`
+	ECSVersion    string       `json:"ecs_version"`
+	Status        ReportStatus `json:"status"`
+	StartedAt     time.Time    `json:"started_at"`
+	FinishedAt    time.Time    `json:"finished_at"`
+	DurationMS    int64        `json:"duration_ms"`
+	DeepMode      bool         `json:"deep_mode"`
+	PrivacyMode   bool         `json:"privacy_mode"`
+	// ConfigWarnings lists the values validation corrected before the run.
+	ConfigWarnings []ConfigIssue     `json:"config_warnings,omitempty"`
+	Data           *DataVersion      `json:"data,omitempty"`
+	DataFiles      []DataFileVersion `json:"data_files,omitempty"`
+	Sections       []SectionReport   `json:"sections"`
+	Components     []ComponentReport `json:"components,omitempty"`
+	TCP            []TCPReport       `json:"tcp,omitempty"`
+	// Assertions holds the results of the -assert checks of the run.
+	Assertions []AssertionResult `json:"assertions,omitempty"`
+	// Summary is the post-test summary attached by a ReportSummarizer. Its
+	// schema is versioned by the summarizer, like component payloads.
+	Summary json.RawMessage `json:"summary,omitempty"`
+	Text    string          `json:"text"`
 }
 
 // ComponentReport is the cross-repository envelope used by component
@@ -136,10 +159,15 @@ func (report *StructuredReport) JSON() ([]byte, error) {
 	return json.MarshalIndent(report, "", "  ")
 }
 
+// tcpHistogramBoundsMS are the upper bounds of the TCP handshake histogram.
+var tcpHistogramBoundsMS = []float64{1, 2, 5, 10, 20, 50, 100, 200, 500, 1000, 2000}
+
 type tcpProbeConfig struct {
 	attempts    int
 	timeout     time.Duration
 	concurrency int
+	interval    time.Duration
+	rootCAs     *x509.CertPool
 	dial        func(context.Context, string, string) (net.Conn, error)
 }
 
@@ -183,18 +211,31 @@ func CollectStructuredReport(ctx context.Context, preCheck utils.NetCheckResult,
 		SchemaVersion: StructuredReportSchema, ECSVersion: config.EcsVersion,
 		Status: status, StartedAt: startedAt, FinishedAt: finishedAt,
 		DurationMS: finishedAt.Sub(startedAt).Milliseconds(), DeepMode: config.DeepMode,
-		PrivacyMode: config.PrivacyMode, Data: extras.data, DataFiles: extras.dataFiles,
+		PrivacyMode: config.PrivacyMode, ConfigWarnings: configWarnings(config),
+		Data: extras.data, DataFiles: extras.dataFiles,
 		Components: extras.components, TCP: extras.tcp,
 		Sections: sections, Text: text,
 	}
+	report.Assertions = configAssertionResults(config, report)
 	if config.PrivacyMode {
 		applyStructuredPrivacy(report)
 	}
 	return report
 }
 
+// configWarnings copies the corrections recorded on config so a report does
+// not share them with later runs of the same config.
+func configWarnings(config *Config) []ConfigIssue {
+	if len(config.ConfigWarnings) == 0 {
+		return nil
+	}
+	return append([]ConfigIssue(nil), config.ConfigWarnings...)
+}
+
 func collectStructuredExtras(ctx context.Context, preCheck utils.NetCheckResult, config *Config) structuredExtras {
 	loader := datarepo.NewLoader(nil, config.DataCDNBase)
+	loader.CacheDir, loader.CacheMaxAge = config.DataCacheDir, config.DataCacheMaxAge
+	loader.OverlayDir = config.DataOverlayDir
 	if config.DataOffline {
 		loader.CDNBase = ""
 		loader.RawBase = ""
@@ -218,7 +259,11 @@ func collectStructuredExtras(ctx context.Context, preCheck utils.NetCheckResult,
 		extras.err = errors.Join(extras.err, fmt.Errorf("decode TCP targets: %w", err))
 		return extras
 	}
-	targets = mergeComponentTCPTargets(targets)
+	custom, customErr := LoadCustomTCPTargets(config)
+	if customErr != nil {
+		extras.err = errors.Join(extras.err, customErr)
+	}
+	targets = mergeComponentTCPTargets(targets, custom...)
 	publicIPv4, publicIPv6 := GetIPv4Address(), GetIPv6Address()
 	if preCheck.Connected && publicIPv4 == "" && publicIPv6 == "" {
 		publicIPv4, publicIPv6 = structuredIdentity(ctx)
@@ -250,11 +295,12 @@ func collectStructuredExtras(ctx context.Context, preCheck utils.NetCheckResult,
 		return extras
 	}
 	progressStarted(ctx, "tcp")
-	extras.tcp = runTCPReports(ctx, targets, tcpProbeConfig{
-		attempts: 3, timeout: 3 * time.Second, concurrency: 16,
-		dial: (&net.Dialer{}).DialContext,
-	})
-	tcpStatus, tcpReason := tcpSectionStatus(extras.tcp)
+	probe := newTCPProbeConfig(config)
+	extras.tcp = runTCPReports(ctx, targets, probe)
+	if config.TLSProbeStatus && ctx.Err() == nil {
+		extras.components = append(extras.components, runTLSProbeComponent(ctx, targets, probe))
+	}
+	tcpStatus, tcpReason := tcpStageStatus(extras.tcp, extras.components)
 	if status, done := contextProgressStatus(ctx); done {
 		tcpStatus, tcpReason = status, ctx.Err().Error()
 	}
@@ -272,11 +318,11 @@ func contextProgressStatus(ctx context.Context) (ReportStatus, bool) {
 	return "", false
 }
 
-// loadKnownDataFiles validates every payload understood by this build. Each
+// loadKnownDataFiles validates every published payload of this build. Each
 // file is verified against one manifest candidate, preventing a report from
 // mixing payload generations when a CDN is only partially updated.
 func loadKnownDataFiles(ctx context.Context, loader *datarepo.Loader) (map[string]datarepo.Result, []DataFileVersion, error) {
-	names := datarepo.KnownFiles()
+	names := datarepo.SnapshotFiles()
 	loaded, loadErr := loader.LoadMany(ctx, names)
 	versions := make([]DataFileVersion, len(names))
 	if loadErr != nil {
@@ -305,12 +351,22 @@ func loadKnownDataFiles(ctx context.Context, loader *datarepo.Loader) (map[strin
 		versions[index] = DataFileVersion{
 			File: name, Schema: result.Manifest.Schema,
 			GeneratedAt: result.Manifest.GeneratedAt, Source: result.Source,
-			Fallback: result.Fallback, Count: meta.Count, Status: ReportStatusOK,
+			Fallback: result.Fallback, Count: meta.Count, Signature: result.Signature,
+			Status: ReportStatusOK,
 		}
 	}
 	return loaded, versions, loadErr
 }
 
+// ValidateDataOverlay checks Config.DataOverlayDir up front. The run itself
+// would only record a broken overlay as failed data files.
+func ValidateDataOverlay(config *Config) error {
+	if config == nil || config.DataOverlayDir == "" {
+		return nil
+	}
+	return datarepo.ValidateOverlay(config.DataOverlayDir)
+}
+
 func dataFileStatus(ctx context.Context, err error) ReportStatus {
 	if errors.Is(ctx.Err(), context.DeadlineExceeded) || errors.Is(err, context.DeadlineExceeded) {
 		return ReportStatusTimeout
@@ -321,6 +377,22 @@ func dataFileStatus(ctx context.Context, err error) ReportStatus {
 	return ReportStatusError
 }
 
+// newTCPProbeConfig applies the configured TCP settings. Three handshakes
+// cannot support a tail percentile, so deep mode raises the default to 20.
+func newTCPProbeConfig(config *Config) tcpProbeConfig {
+	attempts := config.TCPAttempts
+	if attempts <= 0 {
+		attempts = 3
+		if config.DeepMode {
+			attempts = 20
+		}
+	}
+	return tcpProbeConfig{
+		attempts: attempts, timeout: config.TCPTimeout, concurrency: config.TCPConcurrency,
+		interval: config.TCPInterval, dial: (&net.Dialer{}).DialContext,
+	}
+}
+
 func runTCPReports(ctx context.Context, targets []TCPTarget, config tcpProbeConfig) []TCPReport {
 	if len(targets) == 0 {
 		return nil
@@ -338,30 +410,36 @@ func runTCPReports(ctx context.Context, targets []TCPTarget, config tcpProbeConf
 		config.dial = (&net.Dialer{}).DialContext
 	}
 	results := make([]TCPReport, len(targets))
+	forEachProbeTarget(ctx, len(targets), config.concurrency, func(index int) {
+		results[index] = runOneTCPReport(ctx, targets[index], config)
+	})
+	return results
+}
+
+// forEachProbeTarget calls probe for indexes 0..count-1 on at most
+// concurrency workers and stops handing out work once ctx is done.
+func forEachProbeTarget(ctx context.Context, count, concurrency int, probe func(int)) {
 	jobs := make(chan int)
-	workerCount := min(config.concurrency, len(targets))
+	workerCount := min(max(concurrency, 1), count)
 	var wg sync.WaitGroup
 	wg.Add(workerCount)
 	for range workerCount {
 		go func() {
 			defer wg.Done()
 			for index := range jobs {
-				results[index] = runOneTCPReport(ctx, targets[index], config)
+				probe(index)
 			}
 		}()
 	}
-	for index := range targets {
+	defer wg.Wait()
+	defer close(jobs)
+	for index := range count {
 		select {
 		case jobs <- index:
 		case <-ctx.Done():
-			close(jobs)
-			wg.Wait()
-			return results
+			return
 		}
 	}
-	close(jobs)
-	wg.Wait()
-	return results
 }
 
 func runOneTCPReport(ctx context.Context, target TCPTarget, config tcpProbeConfig) TCPReport {
@@ -371,7 +449,15 @@ func runOneTCPReport(ctx context.Context, target TCPTarget, config tcpProbeConfi
 	}
 	address := net.JoinHostPort(target.Host, fmt.Sprintf("%d", target.Port))
 	latencies := make([]float64, 0, config.attempts)
-	for range config.attempts {
+	for attempt := range config.attempts {
+		if attempt > 0 && config.interval > 0 {
+			timer := time.NewTimer(config.interval)
+			select {
+			case <-timer.C:
+			case <-ctx.Done():
+				timer.Stop()
+			}
+		}
 		attemptCtx, cancel := context.WithTimeout(ctx, config.timeout)
 		started := time.Now()
 		conn, err := config.dial(attemptCtx, "tcp", address)
@@ -392,8 +478,23 @@ func runOneTCPReport(ctx context.Context, target TCPTarget, config tcpProbeConfi
 	}
 	report.SuccessRatePercent = float64(report.Successful) * 100 / float64(report.Attempts)
 	report.LossPercent = float64(report.Attempts-report.Successful) * 100 / float64(report.Attempts)
+	applyTCPLatencyStats(&report, latencies)
+	return report
+}
+
+// applyTCPLatencyStats fills the latency statistics from successful
+// handshake times in attempt order.
+func applyTCPLatencyStats(report *TCPReport, latencies []float64) {
 	if len(latencies) == 0 {
-		return report
+		return
+	}
+	// Jitter is the mean absolute difference between consecutive successful
+	// handshakes, so it must be taken before sorting.
+	for index := 1; index < len(latencies); index++ {
+		report.JitterMS += math.Abs(latencies[index] - latencies[index-1])
+	}
+	if len(latencies) > 1 {
+		report.JitterMS /= float64(len(latencies) - 1)
 	}
 	sort.Float64s(latencies)
 	report.MinMS, report.MaxMS = latencies[0], latencies[len(latencies)-1]
@@ -401,9 +502,36 @@ func runOneTCPReport(ctx context.Context, target TCPTarget, config tcpProbeConfi
 		report.MeanMS += value
 	}
 	report.MeanMS /= float64(len(latencies))
+	if len(latencies) > 1 {
+		for _, value := range latencies {
+			report.StdDevMS += (value - report.MeanMS) * (value - report.MeanMS)
+		}
+		report.StdDevMS = math.Sqrt(report.StdDevMS / float64(len(latencies)-1))
+	}
 	report.P50MS = percentileFloat(latencies, 0.50)
 	report.P95MS = percentileFloat(latencies, 0.95)
-	return report
+	report.P99MS = percentileFloat(latencies, 0.99)
+	report.Histogram = tcpHistogram(latencies)
+}
+
+// tcpHistogram buckets sorted latencies and keeps only non-empty buckets.
+func tcpHistogram(sorted []float64) []TCPHistogramBucket {
+	var buckets []TCPHistogramBucket
+	next := 0
+	for _, bound := range tcpHistogramBoundsMS {
+		count := 0
+		for next < len(sorted) && sorted[next] <= bound {
+			count++
+			next++
+		}
+		if count > 0 {
+			buckets = append(buckets, TCPHistogramBucket{UpperMS: bound, Count: count})
+		}
+	}
+	if next < len(sorted) {
+		buckets = append(buckets, TCPHistogramBucket{Count: len(sorted) - next})
+	}
+	return buckets
 }
 
 func percentileFloat(values []float64, quantile float64) float64 {
@@ -448,6 +576,7 @@ func sectionReports(config *Config, preCheck utils.NetCheckResult, extras struct
 		{"email", config.EmailTestStatus, true}, {"backtrace", config.BacktraceStatus, true},
 		{"routes", config.Nt3Status, true}, {"ping", config.PingTestStatus, true},
 		{"tgdc", config.TgdcTestStatus, true}, {"web", config.WebTestStatus, true},
+		{"quic", config.QUICTestStatus, true},
 		{"tcp", config.TCPProbeStatus, true}, {"speed", config.SpeedTestStatus, true},
 		{"nat", true, true},
 	}
@@ -461,7 +590,7 @@ func sectionReports(config *Config, preCheck utils.NetCheckResult, extras struct
 		"unlocktests.media": "media", "security.evidence": "security",
 		"backtrace.ip_bgp": "backtrace", "portchecker.email": "email", "speed.registry": "speed",
 		"gostun.nat": "nat", "ping.icmp": "ping",
-		"ping.telegram": "tgdc", "ping.web_tcp": "web",
+		"ping.telegram": "tgdc", "ping.web_tcp": "web", QUICProbeComponent: "quic",
 	}
 	// A section without a structured component must not inherit the overall
 	// report's ok status. This is especially important for release builds while
@@ -469,7 +598,7 @@ func sectionReports(config *Config, preCheck utils.NetCheckResult, extras struct
 	structuredSections := map[string]bool{
 		"basics": true, "cpu": true, "memory": true, "disk": true,
 		"media": true, "security": true, "email": true, "backtrace": true,
-		"routes": true, "ping": true, "tgdc": true, "web": true,
+		"routes": true, "ping": true, "tgdc": true, "web": true, "quic": true,
 		"tcp": true, "speed": true, "nat": true,
 	}
 	for _, component := range extras.components {
@@ -490,7 +619,7 @@ func sectionReports(config *Config, preCheck utils.NetCheckResult, extras struct
 		} else if section.name == "tcp" && len(extras.tcp) == 0 {
 			sectionStatus, sectionReason = ReportStatusUnavailable, "no TCP results"
 		} else if section.name == "tcp" {
-			sectionStatus, sectionReason = tcpSectionStatus(extras.tcp)
+			sectionStatus, sectionReason = tcpStageStatus(extras.tcp, extras.components)
 		} else if structuredSections[section.name] {
 			sectionStatus, sectionReason = ReportStatusPartial, "structured component unavailable"
 		}
@@ -564,6 +693,18 @@ func tcpSectionStatus(reports []TCPReport) (ReportStatus, string) {
 	return ReportStatusOK, ""
 }
 
+// tcpStageStatus folds the optional TLS component into the TCP section so a
+// path that completes SYN-ACKs but breaks handshakes is not reported as ok.
+func tcpStageStatus(reports []TCPReport, components []ComponentReport) (ReportStatus, string) {
+	status, reason := tcpSectionStatus(reports)
+	for _, component := range components {
+		if component.Name == TLSProbeComponent {
+			return aggregateComponentSectionStatus([]ComponentReport{{Name: "tcp", Status: status, Reason: reason}, component})
+		}
+	}
+	return status, reason
+}
+
 func aggregateReportStatus(current ReportStatus, sections []SectionReport) ReportStatus {
 	if current != ReportStatusOK {
 		return current
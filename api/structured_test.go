@@ -106,7 +106,7 @@ func TestDataOfflineForcesEmbeddedSnapshot(t *testing.T) {
 		t.Fatalf("unexpected offline data source: %#v", report.Data)
 	}
 	if len(report.DataFiles) != 7 {
-		t.Fatalf("expected all known data files, got %#v", report.DataFiles)
+		t.Fatalf("expected all snapshot data files, got %#v", report.DataFiles)
 	}
 	for index, file := range report.DataFiles {
 		if file.Status != ReportStatusOK || file.Source != "embedded" || file.Fallback != "embedded" {
@@ -330,3 +330,38 @@ func TestSectionReportsIncludesTelegramAndWebsiteComponents(t *testing.T) {
 		t.Fatalf("unexpected website section: %#v", statuses["web"])
 	}
 }
+
+func TestApplyTCPLatencyStatsComputesSpreadAndHistogram(t *testing.T) {
+	var report TCPReport
+	applyTCPLatencyStats(&report, []float64{10, 30, 20, 3000})
+	if report.JitterMS != (20+10+2980)/3.0 || report.MeanMS != 765 || report.P99MS <= report.P95MS || report.P95MS <= report.P50MS {
+		t.Fatalf("unexpected TCP statistics: %#v", report)
+	}
+	if report.StdDevMS < 1490 || report.StdDevMS > 1491 {
+		t.Fatalf("stddev = %f", report.StdDevMS)
+	}
+	want := []TCPHistogramBucket{{UpperMS: 10, Count: 1}, {UpperMS: 20, Count: 1}, {UpperMS: 50, Count: 1}, {Count: 1}}
+	if len(report.Histogram) != len(want) {
+		t.Fatalf("histogram = %#v", report.Histogram)
+	}
+	for index := range want {
+		if report.Histogram[index] != want[index] {
+			t.Fatalf("histogram = %#v", report.Histogram)
+		}
+	}
+}
+
+func TestNewTCPProbeConfigRaisesDeepAttempts(t *testing.T) {
+	cfg := NewConfig("test")
+	if probe := newTCPProbeConfig(cfg); probe.attempts != 3 || probe.timeout != 3*time.Second || probe.concurrency != 16 {
+		t.Fatalf("standard probe config = %+v", probe)
+	}
+	cfg.DeepMode = true
+	if probe := newTCPProbeConfig(cfg); probe.attempts != 20 {
+		t.Fatalf("deep attempts = %d", probe.attempts)
+	}
+	cfg.TCPAttempts, cfg.TCPInterval = 7, 50*time.Millisecond
+	if probe := newTCPProbeConfig(cfg); probe.attempts != 7 || probe.interval != 50*time.Millisecond {
+		t.Fatalf("explicit probe config = %+v", probe)
+	}
+}
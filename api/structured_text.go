@@ -3,6 +3,7 @@ package api
 import (
 	"encoding/json"
 	"fmt"
+	"html"
 	"sort"
 	"strconv"
 	"strings"
@@ -13,21 +14,39 @@ import (
 
 const structuredLabelWidth = 14
 
+// renderFormat selects how the section, row and table primitives are
+// written. Payload renderers only call the primitives, so every component
+// layout is shared by the text, Markdown and HTML outputs.
+type renderFormat int
+
+const (
+	renderText renderFormat = iota
+	renderMarkdown
+	renderHTML
+)
+
 type structuredTextRenderer struct {
 	builder strings.Builder
 	width   int
 	zh      bool
+	format  renderFormat
+	// sectionOpen tracks an unclosed HTML <details> element.
+	sectionOpen bool
 }
 
 func renderStructuredRunText(config *Config, dataFiles []DataFileVersion, components []ComponentReport, tcp []TCPReport) string {
 	renderer := newStructuredTextRenderer(config)
+	renderer.report(config, dataFiles, components, tcp)
+	return renderer.builder.String()
+}
+
+func (renderer *structuredTextRenderer) report(config *Config, dataFiles []DataFileVersion, components []ComponentReport, tcp []TCPReport) {
 	renderer.header(config)
 	renderer.dataFiles(dataFiles)
 	for _, component := range components {
 		renderer.component(component)
 	}
 	renderer.tcp(tcp)
-	return renderer.builder.String()
 }
 
 func appendStructuredTimeText(output string, config *Config, started, finished time.Time) string {
@@ -36,6 +55,11 @@ func appendStructuredTimeText(output string, config *Config, started, finished t
 	if output != "" && !strings.HasSuffix(output, "\n") {
 		renderer.builder.WriteByte('\n')
 	}
+	renderer.timing(started, finished)
+	return renderer.builder.String()
+}
+
+func (renderer *structuredTextRenderer) timing(started, finished time.Time) {
 	renderer.section("")
 	duration := finished.Sub(started)
 	if renderer.zh {
@@ -46,7 +70,6 @@ func appendStructuredTimeText(output string, config *Config, started, finished t
 		renderer.row("Current Time", finished.Format("Mon Jan 2 15:04:05 MST 2006"))
 	}
 	renderer.section("")
-	return renderer.builder.String()
 }
 
 func newStructuredTextRenderer(config *Config) *structuredTextRenderer {
@@ -72,19 +95,72 @@ func (renderer *structuredTextRenderer) header(config *Config) {
 	if renderer.zh {
 		renderer.section("VPS融合怪测试")
 		renderer.row("版本", version)
-		renderer.builder.WriteString("测评频道: https://t.me/+UHVoo2U4VyA5NTQ1\n")
-		renderer.builder.WriteString("Go项目地址：https://github.com/oneclickvirt/ecs\n")
-		renderer.builder.WriteString("Shell项目地址：https://github.com/spiritLHLS/ecs\n")
+		renderer.line("测评频道: https://t.me/+UHVoo2U4VyA5NTQ1")
+		renderer.line("Go项目地址：https://github.com/oneclickvirt/ecs")
+		renderer.line("Shell项目地址：https://github.com/spiritLHLS/ecs")
 		return
 	}
 	renderer.section("VPS Fusion Monster Test")
 	renderer.row("Version", version)
-	renderer.builder.WriteString("Review Channel: https://t.me/+UHVoo2U4VyA5NTQ1\n")
-	renderer.builder.WriteString("Go Project: https://github.com/oneclickvirt/ecs\n")
-	renderer.builder.WriteString("Shell Project: https://github.com/spiritLHLS/ecs\n")
+	renderer.line("Review Channel: https://t.me/+UHVoo2U4VyA5NTQ1")
+	renderer.line("Go Project: https://github.com/oneclickvirt/ecs")
+	renderer.line("Shell Project: https://github.com/spiritLHLS/ecs")
+}
+
+func (renderer *structuredTextRenderer) line(text string) {
+	switch renderer.format {
+	case renderMarkdown:
+		renderer.builder.WriteString(text + "  \n")
+	case renderHTML:
+		renderer.builder.WriteString("<p>" + html.EscapeString(text) + "</p>\n")
+	default:
+		renderer.builder.WriteString(text + "\n")
+	}
+}
+
+// statusSection starts a component section. Markdown and HTML carry the
+// status as a badge in the heading instead of a separate row.
+func (renderer *structuredTextRenderer) statusSection(title string, status ReportStatus) {
+	switch renderer.format {
+	case renderMarkdown:
+		renderer.builder.WriteString("## " + markdownEscape(title) + " `" + renderer.status(status) + "`\n\n")
+	case renderHTML:
+		renderer.closeSection()
+		renderer.builder.WriteString(`<details open><summary>` + html.EscapeString(title) +
+			` <span class="badge badge-` + html.EscapeString(string(status)) + `">` + html.EscapeString(renderer.status(status)) + "</span></summary>\n")
+		renderer.sectionOpen = true
+	default:
+		renderer.section(title)
+		if status != ReportStatusOK {
+			renderer.row(renderer.pick("状态", "Status"), renderer.status(status))
+		}
+	}
+}
+
+func (renderer *structuredTextRenderer) closeSection() {
+	if renderer.sectionOpen {
+		renderer.builder.WriteString("</details>\n")
+		renderer.sectionOpen = false
+	}
 }
 
 func (renderer *structuredTextRenderer) section(title string) {
+	switch renderer.format {
+	case renderMarkdown:
+		if title == "" {
+			renderer.builder.WriteString("\n---\n\n")
+		} else {
+			renderer.builder.WriteString("## " + markdownEscape(title) + "\n\n")
+		}
+		return
+	case renderHTML:
+		renderer.closeSection()
+		if title != "" {
+			renderer.builder.WriteString("<details open><summary>" + html.EscapeString(title) + "</summary>\n")
+			renderer.sectionOpen = true
+		}
+		return
+	}
 	titleWidth := runewidth.StringWidth(title)
 	padding := renderer.width - titleWidth
 	if padding < 0 {
@@ -102,6 +178,14 @@ func (renderer *structuredTextRenderer) row(label, value string) {
 	if value == "" {
 		value = "-"
 	}
+	switch renderer.format {
+	case renderMarkdown:
+		renderer.builder.WriteString("- **" + markdownEscape(label) + "**: " + markdownEscape(value) + "\n")
+		return
+	case renderHTML:
+		renderer.builder.WriteString(`<div class="row"><span class="label">` + html.EscapeString(label) + "</span>" + html.EscapeString(value) + "</div>\n")
+		return
+	}
 	prefix := padDisplay(label, structuredLabelWidth) + " : "
 	continuation := strings.Repeat(" ", runewidth.StringWidth(prefix))
 	available := renderer.width - runewidth.StringWidth(prefix)
@@ -124,6 +208,14 @@ func (renderer *structuredTextRenderer) table(headers []string, rows [][]string,
 	if len(headers) == 0 || len(headers) != len(widths) {
 		return
 	}
+	switch renderer.format {
+	case renderMarkdown:
+		renderer.markdownTable(headers, rows)
+		return
+	case renderHTML:
+		renderer.htmlTable(headers, rows)
+		return
+	}
 	gapWidth := (len(widths) - 1) * 2
 	total := gapWidth
 	for _, width := range widths {
@@ -193,6 +285,9 @@ func (renderer *structuredTextRenderer) dataFiles(files []DataFileVersion) {
 	if !files[0].GeneratedAt.IsZero() {
 		renderer.row(renderer.pick("同步时间", "Synced At"), files[0].GeneratedAt.Local().Format("2006-01-02 15:04:05"))
 	}
+	if files[0].Signature != "" {
+		renderer.row(renderer.pick("清单签名", "Manifest Signature"), localizedValue(files[0].Signature, renderer.zh))
+	}
 }
 
 func (renderer *structuredTextRenderer) component(component ComponentReport) {
@@ -200,10 +295,7 @@ func (renderer *structuredTextRenderer) component(component ComponentReport) {
 	if title == "" {
 		return
 	}
-	renderer.section(title)
-	if component.Status != ReportStatusOK {
-		renderer.row(renderer.pick("状态", "Status"), renderer.status(component.Status))
-	}
+	renderer.statusSection(title, component.Status)
 	if component.Reason != "" {
 		renderer.row(renderer.pick("说明", "Reason"), component.Reason)
 	}
@@ -233,6 +325,10 @@ func (renderer *structuredTextRenderer) component(component ComponentReport) {
 		renderer.latencyPayload(component.Payload)
 	case "ping.web_tcp":
 		renderer.tcpPayload(component.Payload)
+	case TLSProbeComponent:
+		renderer.tlsPayload(component.Payload)
+	case QUICProbeComponent:
+		renderer.quicPayload(component.Payload)
 	case "nt3.province_routes":
 		renderer.routePayload(component.Payload)
 	case "speed.registry":
@@ -446,19 +542,26 @@ func (renderer *structuredTextRenderer) mailPayload(payload json.RawMessage) {
 }
 
 func (renderer *structuredTextRenderer) latencyPayload(payload json.RawMessage) {
-	var values []any
-	if err := json.Unmarshal(payload, &values); err != nil {
+	values := latencyResults(payload)
+	if values == nil {
 		return
 	}
+	versions := make(map[string]bool)
+	for _, result := range values {
+		versions[stringValue(objectValue(result, "target"), "ip_version")] = true
+	}
 	rows := make([][]string, 0, len(values))
-	for _, raw := range values {
-		result, _ := raw.(map[string]any)
+	for _, result := range values {
 		target := objectValue(result, "target")
 		name := fallback(stringValue(target, "name"), stringValue(target, "province_name"), stringValue(target, "id"))
 		carrier := stringValue(target, "carrier")
 		if carrier != "" {
 			name = joinNonEmpty(name, strings.ToUpper(carrier))
 		}
+		// Only dual-stack runs need the IP version to tell rows apart.
+		if len(versions) > 1 {
+			name = joinNonEmpty(name, stringValue(target, "ip_version"))
+		}
 		sent := intValue(result, "sent")
 		received := intValue(result, "received")
 		if sent == 0 {
@@ -478,6 +581,43 @@ func (renderer *structuredTextRenderer) latencyPayload(payload json.RawMessage)
 		})
 	}
 	renderer.table([]string{renderer.pick("目标", "Target"), renderer.pick("状态", "Status"), renderer.pick("成功", "Success"), renderer.pick("平均", "Mean"), "P95", renderer.pick("丢包", "Loss")}, rows, []int{24, 14, 10, 10, 10, 10})
+	renderer.latencyAggregates(payloadObject(payload))
+}
+
+// latencyAggregates renders the carrier and region comparison of a v2
+// province latency payload.
+func (renderer *structuredTextRenderer) latencyAggregates(root map[string]any) {
+	aggregates := append(arrayValue(root, "carriers"), arrayValue(root, "regions")...)
+	if len(aggregates) == 0 {
+		return
+	}
+	regions := map[string][2]string{"north": {"北方", "North"}, "east": {"东部", "East"}, "south": {"南方", "South"}, "west": {"西部", "West"}}
+	extreme := func(value map[string]any) string {
+		if value == nil {
+			return "-"
+		}
+		return fmt.Sprintf("%s %s %.1f ms", stringValue(value, "province_name"), strings.ToUpper(stringValue(value, "carrier")), floatValue(value, "rtt_ms"))
+	}
+	rows := make([][]string, 0, len(aggregates))
+	for _, raw := range aggregates {
+		aggregate, _ := raw.(map[string]any)
+		group := strings.ToUpper(stringValue(aggregate, "carrier"))
+		if region, ok := regions[stringValue(aggregate, "region")]; ok {
+			group = renderer.pick(region[0], region[1])
+		}
+		median := "-"
+		if value := floatValue(aggregate, "median_rtt_ms"); value > 0 {
+			median = fmt.Sprintf("%.1f ms", value)
+		}
+		rows = append(rows, []string{
+			joinNonEmpty(group, stringValue(aggregate, "ip_version")), fmt.Sprintf("%d/%d", intValue(aggregate, "reachable"), intValue(aggregate, "targets")),
+			median, fmt.Sprintf("%.0f%%", floatValue(aggregate, "loss_percent")), extreme(objectValue(aggregate, "best")), extreme(objectValue(aggregate, "worst")),
+		})
+	}
+	renderer.table([]string{
+		renderer.pick("分组", "Group"), renderer.pick("可达", "Reachable"), renderer.pick("中位延迟", "Median"),
+		renderer.pick("丢包", "Loss"), renderer.pick("最佳省份", "Best"), renderer.pick("最差省份", "Worst"),
+	}, rows, []int{16, 10, 10, 8, 22, 22})
 }
 
 func (renderer *structuredTextRenderer) tcpPayload(payload json.RawMessage) {
@@ -496,18 +636,114 @@ func (renderer *structuredTextRenderer) tcpPayload(payload json.RawMessage) {
 	renderer.table([]string{renderer.pick("目标", "Target"), renderer.pick("成功", "Success"), renderer.pick("平均", "Mean"), "P95", renderer.pick("丢包", "Loss"), renderer.pick("错误", "Errors")}, rows, []int{24, 10, 10, 10, 10, 18})
 }
 
+func (renderer *structuredTextRenderer) tlsPayload(payload json.RawMessage) {
+	root := payloadObject(payload)
+	results := arrayValue(root, "results")
+	rows := make([][]string, 0, len(results))
+	for _, raw := range results {
+		result, _ := raw.(map[string]any)
+		target := objectValue(result, "target")
+		milliseconds := func(key string) string {
+			if value := floatValue(result, key); value > 0 {
+				return fmt.Sprintf("%.1f ms", value)
+			}
+			return "-"
+		}
+		rows = append(rows, []string{
+			fallback(stringValue(target, "name"), stringValue(target, "id")), localizedValue(stringValue(result, "status"), renderer.zh),
+			strings.TrimPrefix(fallback(stringValue(result, "tls_version"), "-"), "TLS "), fallback(stringValue(result, "alpn"), "-"),
+			milliseconds("connect_ms"), milliseconds("tls_ms"), milliseconds("ttfb_ms"),
+		})
+	}
+	renderer.table([]string{
+		renderer.pick("目标", "Target"), renderer.pick("状态", "Status"), "TLS", "ALPN",
+		renderer.pick("连接", "Connect"), renderer.pick("握手", "Handshake"), "TTFB",
+	}, rows, []int{20, 12, 8, 8, 10, 10, 10})
+}
+
+func (renderer *structuredTextRenderer) quicPayload(payload json.RawMessage) {
+	var values []map[string]any
+	if err := json.Unmarshal(payload, &values); err != nil {
+		return
+	}
+	rows := make([][]string, 0, len(values))
+	for _, result := range values {
+		target := objectValue(result, "target")
+		quicStats, tcpStats := objectValue(result, "quic"), objectValue(result, "tcp_tls")
+		delta := "-"
+		if value, ok := result["quic_minus_tcp_tls_ms"].(float64); ok {
+			delta = fmt.Sprintf("%+.1f ms", value)
+		}
+		rows = append(rows, []string{
+			fallback(stringValue(target, "name"), stringValue(target, "id")),
+			fmt.Sprintf("%d/%d", intValue(quicStats, "successful"), intValue(quicStats, "attempts")), fmt.Sprintf("%.1f ms", floatValue(quicStats, "mean_ms")),
+			fmt.Sprintf("%.1f ms", floatValue(tcpStats, "mean_ms")), delta, localizedValue(stringValue(result, "status"), renderer.zh),
+		})
+	}
+	renderer.table([]string{
+		renderer.pick("目标", "Target"), "QUIC", renderer.pick("QUIC平均", "QUIC Mean"), renderer.pick("TCP+TLS平均", "TCP+TLS"),
+		renderer.pick("差值", "Delta"), renderer.pick("状态", "Status"),
+	}, rows, []int{20, 8, 10, 10, 10, 12})
+}
+
 func (renderer *structuredTextRenderer) routePayload(payload json.RawMessage) {
 	var values []map[string]any
 	if err := json.Unmarshal(payload, &values); err != nil {
 		return
 	}
 	rows := make([][]string, 0, len(values))
+	// Route classes are tallied per carrier and IP version in first-seen order.
+	var groups []string
+	classes := make(map[string]map[string]int)
 	for _, result := range values {
 		target := objectValue(result, "target")
 		name := joinNonEmpty(stringValue(target, "province_name"), strings.ToUpper(stringValue(target, "carrier")), stringValue(target, "ip_version"))
-		rows = append(rows, []string{name, localizedValue(stringValue(result, "status"), renderer.zh), strconv.Itoa(len(arrayValue(result, "hops"))), formatJSONDuration(result["duration"]), stringValue(result, "error")})
+		class := stringValue(result, "route_class")
+		rows = append(rows, []string{name, localizedValue(stringValue(result, "status"), renderer.zh), renderer.routeClass(class), strconv.Itoa(len(arrayValue(result, "hops"))), formatJSONDuration(result["duration"]), stringValue(result, "error")})
+		if class == "" {
+			continue
+		}
+		group := joinNonEmpty(strings.ToUpper(stringValue(target, "carrier")), stringValue(target, "ip_version"))
+		if classes[group] == nil {
+			groups = append(groups, group)
+			classes[group] = make(map[string]int)
+		}
+		classes[group][class]++
+	}
+	renderer.table([]string{renderer.pick("目标", "Target"), renderer.pick("状态", "Status"), renderer.pick("线路", "Route"), renderer.pick("跳数", "Hops"), renderer.pick("耗时", "Duration"), renderer.pick("说明", "Detail")}, rows, []int{28, 14, 10, 8, 12, 20})
+	if len(groups) == 0 {
+		return
+	}
+	summary := make([][]string, 0, len(groups))
+	for _, group := range groups {
+		names := make([]string, 0, len(classes[group]))
+		for class := range classes[group] {
+			names = append(names, class)
+		}
+		sort.Slice(names, func(i, j int) bool {
+			if classes[group][names[i]] != classes[group][names[j]] {
+				return classes[group][names[i]] > classes[group][names[j]]
+			}
+			return names[i] < names[j]
+		})
+		counts := make([]string, 0, len(names))
+		for _, class := range names {
+			counts = append(counts, fmt.Sprintf("%s ×%d", renderer.routeClass(class), classes[group][class]))
+		}
+		summary = append(summary, []string{group, strings.Join(counts, ", ")})
+	}
+	renderer.table([]string{renderer.pick("运营商", "Carrier"), renderer.pick("线路分布", "Route Classes")}, summary, []int{14, 60})
+}
+
+func (renderer *structuredTextRenderer) routeClass(class string) string {
+	switch class {
+	case "":
+		return "-"
+	case RouteClassUnknown:
+		return renderer.pick("未识别", "Unknown")
+	default:
+		return routeClassLabel(class)
 	}
-	renderer.table([]string{renderer.pick("目标", "Target"), renderer.pick("状态", "Status"), renderer.pick("跳数", "Hops"), renderer.pick("耗时", "Duration"), renderer.pick("说明", "Detail")}, rows, []int{28, 14, 8, 12, 20})
 }
 
 func (renderer *structuredTextRenderer) speedPayload(payload json.RawMessage) {
@@ -597,10 +833,14 @@ func (renderer *structuredTextRenderer) tcp(reports []TCPReport) {
 	for _, report := range reports {
 		rows = append(rows, []string{
 			fallback(report.Target.Name, report.Target.ID), fmt.Sprintf("%d/%d", report.Successful, report.Attempts),
-			fmt.Sprintf("%.2f ms", report.MeanMS), fmt.Sprintf("%.2f ms", report.P95MS), fmt.Sprintf("%.0f%%", report.LossPercent), formatIntCounts(report.Errors),
+			fmt.Sprintf("%.2f ms", report.MeanMS), fmt.Sprintf("%.1f/%.1f ms", report.P95MS, report.P99MS),
+			fmt.Sprintf("%.2f ms", report.JitterMS), fmt.Sprintf("%.0f%%", report.LossPercent), formatIntCounts(report.Errors),
 		})
 	}
-	renderer.table([]string{renderer.pick("目标", "Target"), renderer.pick("成功", "Success"), renderer.pick("平均", "Mean"), "P95", renderer.pick("丢包", "Loss"), renderer.pick("错误", "Errors")}, rows, []int{24, 10, 10, 10, 10, 18})
+	renderer.table([]string{
+		renderer.pick("目标", "Target"), renderer.pick("成功", "Success"), renderer.pick("平均", "Mean"), "P95/P99",
+		renderer.pick("抖动", "Jitter"), renderer.pick("丢包", "Loss"), renderer.pick("错误", "Errors"),
+	}, rows, []int{20, 8, 10, 14, 10, 8, 12})
 }
 
 func (renderer *structuredTextRenderer) componentTitle(name string) string {
@@ -614,7 +854,8 @@ func (renderer *structuredTextRenderer) componentTitle(name string) string {
 		"ping.icmp": {"PING值检测", "PING Test"}, "ping.telegram": {"Telegram DC延迟", "Telegram DC Latency"},
 		"ping.web_tcp": {"网站连接延迟", "Website TCP Latency"}, "disktest.deep_multi": {"多目录深度磁盘测试", "Deep Multi-Path Disk Test"},
 		"basics.smart_selftest": {"SMART自检", "SMART Self-Test"}, "cputest.burn": {"CPU压力测试", "CPU Burn Test"},
-		"basics.gpu_compute": {"GPU计算测试", "GPU Compute Test"},
+		"basics.gpu_compute": {"GPU计算测试", "GPU Compute Test"}, TLSProbeComponent: {"TLS握手与首字节", "TLS Handshake and First Byte"},
+		QUICProbeComponent: {"HTTP/3 (QUIC) 连通性", "HTTP/3 (QUIC) Reachability"},
 	}
 	value, ok := titles[name]
 	if !ok {
@@ -695,6 +936,8 @@ func localizedValue(value string, zh bool) string {
 		"ok": "正常", "available": "可用", "unavailable": "不可用", "partial": "部分可用", "timeout": "超时",
 		"canceled": "已取消", "error": "错误", "skipped": "已跳过", "unsupported": "不支持", "rate_limited": "限流",
 		"missing_fields": "字段缺失", "permission_denied": "权限不足", "clean": "正常", "listed": "列入名单", "marked": "已标记",
+		"udp_blocked": "UDP受阻", "unreachable": "不可达", "sni_blocked": "SNI阻断", "tls_reset": "TLS重置", "tls_timeout": "TLS超时",
+		"cert_invalid": "证书无效", "verified": "已验证", "unsigned": "未签名", "embedded": "内置快照",
 		"Yes": "解锁", "No": "不解锁", "Restricted": "受限", "Banned": "封禁", "CDN Relay": "CDN中转", "RateLimited": "限流",
 	}
 	if result, ok := values[value]; ok {
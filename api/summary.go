@@ -0,0 +1,82 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+
+	"github.com/oneclickvirt/ecs/internal/analysis"
+)
+
+// ReportSummarizer computes the post-test summary of a finished report. The
+// summary is stored in StructuredReport.Summary and text is appended to the
+// rendered output. Runs use the built-in summary unless the context carries
+// another summarizer.
+type ReportSummarizer func(config *Config, report *StructuredReport) (summary any, text string)
+
+type reportSummarizerContextKey struct{}
+
+// WithReportSummarizer returns a context whose runs attach the summary of
+// summarizer instead of the built-in one when Config.AnalyzeResult is set.
+func WithReportSummarizer(parent context.Context, summarizer ReportSummarizer) context.Context {
+	if parent == nil {
+		parent = context.Background()
+	}
+	if summarizer == nil {
+		return parent
+	}
+	return context.WithValue(parent, reportSummarizerContextKey{}, summarizer)
+}
+
+// summarizeReport runs the context's summarizer on report, or fallback when
+// the context carries none, and returns the text to append.
+func summarizeReport(ctx context.Context, config *Config, report *StructuredReport, fallback ReportSummarizer) string {
+	if ctx == nil || report == nil {
+		return ""
+	}
+	summarizer, _ := ctx.Value(reportSummarizerContextKey{}).(ReportSummarizer)
+	if summarizer == nil {
+		summarizer = fallback
+	}
+	if summarizer == nil {
+		return ""
+	}
+	progressStarted(ctx, "analysis")
+	summary, text := summarizer(config, report)
+	if summary != nil {
+		if data, err := json.Marshal(summary); err == nil {
+			report.Summary = data
+		}
+	}
+	progressCompleted(ctx, "analysis", ReportStatusOK, "")
+	return text
+}
+
+// summarizeComponents is the built-in summary of runs rendered from
+// component payloads.
+func summarizeComponents(config *Config, report *StructuredReport) (any, string) {
+	components := make([]analysis.Component, len(report.Components))
+	for index, component := range report.Components {
+		components[index] = analysis.Component{Name: component.Name, Payload: component.Payload}
+	}
+	summary := analysis.SummarizeComponents(config, components)
+	return summary, summary.Markdown(config.Language)
+}
+
+// summarizeLegacyText is the built-in summary of runs whose sections the
+// streaming text workflow printed; it reads the values from report.Text as
+// the CLI does.
+func summarizeLegacyText(config *Config, report *StructuredReport) (any, string) {
+	summary := analysis.SummarizeText(config, report.Text)
+	return summary, summary.Markdown(config.Language)
+}
+
+func appendSummaryText(output, text string) string {
+	if strings.TrimSpace(text) == "" {
+		return output
+	}
+	if output != "" && !strings.HasSuffix(output, "\n") {
+		output += "\n"
+	}
+	return output + text
+}
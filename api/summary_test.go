@@ -0,0 +1,65 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/oneclickvirt/ecs/internal/analysis"
+)
+
+func TestSummarizeReportAttachesSummaryFromContext(t *testing.T) {
+	var events []ProgressEvent
+	ctx := WithProgressObserver(context.Background(), func(event ProgressEvent) {
+		events = append(events, event)
+	})
+	ctx = WithReportSummarizer(ctx, func(config *Config, report *StructuredReport) (any, string) {
+		return map[string]int{"components": len(report.Components)}, "| " + config.Language + " |\n"
+	})
+	config := NewDefaultConfig()
+	config.Language = "en"
+	report := &StructuredReport{Components: []ComponentReport{{Name: "cputest"}}}
+	text := summarizeReport(ctx, config, report, summarizeComponents)
+	if text != "| en |\n" || string(report.Summary) != `{"components":1}` {
+		t.Fatalf("unexpected summary: %q %s", text, report.Summary)
+	}
+	if len(events) != 2 || events[0].Section != "analysis" || events[1].Phase != ProgressCompleted {
+		t.Fatalf("unexpected progress events: %#v", events)
+	}
+	if output := appendSummaryText("tail", text); output != "tail\n| en |\n" {
+		t.Fatalf("unexpected output: %q", output)
+	}
+}
+
+func TestSummarizeReportFallsBackToBuiltInSummary(t *testing.T) {
+	config := NewDefaultConfig()
+	config.Language = "en"
+	report := &StructuredReport{Components: []ComponentReport{
+		{Name: "speed.registry", Payload: json.RawMessage(`{"benchmarks":[{"download_mbps":900,"upload_mbps":300}]}`)},
+		{Name: "nt3.province_routes", Payload: json.RawMessage(`[{"target":{"carrier":"cu"},"route_class":"` + RouteClass9929 + `"}]`)},
+	}}
+	text := summarizeReport(WithReportSummarizer(context.Background(), nil), config, report, summarizeComponents)
+	if !strings.Contains(text, "| Peak Bandwidth | > 900.00Mbps |") || !strings.Contains(string(report.Summary), analysis.SummarySchema) {
+		t.Fatalf("unexpected built-in summary: %q %s", text, report.Summary)
+	}
+	if text := summarizeReport(context.Background(), config, &StructuredReport{}, nil); text != "" {
+		t.Fatalf("summary without a summarizer: %q", text)
+	}
+	if output := appendSummaryText("body\n", " \n"); output != "body\n" {
+		t.Fatalf("blank summary changed output: %q", output)
+	}
+}
+
+// TestRouteClassesHaveSummaryQualities keeps the ISP ranking in step with the
+// route classes nt3 reports.
+func TestRouteClassesHaveSummaryQualities(t *testing.T) {
+	for _, class := range []string{RouteClassCN2GIA, RouteClassCN2GT, RouteClass163, RouteClass9929, RouteClass10099, RouteClass4837, RouteClassCMIN2, RouteClassCMI, RouteClass9808} {
+		if analysis.RouteQuality(class) == 0 {
+			t.Errorf("route class %q has no ISP ranking quality", class)
+		}
+	}
+	if analysis.RouteQuality(RouteClassUnknown) != 0 {
+		t.Error("an unknown route must not rank")
+	}
+}
@@ -0,0 +1,88 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+
+	datasync "github.com/oneclickvirt/ecs/internal/data/sync"
+)
+
+// CustomTCPCategory marks targets supplied with -tcp-targets or -tcp-target.
+// Privacy mode redacts their names and IDs as well as their hosts because
+// they usually describe the operator's own infrastructure.
+const CustomTCPCategory = "custom"
+
+// LoadCustomTCPTargets reads the configured target file and inline targets.
+// Both are validated together with the tcp-targets.json rules, so a file
+// entry and an inline flag may not name the same endpoint or ID.
+func LoadCustomTCPTargets(config *Config) ([]TCPTarget, error) {
+	if config == nil || (config.TCPTargetsFile == "" && len(config.TCPTargets) == 0) {
+		return nil, nil
+	}
+	var targets []TCPTarget
+	if config.TCPTargetsFile != "" {
+		data, err := os.ReadFile(config.TCPTargetsFile)
+		if err != nil {
+			return nil, fmt.Errorf("read TCP targets: %w", err)
+		}
+		decoder := json.NewDecoder(bytes.NewReader(data))
+		decoder.DisallowUnknownFields()
+		if err := decoder.Decode(&targets); err != nil {
+			return nil, fmt.Errorf("decode TCP targets %s: %w", config.TCPTargetsFile, err)
+		}
+	}
+	for _, value := range config.TCPTargets {
+		target, err := parseInlineTCPTarget(value)
+		if err != nil {
+			return nil, err
+		}
+		targets = append(targets, target)
+	}
+	endpoints := make(map[string]struct{}, len(targets))
+	for index := range targets {
+		targets[index].ID = strings.TrimSpace(targets[index].ID)
+		targets[index].Name = strings.TrimSpace(targets[index].Name)
+		targets[index].Host = strings.ToLower(strings.TrimSpace(targets[index].Host))
+		targets[index].Category = CustomTCPCategory
+		endpoint := net.JoinHostPort(targets[index].Host, strconv.Itoa(targets[index].Port))
+		if _, exists := endpoints[endpoint]; exists {
+			return nil, fmt.Errorf("invalid custom TCP targets: record %d duplicates endpoint %s", index, endpoint)
+		}
+		endpoints[endpoint] = struct{}{}
+	}
+	data, err := json.Marshal(targets)
+	if err != nil {
+		return nil, err
+	}
+	if err := datasync.ValidateTCPTargets(data); err != nil {
+		return nil, fmt.Errorf("invalid custom TCP targets: %w", err)
+	}
+	return targets, nil
+}
+
+// parseInlineTCPTarget parses name=host:port. IPv6 literals use the usual
+// bracketed form, for example db=[2001:db8::1]:5432.
+func parseInlineTCPTarget(value string) (TCPTarget, error) {
+	name, endpoint, ok := strings.Cut(value, "=")
+	name = strings.TrimSpace(name)
+	if !ok || name == "" {
+		return TCPTarget{}, fmt.Errorf("invalid TCP target %q: expected name=host:port", value)
+	}
+	host, portText, err := net.SplitHostPort(strings.TrimSpace(endpoint))
+	if err != nil {
+		return TCPTarget{}, fmt.Errorf("invalid TCP target %q: %w", value, err)
+	}
+	port, err := strconv.Atoi(portText)
+	if err != nil {
+		return TCPTarget{}, fmt.Errorf("invalid TCP target %q: port must be numeric", value)
+	}
+	if host == "" {
+		return TCPTarget{}, fmt.Errorf("invalid TCP target %q: empty host", value)
+	}
+	return TCPTarget{ID: targetID(name, host, port), Name: name, Host: host, Port: port, Category: CustomTCPCategory}, nil
+}
@@ -0,0 +1,102 @@
+package api
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestLoadCustomTCPTargetsMergesFileAndInlineTargets(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "targets.json")
+	if err := os.WriteFile(path, []byte(`[{"id":"db","name":"Database","host":"DB.internal.test","port":5432,"category":"cloud"}]`), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	config := NewConfig("v-test")
+	config.TCPTargetsFile = path
+	config.TCPTargets = []string{"Cache Node=cache.internal.test:6379", "v6=[2001:db8::1]:443"}
+	targets, err := LoadCustomTCPTargets(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(targets) != 3 || targets[0].Host != "db.internal.test" || targets[1].ID != "cache-node" || targets[2].Host != "2001:db8::1" {
+		t.Fatalf("unexpected custom targets: %+v", targets)
+	}
+	for _, target := range targets {
+		if target.Category != CustomTCPCategory {
+			t.Fatalf("custom target kept category %q", target.Category)
+		}
+	}
+}
+
+func TestLoadCustomTCPTargetsRejectsInvalidTargets(t *testing.T) {
+	for _, inline := range [][]string{
+		{"missing-port.test"},
+		{"bad=host.test:http"},
+		{"one=host.test:443", "two=HOST.test:443"},
+		{"same=one.test:443", "same=two.test:443"},
+		{"zero=host.test:0"},
+	} {
+		config := NewConfig("v-test")
+		config.TCPTargets = inline
+		if _, err := LoadCustomTCPTargets(config); err == nil {
+			t.Fatalf("LoadCustomTCPTargets(%q) succeeded", inline)
+		}
+	}
+}
+
+func TestMergeComponentTCPTargetsPrefersCustomEndpoints(t *testing.T) {
+	snapshot := []TCPTarget{
+		{ID: "github", Name: "GitHub", Host: "github.com", Port: 443, Category: "global"},
+		{ID: "kept", Name: "Kept", Host: "kept.test", Port: 443},
+	}
+	custom := TCPTarget{ID: "my-github", Name: "My GitHub", Host: "github.com", Port: 443, Category: CustomTCPCategory}
+	merged := mergeComponentTCPTargets(snapshot, custom)
+	if len(merged) < 2 || merged[0].ID != "kept" || merged[1].ID != "my-github" {
+		t.Fatalf("custom target did not replace snapshot endpoint: %+v", merged[:min(len(merged), 2)])
+	}
+	for _, target := range merged[2:] {
+		if strings.EqualFold(target.Host, "github.com") && target.Port == 443 {
+			t.Fatalf("registry re-added a custom endpoint: %+v", target)
+		}
+	}
+}
+
+func TestMergeComponentTCPTargetsReplacesBuiltInIDs(t *testing.T) {
+	snapshot := []TCPTarget{
+		{ID: "github", Name: "GitHub", Host: "github.com", Port: 443, Category: "global"},
+		{ID: "kept", Name: "Kept", Host: "kept.test", Port: 443},
+	}
+	config := NewDefaultConfig()
+	config.TCPTargets = []string{"github=10.0.0.1:443"}
+	custom, err := LoadCustomTCPTargets(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	merged := mergeComponentTCPTargets(snapshot, custom...)
+	ids := make(map[string]TCPTarget, len(merged))
+	for _, target := range merged {
+		if previous, exists := ids[target.ID]; exists {
+			t.Fatalf("TCP target ID %q is repeated: %+v and %+v", target.ID, previous, target)
+		}
+		ids[target.ID] = target
+	}
+	if github := ids["github"]; github.Host != "10.0.0.1" || github.Category != CustomTCPCategory {
+		t.Fatalf("custom target did not replace the built-in ID: %+v", github)
+	}
+}
+
+func TestStructuredPrivacyRedactsCustomTCPTargets(t *testing.T) {
+	report := &StructuredReport{PrivacyMode: true, TCP: []TCPReport{
+		{Target: TCPTarget{ID: "db", Name: "Database", Host: "db.internal.test", Port: 5432, Category: CustomTCPCategory}},
+		{Target: TCPTarget{ID: "github", Name: "GitHub", Host: "github.com", Port: 443, Category: "global"}},
+	}}
+	applyStructuredPrivacy(report)
+	custom, public := report.TCP[0].Target, report.TCP[1].Target
+	if custom.Host != privacyRedacted || custom.Name != privacyRedacted || !strings.HasPrefix(custom.ID, "custom-") || len(custom.ID) != len("custom-")+8 {
+		t.Fatalf("custom target was not redacted: %+v", custom)
+	}
+	if public.ID != "github" || public.Name != "GitHub" || public.Host != privacyRedacted {
+		t.Fatalf("public target identity changed: %+v", public)
+	}
+}
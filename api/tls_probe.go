@@ -0,0 +1,251 @@
+package api
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptrace"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+)
+
+const (
+	TLSProbeComponent = "tcp.tls"
+	TLSProbeSchema    = "goecs.tcp/tls-v1"
+)
+
+// TLSProbeResult records one TLS handshake and HTTP HEAD request. Status is
+// "ok" or an error class: the TCP classes from the connect phase, or
+// tls_reset, tls_timeout, sni_blocked, cert_invalid, tls_error and
+// http_error from the later phases. TTFBMS is measured from the start of the
+// request, like curl's time_starttransfer.
+type TLSProbeResult struct {
+	Target       TCPTarget  `json:"target"`
+	Status       string     `json:"status"`
+	ConnectMS    float64    `json:"connect_ms,omitempty"`
+	TLSMS        float64    `json:"tls_ms,omitempty"`
+	TTFBMS       float64    `json:"ttfb_ms,omitempty"`
+	TLSVersion   string     `json:"tls_version,omitempty"`
+	ALPN         string     `json:"alpn,omitempty"`
+	HTTPStatus   int        `json:"http_status,omitempty"`
+	CertNotAfter *time.Time `json:"cert_not_after,omitempty"`
+	Error        string     `json:"error,omitempty"`
+}
+
+type tlsProbePayload struct {
+	SchemaVersion string           `json:"schema_version"`
+	Results       []TLSProbeResult `json:"results"`
+}
+
+// runTLSProbeComponent probes every port 443 target once. A handshake is far
+// heavier than a SYN, so the TCP attempt count does not apply here.
+func runTLSProbeComponent(ctx context.Context, targets []TCPTarget, config tcpProbeConfig) ComponentReport {
+	started := time.Now()
+	var httpsTargets []TCPTarget
+	for _, target := range targets {
+		if target.Port == 443 {
+			httpsTargets = append(httpsTargets, target)
+		}
+	}
+	payload := tlsProbePayload{SchemaVersion: TLSProbeSchema, Results: make([]TLSProbeResult, len(httpsTargets))}
+	if len(httpsTargets) == 0 {
+		report := componentPayload(TLSProbeComponent, TLSProbeSchema, ReportStatusSkipped, started, payload, nil)
+		report.Reason = "no port 443 targets"
+		return report
+	}
+	if config.timeout <= 0 {
+		config.timeout = 3 * time.Second
+	}
+	if config.dial == nil {
+		config.dial = (&net.Dialer{}).DialContext
+	}
+	forEachProbeTarget(ctx, len(httpsTargets), config.concurrency, func(index int) {
+		payload.Results[index] = runOneTLSProbe(ctx, httpsTargets[index], config)
+	})
+	succeeded, probed := 0, 0
+	for index, result := range payload.Results {
+		if result.Status == "" {
+			// Never handed to a worker because the context ended first.
+			payload.Results[index] = TLSProbeResult{Target: httpsTargets[index], Status: "canceled"}
+			continue
+		}
+		probed++
+		if result.Status == "ok" {
+			succeeded++
+		}
+	}
+	status, reason := ReportStatusOK, ""
+	switch {
+	case ctx.Err() != nil && probed < len(httpsTargets):
+		status, _ = contextProgressStatus(ctx)
+		reason = ctx.Err().Error()
+	case succeeded == 0:
+		status, reason = ReportStatusUnavailable, "no TLS probes succeeded"
+	case succeeded < len(httpsTargets):
+		status, reason = ReportStatusPartial, fmt.Sprintf("%d/%d TLS probes succeeded", succeeded, len(httpsTargets))
+	}
+	report := componentPayload(TLSProbeComponent, TLSProbeSchema, status, started, payload, nil)
+	report.Reason = reason
+	return report
+}
+
+func runOneTLSProbe(ctx context.Context, target TCPTarget, config tcpProbeConfig) TLSProbeResult {
+	result := TLSProbeResult{Target: target}
+	address := net.JoinHostPort(target.Host, strconv.Itoa(target.Port))
+	// The transport dials and handshakes on its own goroutine, which can
+	// outlive client.Do when ctx ends.
+	var mu sync.Mutex
+	var connectErr, handshakeErr error
+	var connectMS, handshakeMS float64
+	var handshakeStarted, firstByte time.Time
+	var state *tls.ConnectionState
+	transport := &http.Transport{
+		DialContext: func(ctx context.Context, network, _ string) (net.Conn, error) {
+			dialCtx, cancel := context.WithTimeout(ctx, config.timeout)
+			defer cancel()
+			started := time.Now()
+			conn, err := config.dial(dialCtx, network, address)
+			mu.Lock()
+			defer mu.Unlock()
+			if err != nil {
+				connectErr = err
+				return nil, err
+			}
+			connectMS = float64(time.Since(started).Microseconds()) / 1000
+			return conn, nil
+		},
+		TLSClientConfig:       &tls.Config{RootCAs: config.rootCAs},
+		ForceAttemptHTTP2:     true,
+		TLSHandshakeTimeout:   config.timeout,
+		ResponseHeaderTimeout: config.timeout,
+		DisableKeepAlives:     true,
+	}
+	defer transport.CloseIdleConnections()
+	trace := &httptrace.ClientTrace{
+		TLSHandshakeStart: func() {
+			mu.Lock()
+			handshakeStarted = time.Now()
+			mu.Unlock()
+		},
+		TLSHandshakeDone: func(connection tls.ConnectionState, err error) {
+			mu.Lock()
+			defer mu.Unlock()
+			if err != nil {
+				handshakeErr = err
+				return
+			}
+			handshakeMS = float64(time.Since(handshakeStarted).Microseconds()) / 1000
+			state = &connection
+		},
+		GotFirstResponseByte: func() {
+			mu.Lock()
+			firstByte = time.Now()
+			mu.Unlock()
+		},
+	}
+	client := &http.Client{
+		Transport:     transport,
+		CheckRedirect: func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse },
+	}
+	request, err := http.NewRequestWithContext(httptrace.WithClientTrace(ctx, trace), http.MethodHead, "https://"+address+"/", nil)
+	if err != nil {
+		result.Status, result.Error = "http_error", err.Error()
+		return result
+	}
+	started := time.Now()
+	response, err := client.Do(request)
+	if err == nil {
+		_ = response.Body.Close()
+	}
+	mu.Lock()
+	result.ConnectMS, result.TLSMS = connectMS, handshakeMS
+	if !firstByte.IsZero() {
+		result.TTFBMS = float64(firstByte.Sub(started).Microseconds()) / 1000
+	}
+	connectFailure, handshakeFailure, negotiated := connectErr, handshakeErr, state
+	mu.Unlock()
+	if negotiated != nil {
+		result.TLSVersion = tls.VersionName(negotiated.Version)
+		result.ALPN = negotiated.NegotiatedProtocol
+		if len(negotiated.PeerCertificates) > 0 {
+			notAfter := negotiated.PeerCertificates[0].NotAfter.UTC()
+			result.CertNotAfter = &notAfter
+		}
+	}
+	if err != nil {
+		result.Error = err.Error()
+		switch {
+		case connectFailure != nil:
+			result.Status = classifyTCPError(connectFailure)
+		case negotiated == nil:
+			cause := handshakeFailure
+			if cause == nil {
+				cause = err
+			}
+			result.Status = classifyTLSError(ctx, cause, target, config)
+		case errors.Is(err, context.Canceled):
+			result.Status = "canceled"
+		default:
+			result.Status = "http_error"
+		}
+		return result
+	}
+	result.HTTPStatus = response.StatusCode
+	result.Status = "ok"
+	return result
+}
+
+// classifyTLSError names a handshake failure. A reset or stall is retried
+// once without SNI: if that handshake completes, something on the path is
+// filtering on the server name rather than the server being down.
+func classifyTLSError(ctx context.Context, err error, target TCPTarget, config tcpProbeConfig) string {
+	var verifyErr *tls.CertificateVerificationError
+	var authorityErr x509.UnknownAuthorityError
+	var hostnameErr x509.HostnameError
+	var invalidErr x509.CertificateInvalidError
+	if errors.As(err, &verifyErr) || errors.As(err, &authorityErr) || errors.As(err, &hostnameErr) || errors.As(err, &invalidErr) {
+		return "cert_invalid"
+	}
+	if errors.Is(err, context.Canceled) {
+		return "canceled"
+	}
+	status := ""
+	var netErr net.Error
+	switch {
+	case errors.Is(err, syscall.ECONNRESET) || errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) ||
+		strings.Contains(strings.ToLower(err.Error()), "connection reset"):
+		status = "tls_reset"
+	case errors.Is(err, context.DeadlineExceeded) || (errors.As(err, &netErr) && netErr.Timeout()):
+		status = "tls_timeout"
+	default:
+		return "tls_error"
+	}
+	// Go sends no SNI for IP literals, so the control handshake would be
+	// identical to the failed one.
+	if net.ParseIP(target.Host) == nil && ctx.Err() == nil && tlsHandshakeWithoutSNI(ctx, target, config) {
+		return "sni_blocked"
+	}
+	return status
+}
+
+func tlsHandshakeWithoutSNI(ctx context.Context, target TCPTarget, config tcpProbeConfig) bool {
+	handshakeCtx, cancel := context.WithTimeout(ctx, config.timeout)
+	defer cancel()
+	conn, err := config.dial(handshakeCtx, "tcp", net.JoinHostPort(target.Host, strconv.Itoa(target.Port)))
+	if err != nil {
+		return false
+	}
+	defer conn.Close()
+	// Only reachability matters here; the certificate for the default
+	// virtual host is not expected to match.
+	client := tls.Client(conn, &tls.Config{InsecureSkipVerify: true})
+	return client.HandshakeContext(handshakeCtx) == nil
+}
@@ -0,0 +1,117 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"crypto/x509"
+	"encoding/json"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+// sniFilterProxy forwards to backend but resets any connection whose
+// ClientHello names blocked, like a middlebox filtering on SNI.
+func sniFilterProxy(t *testing.T, backend, blocked string) string {
+	t.Helper()
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { listener.Close() })
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer conn.Close()
+				hello := make([]byte, 4096)
+				n, err := conn.Read(hello)
+				if err != nil {
+					return
+				}
+				if bytes.Contains(hello[:n], []byte(blocked)) {
+					_ = conn.(*net.TCPConn).SetLinger(0)
+					return
+				}
+				upstream, err := net.Dial("tcp", backend)
+				if err != nil {
+					return
+				}
+				defer upstream.Close()
+				if _, err := upstream.Write(hello[:n]); err != nil {
+					return
+				}
+				go io.Copy(upstream, conn)
+				_, _ = io.Copy(conn, upstream)
+			}()
+		}
+	}()
+	return listener.Addr().String()
+}
+
+func TestTLSProbeComponentRecordsTimingsAndClassifiesFailures(t *testing.T) {
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	// The reset connections are expected; keep their handshake errors quiet.
+	server.Config.ErrorLog = log.New(io.Discard, "", 0)
+	server.EnableHTTP2 = true
+	server.StartTLS()
+	defer server.Close()
+	proxy := sniFilterProxy(t, server.Listener.Addr().String(), "blocked.example.com")
+	roots := x509.NewCertPool()
+	roots.AddCert(server.Certificate())
+	dial := func(ctx context.Context, network, address string) (net.Conn, error) {
+		return (&net.Dialer{}).DialContext(ctx, network, proxy)
+	}
+	targets := []TCPTarget{
+		{ID: "ok", Host: "example.com", Port: 443},
+		{ID: "blocked", Host: "blocked.example.com", Port: 443},
+		{ID: "ssh", Host: "example.com", Port: 22},
+	}
+	report := runTLSProbeComponent(context.Background(), targets, tcpProbeConfig{timeout: 2 * time.Second, concurrency: 2, rootCAs: roots, dial: dial})
+	if report.Name != TLSProbeComponent || report.SchemaVersion != TLSProbeSchema || report.Status != ReportStatusPartial {
+		t.Fatalf("unexpected TLS component: %+v", report)
+	}
+	var payload tlsProbePayload
+	if err := json.Unmarshal(report.Payload, &payload); err != nil {
+		t.Fatal(err)
+	}
+	if len(payload.Results) != 2 {
+		t.Fatalf("non-443 target was probed: %+v", payload.Results)
+	}
+	ok, blocked := payload.Results[0], payload.Results[1]
+	if ok.Status != "ok" || ok.HTTPStatus != http.StatusNoContent || ok.TLSVersion == "" || ok.ALPN == "" || ok.CertNotAfter == nil || ok.TLSMS <= 0 || ok.TTFBMS <= 0 {
+		t.Fatalf("unexpected TLS success: %+v", ok)
+	}
+	if blocked.Status != "sni_blocked" {
+		t.Fatalf("SNI reset classified as %q: %+v", blocked.Status, blocked)
+	}
+
+	report = runTLSProbeComponent(context.Background(), targets[:1], tcpProbeConfig{timeout: 2 * time.Second, concurrency: 1, dial: dial})
+	if err := json.Unmarshal(report.Payload, &payload); err != nil {
+		t.Fatal(err)
+	}
+	if report.Status != ReportStatusUnavailable || payload.Results[0].Status != "cert_invalid" {
+		t.Fatalf("untrusted certificate was not classified: %+v", payload.Results)
+	}
+}
+
+func TestTCPStageStatusIncludesTLSComponent(t *testing.T) {
+	reports := []TCPReport{{Attempts: 3, Successful: 3}}
+	if status, _ := tcpStageStatus(reports, nil); status != ReportStatusOK {
+		t.Fatalf("TCP-only status = %q", status)
+	}
+	tls := ComponentReport{Name: TLSProbeComponent, Status: ReportStatusPartial, Reason: "1/2 TLS probes succeeded"}
+	status, reason := tcpStageStatus(reports, []ComponentReport{tls})
+	if status != ReportStatusPartial || reason != "tcp.tls: 1/2 TLS probes succeeded" {
+		t.Fatalf("TLS failure was not folded into the TCP section: %q %q", status, reason)
+	}
+}
@@ -0,0 +1,145 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	UploadFormatText = "text"
+	UploadFormatJSON = "json"
+	UploadFormatBoth = "both"
+)
+
+// uploadRequestTimeout bounds each POST to a self-hosted collector; the run
+// context may have no deadline of its own by the time results are shared.
+const uploadRequestTimeout = 30 * time.Second
+
+// maxUploadResponseBytes caps how much of a collector response is read while
+// looking for the result URL.
+const maxUploadResponseBytes = 1 << 20
+
+var uploadHTTPClient = &http.Client{}
+
+// uploadToEndpoint shares the text and/or JSON report with the collector at
+// config.UploadEndpoint instead of the public paste service. Each format is
+// a separate POST so a collector can route them by Content-Type.
+func uploadToEndpoint(ctx context.Context, config *Config, result *RunResult, finalized *FinalizeResult) error {
+	format := config.UploadFormat
+	if format == "" {
+		format = UploadFormatText
+	}
+	progressStarted(ctx, "upload")
+	var uploadErr error
+	attempted, succeeded := 0, 0
+	if format == UploadFormatText || format == UploadFormatBoth {
+		attempted++
+		text := ansiOutputPattern.ReplaceAllString(result.Output, "")
+		if text == "" {
+			uploadErr = errors.Join(uploadErr, errors.New("upload text report: no text output"))
+		} else if location, err := postReport(ctx, config, []byte(text), "text/plain; charset=utf-8"); err != nil {
+			uploadErr = errors.Join(uploadErr, fmt.Errorf("upload text report: %w", err))
+		} else {
+			finalized.UploadURL = location
+			succeeded++
+		}
+	}
+	if format == UploadFormatJSON || format == UploadFormatBoth {
+		attempted++
+		if len(result.JSON) == 0 {
+			uploadErr = errors.Join(uploadErr, errors.New("upload JSON report: no JSON report"))
+		} else if location, err := postReport(ctx, config, result.JSON, "application/json"); err != nil {
+			uploadErr = errors.Join(uploadErr, fmt.Errorf("upload JSON report: %w", err))
+		} else {
+			finalized.JSONUploadURL = location
+			succeeded++
+		}
+	}
+	switch {
+	case uploadErr == nil:
+		progressCompleted(ctx, "upload", ReportStatusOK, "")
+	case succeeded > 0 && succeeded < attempted:
+		progressCompleted(ctx, "upload", ReportStatusPartial, uploadErr.Error())
+	default:
+		progressCompleted(ctx, "upload", ReportStatusError, uploadErr.Error())
+	}
+	return uploadErr
+}
+
+func postReport(ctx context.Context, config *Config, body []byte, contentType string) (string, error) {
+	requestCtx, cancel := context.WithTimeout(ctx, uploadRequestTimeout)
+	defer cancel()
+	request, err := http.NewRequestWithContext(requestCtx, http.MethodPost, config.UploadEndpoint, bytes.NewReader(body))
+	if err != nil {
+		return "", err
+	}
+	request.Header.Set("Content-Type", contentType)
+	request.Header.Set("User-Agent", "goecs/"+config.EcsVersion)
+	if token := strings.TrimSpace(config.UploadToken); token != "" {
+		request.Header.Set("Authorization", "Bearer "+token)
+	}
+	response, err := uploadHTTPClient.Do(request)
+	if err != nil {
+		return "", err
+	}
+	defer response.Body.Close()
+	data, err := io.ReadAll(io.LimitReader(response.Body, maxUploadResponseBytes))
+	if err != nil {
+		return "", fmt.Errorf("read response: %w", err)
+	}
+	if response.StatusCode < 200 || response.StatusCode > 299 {
+		return "", fmt.Errorf("collector returned HTTP %d", response.StatusCode)
+	}
+	return uploadResultURL(data, config.UploadResultField)
+}
+
+// uploadResultURL extracts the share URL from a collector response. With no
+// field path the whole body is the URL; otherwise the body is JSON and field
+// is a dotted path such as "data.url", where numeric segments index arrays.
+func uploadResultURL(body []byte, field string) (string, error) {
+	location := strings.TrimSpace(string(body))
+	if field = strings.TrimSpace(field); field != "" {
+		var value any
+		if err := json.Unmarshal(body, &value); err != nil {
+			return "", fmt.Errorf("decode collector response: %w", err)
+		}
+		for _, segment := range strings.Split(field, ".") {
+			switch node := value.(type) {
+			case map[string]any:
+				next, ok := node[segment]
+				if !ok {
+					return "", fmt.Errorf("collector response has no field %q", field)
+				}
+				value = next
+			case []any:
+				index, err := strconv.Atoi(segment)
+				if err != nil || index < 0 || index >= len(node) {
+					return "", fmt.Errorf("collector response has no field %q", field)
+				}
+				value = node[index]
+			default:
+				return "", fmt.Errorf("collector response has no field %q", field)
+			}
+		}
+		text, ok := value.(string)
+		if !ok {
+			return "", fmt.Errorf("collector response field %q is not a string", field)
+		}
+		location = strings.TrimSpace(text)
+	}
+	// An HTML error page served with 200 must not be printed as the share link.
+	parsed, err := url.Parse(location)
+	if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
+		return "", fmt.Errorf("collector response is not an http(s) URL: %q", truncateDisplay(compactText(location), 120))
+	}
+	return location, nil
+}
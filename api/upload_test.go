@@ -0,0 +1,91 @@
+package api
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestFinalizeRunResultUploadsToSelfHostedCollector(t *testing.T) {
+	var mu sync.Mutex
+	received := map[string]string{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		if r.Method != http.MethodPost || r.Header.Get("Authorization") != "Bearer secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		contentType := r.Header.Get("Content-Type")
+		mu.Lock()
+		received[contentType] = string(body)
+		mu.Unlock()
+		kind := "text"
+		if contentType == "application/json" {
+			kind = "json"
+		}
+		_, _ = io.WriteString(w, `{"data":{"links":[{"url":"https://collector.test/r/`+kind+`"}]}}`)
+	}))
+	defer server.Close()
+	originalUpload := uploadTextContext
+	t.Cleanup(func() { uploadTextContext = originalUpload })
+	uploadTextContext = func(context.Context, string) (string, string, error) {
+		t.Fatal("public paste service must not be used when a collector is configured")
+		return "", "", nil
+	}
+	config := NewDefaultConfig()
+	config.FilePath = ""
+	config.EnableUpload = true
+	WithUploadEndpoint(server.URL, "secret", "data.links.0.url", UploadFormatBoth)(config)
+	result := &RunResult{
+		Output: "\x1b[32mresult\x1b[0m\n", JSON: []byte(`{"schema_version":"goecs.report/v1"}`),
+		Report: &StructuredReport{Status: ReportStatusOK},
+	}
+	finalized, err := FinalizeRunResultContext(context.Background(), NetCheckResult{Connected: true}, config, result)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if finalized.UploadURL != "https://collector.test/r/text" || finalized.JSONUploadURL != "https://collector.test/r/json" || finalized.HTTPSURL != "" {
+		t.Fatalf("unexpected upload URLs: %+v", finalized)
+	}
+	if received["text/plain; charset=utf-8"] != "result\n" || received["application/json"] != string(result.JSON) {
+		t.Fatalf("collector received %q", received)
+	}
+
+	WithUploadEndpoint(server.URL, "wrong", "", UploadFormatJSON)(config)
+	finalized, err = FinalizeRunResultContext(context.Background(), NetCheckResult{Connected: true}, config, result)
+	if err == nil || !strings.Contains(err.Error(), "HTTP 401") || finalized.JSONUploadURL != "" {
+		t.Fatalf("rejected upload = %+v err=%v", finalized, err)
+	}
+}
+
+func TestUploadResultURLParsesFieldPaths(t *testing.T) {
+	for _, test := range []struct {
+		name, body, field, want string
+	}{
+		{name: "plain body", body: " https://collector.test/r/1\n", want: "https://collector.test/r/1"},
+		{name: "nested field", body: `{"data":{"url":"http://collector.test/r/2"}}`, field: "data.url", want: "http://collector.test/r/2"},
+		{name: "array index", body: `{"files":[{"link":"https://collector.test/r/3"}]}`, field: "files.0.link", want: "https://collector.test/r/3"},
+		{name: "missing field", body: `{"data":{}}`, field: "data.url"},
+		{name: "non-string field", body: `{"data":{"url":7}}`, field: "data.url"},
+		{name: "out of range", body: `{"files":[]}`, field: "files.0"},
+		{name: "html page", body: "<html>maintenance</html>"},
+		{name: "not json", body: "https://collector.test/r/4", field: "url"},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			got, err := uploadResultURL([]byte(test.body), test.field)
+			if test.want == "" {
+				if err == nil {
+					t.Fatalf("expected an error, got %q", got)
+				}
+				return
+			}
+			if err != nil || got != test.want {
+				t.Fatalf("uploadResultURL = %q err=%v, want %q", got, err, test.want)
+			}
+		})
+	}
+}
@@ -10,6 +10,7 @@ import (
 	"os"
 	"time"
 
+	datarepo "github.com/oneclickvirt/ecs/internal/data"
 	datasync "github.com/oneclickvirt/ecs/internal/data/sync"
 )
 
@@ -17,6 +18,12 @@ func main() {
 	output := flag.String("output", datasync.DefaultOutputDir, "directory for generated data")
 	showVersion := flag.Bool("version", false, "show data synchronizer version")
 	timeout := flag.Duration("timeout", 5*time.Minute, "overall synchronization timeout")
+	signKey := flag.String("sign-key", "", "ed25519 private key file (PKCS#8 PEM or base64) used to write manifest.json.sig")
+	sources := flag.String("sources", "", "YAML or JSON source list replacing the built-in upstream sources")
+	dryRun := flag.Bool("dry-run", false, "run every fetch, health check and guard without writing the snapshot")
+	healthFailures := flag.Int("health-failures", datasync.DefaultHealthFailures, "consecutive failed probes before a speedtest or transfer node is marked unavailable")
+	healthRecoveries := flag.Int("health-recoveries", datasync.DefaultHealthRecoveries, "consecutive successful probes before an unavailable node is marked available again")
+	reportPath := flag.String("report", "", "write a per-file change report to this path (\"-\" for stdout; dry runs default to stdout)")
 	flag.Parse()
 	if *showVersion {
 		fmt.Println(datasync.Version())
@@ -26,16 +33,59 @@ func main() {
 		fmt.Fprintln(os.Stderr, "timeout must be positive")
 		os.Exit(2)
 	}
+	if *healthFailures <= 0 || *healthRecoveries <= 0 {
+		fmt.Fprintln(os.Stderr, "health thresholds must be positive")
+		os.Exit(2)
+	}
+	options := datasync.Options{SourcesFile: *sources, DryRun: *dryRun, HealthFailures: *healthFailures, HealthRecoveries: *healthRecoveries}
+	if *signKey != "" {
+		keyData, err := os.ReadFile(*signKey)
+		if err == nil {
+			options.SignKey, err = datarepo.ParseSigningKey(keyData)
+		}
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "load signing key: %v\n", err)
+			os.Exit(2)
+		}
+		// The runtime only trusts signatures from the key built into it.
+		fmt.Fprintf(os.Stderr, "signing manifest with public key %s\n", datarepo.EncodePublicKey(options.SignKey))
+	}
 	ctx, cancel := context.WithTimeout(context.Background(), *timeout)
 	defer cancel()
-	changed, err := datasync.Sync(ctx, *output)
+	report, err := datasync.SyncReport(ctx, *output, options)
+	// A report is still written when a guard refuses the update so the
+	// rejected change can be reviewed.
+	if report != nil && (*reportPath != "" || *dryRun) {
+		if writeErr := writeReport(*reportPath, report); writeErr != nil {
+			fmt.Fprintf(os.Stderr, "write report: %v\n", writeErr)
+			os.Exit(1)
+		}
+	}
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "sync data: %v\n", err)
 		os.Exit(1)
 	}
-	if changed {
+	switch {
+	case report.Changed && *dryRun:
+		fmt.Println("data snapshot would be updated (dry run)")
+	case report.Changed:
 		fmt.Println("data snapshot updated")
-	} else {
+	default:
 		fmt.Println("data snapshot unchanged")
 	}
 }
+
+func writeReport(path string, report *datasync.Report) error {
+	if path == "" || path == "-" {
+		return report.WriteText(os.Stdout)
+	}
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := report.WriteText(file); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
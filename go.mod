@@ -21,6 +21,8 @@ require (
 	github.com/oneclickvirt/privatespeedtest v0.0.3
 	github.com/oneclickvirt/security v0.0.13
 	github.com/oneclickvirt/speedtest v0.0.16
+	github.com/quic-go/quic-go v0.60.0
+	gopkg.in/yaml.v3 v3.0.1
 )
 
 require (
@@ -94,7 +96,6 @@ require (
 	github.com/power-devops/perfstat v0.0.0-20210106213030-5aafc221ea8c // indirect
 	github.com/prometheus-community/pro-bing v0.4.1 // indirect
 	github.com/quic-go/qpack v0.6.0 // indirect
-	github.com/quic-go/quic-go v0.60.0 // indirect
 	github.com/refraction-networking/utls v1.8.2 // indirect
 	github.com/rivo/uniseg v0.4.7 // indirect
 	github.com/rodaine/table v1.3.1 // indirect
@@ -128,6 +129,5 @@ require (
 	golang.org/x/term v0.44.0 // indirect
 	golang.org/x/text v0.38.0 // indirect
 	golang.org/x/tools v0.45.0 // indirect
-	gopkg.in/yaml.v3 v3.0.1 // indirect
 	howett.net/plist v1.0.0 // indirect
 )
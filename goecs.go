@@ -17,6 +17,7 @@ import (
 	cputestmodel "github.com/oneclickvirt/cputest/model"
 	disktestmodel "github.com/oneclickvirt/disktest/disk"
 	ecsapi "github.com/oneclickvirt/ecs/api"
+	"github.com/oneclickvirt/ecs/internal/analysis"
 	menu "github.com/oneclickvirt/ecs/internal/menu"
 	params "github.com/oneclickvirt/ecs/internal/params"
 	"github.com/oneclickvirt/ecs/internal/runner"
@@ -65,8 +66,13 @@ func shouldWaitForExitInput() bool {
 	return (runtime.GOOS == "windows" || runtime.GOOS == "darwin") && !utils.IsNonInteractive()
 }
 
+// shouldRunStructuredCLI also routes -fail-on partial or error, since only a
+// structured report records section failures.
 func shouldRunStructuredCLI(config *params.Config) bool {
-	return config != nil && config.JSONPath != ""
+	return config != nil && (config.FailOn == "partial" || config.FailOn == "error" || config.JSONPath != "" || config.ComparePath != "" || config.MetricsPath != "" ||
+		config.HistoryDir != "" || config.RepeatInterval > 0 || config.TCPTargetsFile != "" || len(config.TCPTargets) > 0 ||
+		config.TLSProbeStatus || config.QUICTestStatus || config.UploadEndpoint != "" || config.DataOverlayDir != "" ||
+		len(config.Assertions) > 0 || config.AssertPreset != "")
 }
 
 func legacyDeadlineWindows(maxDuration time.Duration) (time.Duration, time.Duration) {
@@ -78,12 +84,56 @@ func legacyDeadlineWindows(maxDuration time.Duration) (time.Duration, time.Durat
 	return softDeadline, maxDuration
 }
 
-func runStructuredCLI(preCheck utils.NetCheckResult, config *params.Config) {
+// runStructuredCLI returns the most severe exit code of its runs, so a
+// failure in one repeated run is not hidden by later successful ones.
+func runStructuredCLI(preCheck utils.NetCheckResult, config *params.Config) int {
 	if config == nil {
-		return
+		return ecsapi.ExitCodeUsage
+	}
+	// Reject a bad custom target list before any benchmark starts; the run
+	// itself would only record the error as a partial TCP section.
+	if _, err := ecsapi.LoadCustomTCPTargets((*ecsapi.Config)(config)); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return ecsapi.ExitCodeUsage
+	}
+	if err := ecsapi.ValidateDataOverlay((*ecsapi.Config)(config)); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return ecsapi.ExitCodeUsage
+	}
+	if err := ecsapi.ValidateAssertions((*ecsapi.Config)(config)); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return ecsapi.ExitCodeUsage
 	}
 	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
 	defer stop()
+	var history *ecsapi.HistoryStore
+	if config.HistoryDir != "" {
+		history = ecsapi.NewHistoryStore(config.HistoryDir, int64(config.HistoryMaxMB)<<20)
+	}
+	code := ecsapi.ExitCodeOK
+	for {
+		started := time.Now()
+		runCode, again := runStructuredIteration(ctx, preCheck, config, history)
+		code = ecsapi.WorstExitCode(code, runCode)
+		if !again || config.RepeatInterval <= 0 {
+			return code
+		}
+		// Runs start on a fixed cadence; a run that overruns the interval is
+		// followed immediately by the next one.
+		wait := time.NewTimer(time.Until(started.Add(config.RepeatInterval)))
+		select {
+		case <-ctx.Done():
+			wait.Stop()
+			return code
+		case <-wait.C:
+		}
+	}
+}
+
+// runStructuredIteration performs one structured run with its own soft and
+// hard deadlines. It returns the run's exit code and false when no further
+// run should be attempted.
+func runStructuredIteration(ctx context.Context, preCheck utils.NetCheckResult, config *params.Config, history *ecsapi.HistoryStore) (int, bool) {
 	softDeadline, hardDeadline := legacyDeadlineWindows(config.MaxDuration)
 	softTimer := time.NewTimer(softDeadline)
 	defer softTimer.Stop()
@@ -104,29 +154,96 @@ func runStructuredCLI(preCheck utils.NetCheckResult, config *params.Config) {
 	case result = <-resultCh:
 	case <-hardTimer.C:
 		fmt.Fprintln(os.Stderr, "global structured deadline exceeded; terminating benchmark process group")
-		runner.ForceExit(1)
-		return
+		runner.ForceExit(ecsapi.ExitCodeTimeout)
+		return ecsapi.ExitCodeTimeout, false
 	}
 	if result == nil {
 		fmt.Fprintln(os.Stderr, "failed to run structured ECS tests")
-		return
+		return ecsapi.ExitCodeError, false
 	}
 	if config.JSONPath != "-" && result.StructuredOutput != "" {
 		fmt.Print(result.StructuredOutput)
 	}
-	finalized, err := ecsapi.FinalizeRunResultContext(ctx, preCheck, (*ecsapi.Config)(config), result)
-	if err != nil {
-		fmt.Fprintf(os.Stderr, "failed to finalize result: %v\n", err)
+	finalized, finalizeErr := ecsapi.FinalizeRunResultContext(ctx, preCheck, (*ecsapi.Config)(config), result)
+	if finalizeErr != nil {
+		fmt.Fprintf(os.Stderr, "failed to finalize result: %v\n", finalizeErr)
 	}
 	if finalized.HTTPURL != "" || finalized.HTTPSURL != "" {
 		fmt.Printf("Http URL:  %s\nHttps URL: %s\n", finalized.HTTPURL, finalized.HTTPSURL)
 	}
+	if finalized.UploadURL != "" {
+		fmt.Printf("Upload URL: %s\n", finalized.UploadURL)
+	}
+	if finalized.JSONUploadURL != "" {
+		fmt.Printf("JSON URL:   %s\n", finalized.JSONUploadURL)
+	}
 	if config.JSONPath == "-" {
 		fmt.Println(string(result.JSON))
 	}
+	if config.ComparePath != "" {
+		if err := compareWithBaseline(config, result); err != nil {
+			fmt.Fprintf(os.Stderr, "failed to compare with baseline: %v\n", err)
+		}
+	}
+	if result.Report != nil {
+		// Assertion results go to stderr so stdout stays machine-readable.
+		for _, assertion := range result.Report.Assertions {
+			fmt.Fprintln(os.Stderr, assertion)
+		}
+	}
+	if history != nil && result.Report != nil {
+		if err := history.Append(result.Report); err != nil {
+			fmt.Fprintf(os.Stderr, "failed to append history: %v\n", err)
+		}
+	}
+	return ecsapi.RunExitCode((*ecsapi.Config)(config), result, finalizeErr), ctx.Err() == nil
+}
+
+// compareWithBaseline keeps stdout machine-readable when either the report
+// or the comparison is streamed there; the text table then goes to stderr.
+func compareWithBaseline(config *params.Config, result *ecsapi.RunResult) error {
+	if result.Report == nil {
+		return fmt.Errorf("structured report is unavailable")
+	}
+	baseline, err := ecsapi.LoadStructuredReport(config.ComparePath)
+	if err != nil {
+		return err
+	}
+	comparison, err := ecsapi.CompareReports(baseline, result.Report)
+	if err != nil {
+		return err
+	}
+	text := ecsapi.RenderComparisonText((*ecsapi.Config)(config), comparison)
+	if config.JSONPath == "-" || config.CompareJSONPath == "-" {
+		fmt.Fprint(os.Stderr, text)
+	} else {
+		fmt.Print(text)
+	}
+	if config.CompareJSONPath == "" {
+		return nil
+	}
+	data, err := comparison.JSON()
+	if err != nil {
+		return err
+	}
+	if config.CompareJSONPath == "-" {
+		fmt.Println(string(data))
+		return nil
+	}
+	return os.WriteFile(config.CompareJSONPath, append(data, '\n'), 0o600)
 }
 
 func main() {
+	if len(os.Args) > 1 {
+		switch os.Args[1] {
+		case "serve":
+			os.Exit(runServeCommand(os.Args[2:]))
+		case "history":
+			os.Exit(runHistoryCommand(os.Args[2:]))
+		case "render":
+			os.Exit(runRenderCommand(os.Args[2:]))
+		}
+	}
 	runner.IsolateProcessGroup()
 	configs.ParseFlags(os.Args[1:])
 	applyEnvironmentDefaults(configs)
@@ -147,6 +264,9 @@ func main() {
 	if configs.MenuMode {
 		menu.HandleMenuMode(preCheck, configs)
 	} else {
+		if configs.Choice != "" {
+			menu.ApplyPreset(preCheck, configs, configs.Choice)
+		}
 		configs.OnlyIpInfoCheck = true
 	}
 	handleLanguageSpecificSettings()
@@ -158,13 +278,13 @@ func main() {
 	// replace the legacy streaming sections merely because structured adapters
 	// are compiled into this build.
 	if shouldRunStructuredCLI(configs) {
-		runStructuredCLI(preCheck, configs)
+		code := runStructuredCLI(preCheck, configs)
 		configs.Finish = true
-		if shouldWaitForExitInput() && configs.JSONPath != "-" {
+		if shouldWaitForExitInput() && configs.JSONPath != "-" && configs.CompareJSONPath != "-" {
 			fmt.Println("Press Enter to exit...")
 			fmt.Scanln()
 		}
-		return
+		os.Exit(code)
 	}
 	var (
 		wg1, wg2, wg3                                         sync.WaitGroup
@@ -181,10 +301,13 @@ func main() {
 	defer cancel()
 	softDeadline, hardDeadline := legacyDeadlineWindows(configs.MaxDuration)
 	softDeadlineTimer := time.AfterFunc(softDeadline, cancel)
-	hardDeadlineTimer := time.AfterFunc(hardDeadline, func() { runner.ForceExit(1) })
+	hardDeadlineTimer := time.AfterFunc(hardDeadline, func() { runner.ForceExit(ecsapi.ExitCodeTimeout) })
 	defer softDeadlineTimer.Stop()
 	defer hardDeadlineTimer.Stop()
-	go runner.HandleSignalInterrupt(ctx, cancel, sig, configs, &startTime, &output, tempOutput, uploadDone, &outputMutex)
+	failOn := ecsapi.ReportStatus(configs.FailOn)
+	go runner.HandleSignalInterrupt(ctx, cancel, sig, configs, &startTime, &output, tempOutput, uploadDone, &outputMutex,
+		ecsapi.StatusExitCode(ecsapi.ReportStatusCanceled, failOn))
+	ran := true
 	switch configs.Language {
 	case "zh":
 		runner.RunChineseTests(ctx, preCheck, configs, &wg1, &wg2, &wg3, &basicInfo, &securityInfo, &emailInfo, &mediaInfo, &ptInfo, &output, tempOutput, startTime, &outputMutex, &infoMutex)
@@ -192,9 +315,10 @@ func main() {
 		runner.RunEnglishTests(ctx, preCheck, configs, &wg1, &wg2, &wg3, &basicInfo, &securityInfo, &emailInfo, &mediaInfo, &ptInfo, &output, tempOutput, startTime, &outputMutex, &infoMutex)
 	default:
 		fmt.Println("Unsupported language")
+		ran = false
 	}
 	if ctx.Err() == nil && configs.AnalyzeResult {
-		output = runner.AppendAnalysisSummary(configs, output, tempOutput, &outputMutex)
+		output = runner.AppendAnalysisSummary(analysis.GenerateSummary(configs, output), output, tempOutput, &outputMutex)
 	}
 	// HandleUploadResults always writes the local result file. Keep that
 	// behavior after a deadline/cancellation; EnableUpload alone controls the
@@ -202,9 +326,24 @@ func main() {
 	if preCheck.Connected || output != "" {
 		runner.HandleUploadResults(configs, output)
 	}
+	// The text workflow has no report, so it can only tell a run that hit
+	// the soft deadline (timeout) or ran no tests (error) from one that
+	// finished; an interrupt exits as canceled from HandleSignalInterrupt.
+	// Section failures need the structured path, which -fail-on partial and
+	// error select.
+	status := ecsapi.ReportStatusOK
+	switch {
+	case ctx.Err() != nil:
+		status = ecsapi.ReportStatusTimeout
+	case !ran:
+		status = ecsapi.ReportStatusError
+	}
 	configs.Finish = true
 	if shouldWaitForExitInput() {
 		fmt.Println("Press Enter to exit...")
 		fmt.Scanln()
 	}
+	softDeadlineTimer.Stop()
+	hardDeadlineTimer.Stop()
+	os.Exit(ecsapi.StatusExitCode(status, failOn))
 }
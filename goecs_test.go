@@ -59,6 +59,52 @@ func TestStructuredCLIRequiresExplicitJSONOutput(t *testing.T) {
 			t.Fatalf("JSON path %q did not select structured CLI mode", path)
 		}
 	}
+	cfg.JSONPath = ""
+	cfg.ComparePath = "baseline.json"
+	if !shouldRunStructuredCLI(cfg) {
+		t.Fatal("baseline comparison did not select structured CLI mode")
+	}
+	cfg.ComparePath = ""
+	cfg.MetricsPath = "goecs.prom"
+	if !shouldRunStructuredCLI(cfg) {
+		t.Fatal("metrics export did not select structured CLI mode")
+	}
+	cfg.MetricsPath = ""
+	cfg.TCPTargets = []string{"db=db.test:5432"}
+	if !shouldRunStructuredCLI(cfg) {
+		t.Fatal("custom TCP targets did not select structured CLI mode")
+	}
+	cfg.TCPTargets = nil
+	cfg.UploadEndpoint = "https://collector.test/results"
+	if !shouldRunStructuredCLI(cfg) {
+		t.Fatal("self-hosted upload did not select structured CLI mode")
+	}
+	cfg.UploadEndpoint = ""
+	cfg.DataOverlayDir = "/etc/goecs/data"
+	if !shouldRunStructuredCLI(cfg) {
+		t.Fatal("data overlay did not select structured CLI mode")
+	}
+	cfg.DataOverlayDir = ""
+	cfg.AssertPreset = "baseline"
+	if !shouldRunStructuredCLI(cfg) {
+		t.Fatal("assertions did not select structured CLI mode")
+	}
+	cfg.AssertPreset = ""
+	cfg.AnalyzeResult = true
+	if shouldRunStructuredCLI(cfg) {
+		t.Fatal("summary analysis must keep the streaming text runner")
+	}
+	cfg.AnalyzeResult = false
+	for _, failOn := range []string{"partial", "error"} {
+		cfg.FailOn = failOn
+		if !shouldRunStructuredCLI(cfg) {
+			t.Fatalf("-fail-on %s did not select structured CLI mode", failOn)
+		}
+	}
+	cfg.FailOn = "timeout"
+	if shouldRunStructuredCLI(cfg) {
+		t.Fatal("the default -fail-on must keep the streaming text runner")
+	}
 }
 
 func TestLegacyDeadlineKeepsOneCleanupWindow(t *testing.T) {
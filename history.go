@@ -0,0 +1,58 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"time"
+
+	ecsapi "github.com/oneclickvirt/ecs/api"
+)
+
+// runHistoryCommand implements "goecs history". It returns the process exit
+// code.
+func runHistoryCommand(args []string) int {
+	flags := flag.NewFlagSet("goecs history", flag.ContinueOnError)
+	dir := flags.String("dir", "", "History directory written by -history-dir")
+	window := flags.Duration("since", 24*time.Hour, "Summarize runs finished within this window (0 for all)")
+	language := flags.String("lang", "zh", "Set language (supported: en, zh)")
+	asJSON := flags.Bool("json", false, "Print summaries as JSON")
+	if err := flags.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return 0
+		}
+		return 2
+	}
+	if *dir == "" {
+		fmt.Fprintln(os.Stderr, "history: -dir is required")
+		return 2
+	}
+	var since time.Time
+	if *window > 0 {
+		since = time.Now().Add(-*window)
+	}
+	reports, skipped, err := ecsapi.NewHistoryStore(*dir, 0).Load(since)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "history: %v\n", err)
+		return 1
+	}
+	if skipped > 0 {
+		fmt.Fprintf(os.Stderr, "history: skipped %d unreadable records\n", skipped)
+	}
+	summaries := ecsapi.SummarizeHistory(reports)
+	if *asJSON {
+		data, err := json.MarshalIndent(summaries, "", "  ")
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "history: %v\n", err)
+			return 1
+		}
+		fmt.Println(string(data))
+		return 0
+	}
+	config := ecsapi.NewConfig(ecsVersion)
+	config.Language = *language
+	fmt.Print(ecsapi.RenderHistoryText(config, len(reports), since, summaries))
+	return 0
+}
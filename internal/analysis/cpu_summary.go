@@ -1,15 +1,16 @@
 package analysis
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
-	"io"
 	"regexp"
 	"strconv"
 	"strings"
 	"time"
 
-	"github.com/imroc/req/v3"
+	datarepo "github.com/oneclickvirt/ecs/internal/data"
+	"github.com/oneclickvirt/ecs/internal/params"
 )
 
 func parseFloatString(s string) (float64, bool) {
@@ -168,89 +169,48 @@ func fuzzyScoreCPUModel(model string, entry cpuStatsEntry) float64 {
 	return overlapScore
 }
 
-func loadCPUStats() *cpuStatsPayload {
+// loadCPUStats reads the CPU ranking from the goecs data snapshot, so the
+// summary honours the same CDN, cache, overlay and offline settings as the
+// rest of a run. A nil config uses the loader defaults. A failed load is
+// cached for cpuStatsFailCacheTTL and returned as the error.
+func loadCPUStats(config *params.Config) (*cpuStatsPayload, error) {
 	cpuStatsMu.Lock()
 	defer cpuStatsMu.Unlock()
 
 	now := time.Now()
 	if now.Before(cpuStatsExpireAt) {
-		return cachedCPUStats
+		return cachedCPUStats, cpuStatsErr
 	}
 
-	client := req.C()
-	client.SetTimeout(cpuStatsRequestTimout)
-	endpoints := []string{cpuStatsPrimaryURL, cpuStatsFallbackURL}
-
-	availableCDN := detectAvailableCPUCDN(client)
-	for _, endpoint := range endpoints {
-		urls := []string{}
-		if availableCDN != "" {
-			urls = append(urls, availableCDN+endpoint)
-		}
-		urls = append(urls, endpoint)
-
-		for _, u := range urls {
-			payload := tryDecodeCPUStatsFromURL(client, u)
-			if payload == nil {
-				continue
-			}
-			cachedCPUStats = payload
-			cpuStatsExpireAt = now.Add(cpuStatsCacheTTL)
-			return cachedCPUStats
-		}
-	}
-
-	cachedCPUStats = nil
-	cpuStatsExpireAt = now.Add(cpuStatsFailCacheTTL)
-	return nil
-}
-
-func detectAvailableCPUCDN(client *req.Client) string {
-	for _, baseURL := range cpuStatsCDNList {
-		if checkCPUCDN(client, baseURL) {
-			return baseURL
-		}
-		time.Sleep(500 * time.Millisecond)
-	}
-	return ""
-}
-
-func checkCPUCDN(client *req.Client, baseURL string) bool {
-	resp, err := client.R().SetHeader("User-Agent", "goecs-summary/1.0").Get(baseURL + cpuCDNProbeTestURL)
-	if err != nil {
-		return false
-	}
-	defer resp.Body.Close()
-	if resp.StatusCode != 200 {
-		return false
+	ctx, cancel := context.WithTimeout(context.Background(), cpuStatsLoadTimeout)
+	defer cancel()
+	loaded, err := newCPUStatsLoader(config).Load(ctx, cpuStatsFile)
+	var entries []cpuStatsEntry
+	if err == nil {
+		err = json.Unmarshal(loaded.Data, &entries)
 	}
-
-	b, err := io.ReadAll(io.LimitReader(resp.Body, 4<<10))
 	if err != nil {
-		return false
+		cachedCPUStats, cpuStatsErr = nil, fmt.Errorf("load CPU statistics: %w", err)
+		cpuStatsExpireAt = now.Add(cpuStatsFailCacheTTL)
+		return nil, cpuStatsErr
 	}
-	return strings.Contains(string(b), "success")
+	cachedCPUStats, cpuStatsErr = &cpuStatsPayload{CPUStatistics: entries}, nil
+	cpuStatsExpireAt = now.Add(cpuStatsCacheTTL)
+	return cachedCPUStats, nil
 }
 
-func tryDecodeCPUStatsFromURL(client *req.Client, u string) *cpuStatsPayload {
-	resp, err := client.R().SetHeader("User-Agent", "goecs-summary/1.0").Get(u)
-	if err != nil {
-		return nil
-	}
-	defer resp.Body.Close()
-	if resp.StatusCode != 200 {
-		return nil
-	}
-
-	var payload cpuStatsPayload
-	dec := json.NewDecoder(io.LimitReader(resp.Body, 8<<20))
-	if err := dec.Decode(&payload); err != nil {
-		return nil
+func newCPUStatsLoader(config *params.Config) *datarepo.Loader {
+	if config == nil {
+		return datarepo.NewLoader(nil, "")
 	}
-	if len(payload.CPUStatistics) == 0 {
-		return nil
+	loader := datarepo.NewLoader(nil, config.DataCDNBase)
+	loader.CacheDir, loader.CacheMaxAge = config.DataCacheDir, config.DataCacheMaxAge
+	loader.OverlayDir = config.DataOverlayDir
+	if config.DataOffline {
+		loader.CDNBase = ""
+		loader.RawBase = ""
 	}
-	return &payload
+	return loader
 }
 
 func matchCPUStatsEntry(model string, payload *cpuStatsPayload) *cpuStatsEntry {
@@ -317,14 +277,14 @@ func cpuTierText(score float64, lang string) string {
 	}
 }
 
-func summarizeCPUWithRanking(finalOutput, lang string) []string {
+func summarizeCPUWithRanking(config *params.Config, finalOutput, lang string) []string {
 	model := extractCPUModel(finalOutput)
 	single, singleOK, multi, multiOK := extractCPUScores(finalOutput)
 	if !singleOK && !multiOK {
 		return nil
 	}
 
-	stats := loadCPUStats()
+	stats, statsErr := loadCPUStats(config)
 	entry := matchCPUStatsEntry(model, stats)
 
 	var score float64
@@ -363,6 +323,14 @@ func summarizeCPUWithRanking(finalOutput, lang string) []string {
 		lines = append(lines, cpuTierText(score, lang))
 	}
 
+	if statsErr != nil {
+		if lang == "zh" {
+			lines = append(lines, fmt.Sprintf("CPU 对标: CPU 榜单不可用（%v），已仅给出本机分数解读。", statsErr))
+		} else {
+			lines = append(lines, fmt.Sprintf("CPU ranking: CPU statistics unavailable (%v); local score interpretation only.", statsErr))
+		}
+		return lines
+	}
 	if entry == nil || avg <= 0 || max <= 0 {
 		if lang == "zh" {
 			if model != "" {
@@ -0,0 +1,457 @@
+package analysis
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/oneclickvirt/ecs/internal/params"
+)
+
+const SummarySchema = "goecs.analysis/summary-v1"
+
+// Summary is the post-test summary. A section is nil when its test did not
+// run; a section whose test ran without usable data is present with zero
+// values and renders as N/A.
+type Summary struct {
+	SchemaVersion string            `json:"schema_version"`
+	CPU           *CPUSummary       `json:"cpu,omitempty"`
+	Memory        *MemorySummary    `json:"memory,omitempty"`
+	Disk          *DiskSummary      `json:"disk,omitempty"`
+	Bandwidth     *BandwidthSummary `json:"bandwidth,omitempty"`
+	ISPRanking    *ISPRanking       `json:"isp_ranking,omitempty"`
+}
+
+// CPUSummary compares a CPU score with the best score recorded for the same
+// model. ScoreType is "single" or "multi"; Rank and Percent are zero when the
+// model is not in the CPU statistics.
+type CPUSummary struct {
+	Model     string  `json:"model,omitempty"`
+	ScoreType string  `json:"score_type,omitempty"`
+	Score     float64 `json:"score,omitempty"`
+	Rank      int     `json:"rank,omitempty"`
+	MaxScore  float64 `json:"max_score,omitempty"`
+	Percent   float64 `json:"percent,omitempty"`
+	// RankError is set when the CPU statistics could not be loaded, which
+	// leaves the score unranked for a reason other than an unknown model.
+	RankError string `json:"rank_error,omitempty"`
+}
+
+// MemorySummary infers the memory generation and channel count ("single" or
+// "dual") from the best memory bandwidth in MiB/s.
+type MemorySummary struct {
+	BandwidthMBps float64 `json:"bandwidth_mbps,omitempty"`
+	DDR           string  `json:"ddr,omitempty"`
+	Channels      string  `json:"channels,omitempty"`
+	BelowAverage  bool    `json:"below_average,omitempty"`
+}
+
+// DiskSummary classifies storage by its best 4K read speed in MiB/s, or any
+// read speed when no 4K result exists. Type is one of nvme_ssd, sata_ssd,
+// hdd and low_performance.
+type DiskSummary struct {
+	ReadMBps     float64 `json:"read_mbps,omitempty"`
+	Type         string  `json:"type,omitempty"`
+	Paths        int     `json:"paths,omitempty"`
+	BelowAverage bool    `json:"below_average,omitempty"`
+}
+
+type BandwidthSummary struct {
+	PeakMbps float64 `json:"peak_mbps,omitempty"`
+}
+
+// ISPRanking orders the domestic carriers by the best route quality seen
+// towards each: 3 for premium, 2 for quality and 1 for ordinary lines.
+type ISPRanking struct {
+	Carriers []CarrierRank `json:"carriers,omitempty"`
+}
+
+type CarrierRank struct {
+	Carrier string `json:"carrier"`
+	Quality int    `json:"quality"`
+	Route   string `json:"route,omitempty"`
+}
+
+const (
+	// memAvgThreshMbps follows README_NEW_USER: below about 10 GB/s suggests
+	// overselling.
+	memAvgThreshMbps = 10240.0
+	// diskAvgThreshMbps follows README_NEW_USER: below 10 MB/s means poor
+	// performance or severe overselling.
+	diskAvgThreshMbps = 10.0
+)
+
+var carrierOrder = []string{"ct", "cu", "cm"}
+
+// routeQualities maps nt3 route classes, the api.RouteClass values, to the
+// legacy backtrace quality tiers used by the ISP ranking.
+var routeQualities = map[string]int{
+	"cn2_gia": 3, "cu_9929": 3, "cm_cmin2": 3,
+	"cn2_gt": 2, "cu_10099": 2, "cm_cmi": 2,
+	"chinanet_163": 1, "cu_4837": 1, "cm_9808": 1,
+}
+
+// RouteQuality returns the ISP ranking tier of an nt3 route class, or 0 for a
+// class the ranking does not know.
+func RouteQuality(class string) int {
+	return routeQualities[class]
+}
+
+// Component is the name and payload of one component of a structured
+// report. The api package passes its components in this form, since it
+// imports this package and so cannot be imported here.
+type Component struct {
+	Name    string
+	Payload json.RawMessage
+}
+
+// DiskMetric is one fio scenario of a disktest payload.
+type DiskMetric struct {
+	ScenarioID              string  `json:"scenario_id"`
+	Direction               string  `json:"direction"`
+	BandwidthBytesPerSecond float64 `json:"bandwidth_bytes_per_second"`
+	IOPS                    float64 `json:"iops"`
+}
+
+// MemoryBandwidthMbps returns the best of the sequential and copy bandwidths
+// of a memorytest payload in MiB/s. The post-test summary and -assert both
+// rank memory by it.
+func MemoryBandwidthMbps(payload json.RawMessage) float64 {
+	var memory struct {
+		SequentialReadMBps  float64 `json:"sequential_read_mbps"`
+		SequentialWriteMBps float64 `json:"sequential_write_mbps"`
+		CopyMBps            float64 `json:"copy_mbps"`
+	}
+	if json.Unmarshal(payload, &memory) != nil {
+		return 0
+	}
+	return max(memory.SequentialReadMBps, memory.SequentialWriteMBps, memory.CopyMBps)
+}
+
+// DiskReadMbps returns the best 4K read bandwidth of metrics in MiB/s, or
+// the best read bandwidth of any block size when no 4K scenario ran.
+func DiskReadMbps(metrics []DiskMetric) float64 {
+	var small, all float64
+	for _, metric := range metrics {
+		if metric.Direction != "read" {
+			continue
+		}
+		mbps := metric.BandwidthBytesPerSecond / (1 << 20)
+		all = max(all, mbps)
+		if strings.HasPrefix(strings.ToLower(metric.ScenarioID), "4k") {
+			small = max(small, mbps)
+		}
+	}
+	if small > 0 {
+		return small
+	}
+	return all
+}
+
+// SummarizeComponents computes the summary from component payloads, so it
+// does not depend on the language or wording of the rendered text. The
+// config's data settings select where the CPU ranking is loaded from; nil
+// uses the defaults.
+func SummarizeComponents(config *params.Config, components []Component) *Summary {
+	summary := &Summary{SchemaVersion: SummarySchema}
+	var model string
+	var cpuResult *reportCPU
+	var diskMetrics []DiskMetric
+	diskRan, diskPaths := false, 0
+	var peak float64
+	speedRan := false
+	var routes []reportRoute
+	routesRan := false
+	for _, component := range components {
+		switch component.Name {
+		case "basics":
+			var basics struct {
+				CPU struct {
+					Model string `json:"model"`
+				} `json:"cpu"`
+			}
+			if json.Unmarshal(component.Payload, &basics) == nil {
+				model = strings.TrimSpace(basics.CPU.Model)
+			}
+		case "cputest":
+			cpuResult = &reportCPU{}
+			_ = json.Unmarshal(component.Payload, cpuResult)
+		case "memorytest":
+			summary.Memory = summarizeMemory(MemoryBandwidthMbps(component.Payload))
+		case "disktest":
+			var matrix struct {
+				Metrics []DiskMetric `json:"metrics"`
+			}
+			diskRan = true
+			if json.Unmarshal(component.Payload, &matrix) == nil && len(matrix.Metrics) > 0 {
+				diskMetrics = append(diskMetrics, matrix.Metrics...)
+				diskPaths = max(diskPaths, 1)
+			}
+		case "disktest.deep_multi":
+			var deep struct {
+				Paths []struct {
+					Metrics []DiskMetric `json:"metrics"`
+				} `json:"paths"`
+			}
+			diskRan = true
+			if json.Unmarshal(component.Payload, &deep) == nil {
+				paths := 0
+				for _, path := range deep.Paths {
+					if len(path.Metrics) > 0 {
+						paths++
+						diskMetrics = append(diskMetrics, path.Metrics...)
+					}
+				}
+				diskPaths = max(diskPaths, paths)
+			}
+		case "speed.registry":
+			var speed struct {
+				Benchmarks        []reportSpeedBenchmark `json:"benchmarks"`
+				PrivateBenchmarks []reportSpeedBenchmark `json:"private_benchmarks"`
+			}
+			speedRan = true
+			if json.Unmarshal(component.Payload, &speed) == nil {
+				for _, benchmark := range append(speed.Benchmarks, speed.PrivateBenchmarks...) {
+					peak = max(peak, benchmark.DownloadMbps, benchmark.UploadMbps)
+				}
+			}
+		case "nt3.province_routes":
+			routesRan = true
+			_ = json.Unmarshal(component.Payload, &routes)
+		}
+	}
+	if cpuResult != nil {
+		// The structured CPU test runs one sysbench-style pass on every
+		// thread, so it yields a single-core score only on one thread.
+		single, multi := cpuResult.EventsPerSecond, cpuResult.EventsPerSecond
+		singleOK := cpuResult.EffectiveThreads == 1 && single > 0
+		multiOK := cpuResult.EffectiveThreads > 1 && multi > 0
+		summary.CPU = rankCPU(config, model, single, singleOK, multi, multiOK)
+	}
+	if diskRan {
+		summary.Disk = summarizeDisk(DiskReadMbps(diskMetrics), diskPaths)
+	}
+	if speedRan {
+		summary.Bandwidth = &BandwidthSummary{PeakMbps: peak}
+	}
+	if routesRan {
+		summary.ISPRanking = rankRoutes(routes)
+	}
+	return summary
+}
+
+// RenderSummary renders a summary saved in a report in the language of
+// config. It returns "" for data that is not a Summary.
+func RenderSummary(config *params.Config, data json.RawMessage) string {
+	var summary Summary
+	if json.Unmarshal(data, &summary) != nil || summary.SchemaVersion != SummarySchema {
+		return ""
+	}
+	lang := "zh"
+	if config != nil {
+		lang = config.Language
+	}
+	return summary.Markdown(lang)
+}
+
+type reportCPU struct {
+	EffectiveThreads int     `json:"effective_threads"`
+	EventsPerSecond  float64 `json:"events_per_second"`
+}
+
+type reportSpeedBenchmark struct {
+	DownloadMbps float64 `json:"download_mbps"`
+	UploadMbps   float64 `json:"upload_mbps"`
+}
+
+type reportRoute struct {
+	Target struct {
+		Carrier string `json:"carrier"`
+	} `json:"target"`
+	RouteClass string `json:"route_class"`
+}
+
+// rankCPU looks up the model in the CPU statistics, preferring the
+// single-core score when the statistics have one for the model.
+func rankCPU(config *params.Config, model string, single float64, singleOK bool, multi float64, multiOK bool) *CPUSummary {
+	cpu := &CPUSummary{Model: model}
+	switch {
+	case singleOK:
+		cpu.ScoreType, cpu.Score = "single", single
+	case multiOK:
+		cpu.ScoreType, cpu.Score = "multi", multi
+	default:
+		return cpu
+	}
+	stats, err := loadCPUStats(config)
+	if err != nil {
+		cpu.RankError = err.Error()
+		return cpu
+	}
+	entry := matchCPUStatsEntry(model, stats)
+	if entry == nil || entry.Rank <= 0 {
+		return cpu
+	}
+	cpu.Rank = entry.Rank
+	switch {
+	case singleOK && entry.MaxSingle > 0:
+		cpu.ScoreType, cpu.Score, cpu.MaxScore = "single", single, entry.MaxSingle
+	case multiOK && entry.MaxMulti > 0:
+		cpu.ScoreType, cpu.Score, cpu.MaxScore = "multi", multi, entry.MaxMulti
+	}
+	if cpu.MaxScore > 0 {
+		cpu.Percent = cpu.Score / cpu.MaxScore * 100
+	}
+	return cpu
+}
+
+func summarizeMemory(mbps float64) *MemorySummary {
+	if mbps <= 0 {
+		return &MemorySummary{}
+	}
+	ddr, channels := memoryTier(mbps)
+	return &MemorySummary{BandwidthMBps: mbps, DDR: ddr, Channels: channels, BelowAverage: mbps < memAvgThreshMbps}
+}
+
+func summarizeDisk(readMbps float64, paths int) *DiskSummary {
+	if readMbps <= 0 && paths <= 0 {
+		return &DiskSummary{}
+	}
+	if paths <= 0 {
+		paths = 1
+	}
+	return &DiskSummary{ReadMBps: readMbps, Type: diskTypeCode(readMbps), Paths: paths, BelowAverage: readMbps < diskAvgThreshMbps}
+}
+
+// rankRoutes keeps the best route class seen per carrier. Carriers without a
+// recognised route rank last with quality 0.
+func rankRoutes(routes []reportRoute) *ISPRanking {
+	best := make(map[string]CarrierRank, len(carrierOrder))
+	for _, route := range routes {
+		carrier := strings.ToLower(route.Target.Carrier)
+		quality := RouteQuality(route.RouteClass)
+		if quality > best[carrier].Quality {
+			best[carrier] = CarrierRank{Carrier: carrier, Quality: quality, Route: route.RouteClass}
+		}
+	}
+	scores := make(map[string]int, len(best))
+	for carrier, rank := range best {
+		scores[carrier] = rank.Quality
+	}
+	ranking := rankCarriers(scores)
+	for index := range ranking.Carriers {
+		ranking.Carriers[index].Route = best[ranking.Carriers[index].Carrier].Route
+	}
+	return ranking
+}
+
+// rankCarriers orders the three carriers by score, keeping telecom, unicom,
+// mobile order on ties. It returns an empty ranking when every score is 0.
+func rankCarriers(scores map[string]int) *ISPRanking {
+	ranking := &ISPRanking{}
+	if scores["ct"] == 0 && scores["cu"] == 0 && scores["cm"] == 0 {
+		return ranking
+	}
+	for _, carrier := range carrierOrder {
+		ranking.Carriers = append(ranking.Carriers, CarrierRank{Carrier: carrier, Quality: scores[carrier]})
+	}
+	sort.SliceStable(ranking.Carriers, func(i, j int) bool {
+		return ranking.Carriers[i].Quality > ranking.Carriers[j].Quality
+	})
+	return ranking
+}
+
+// Markdown renders the summary as the two-column table printed after a run.
+func (summary *Summary) Markdown(lang string) string {
+	zh := lang == "zh"
+	pick := func(zhText, enText string) string {
+		if zh {
+			return zhText
+		}
+		return enText
+	}
+	na := pick("无有效数据", "N/A")
+	qualifier := func(below bool) string {
+		if below {
+			return pick(" (未达标)", " (below avg)")
+		}
+		return pick(" (达标)", " (pass)")
+	}
+	var rows []summaryRow
+	if cpu := summary.CPU; cpu != nil {
+		value := na
+		if cpu.Rank > 0 && cpu.MaxScore > 0 {
+			value = pick(fmt.Sprintf("#%d  满血性能 %.2f%%", cpu.Rank, cpu.Percent), fmt.Sprintf("#%d (%.2f%% of max)", cpu.Rank, cpu.Percent))
+		} else if cpu.RankError != "" {
+			value = pick("CPU 榜单不可用", "CPU statistics unavailable")
+		}
+		rows = append(rows, summaryRow{pick("CPU排名", "CPU Rank"), value})
+	}
+	if memory := summary.Memory; memory != nil {
+		value := na
+		if memory.BandwidthMBps > 0 {
+			value = inferMemoryDDRAndChannels(memory.BandwidthMBps, lang) + qualifier(memory.BelowAverage)
+		}
+		rows = append(rows, summaryRow{pick("内存", "Memory"), value})
+	}
+	if disk := summary.Disk; disk != nil {
+		value := na
+		if disk.Paths > 0 {
+			dtype := inferDiskType(disk.ReadMBps, lang)
+			value = pick(fmt.Sprintf("%s %d路%s", dtype, disk.Paths, qualifier(disk.BelowAverage)), fmt.Sprintf("%s %d path(s)%s", dtype, disk.Paths, qualifier(disk.BelowAverage)))
+		}
+		rows = append(rows, summaryRow{pick("硬盘IO", "Disk IO"), value})
+	}
+	if bandwidth := summary.Bandwidth; bandwidth != nil {
+		value := na
+		switch {
+		case bandwidth.PeakMbps >= 1000:
+			value = fmt.Sprintf("> %.2fGbps", bandwidth.PeakMbps/1000)
+		case bandwidth.PeakMbps > 0:
+			value = fmt.Sprintf("> %.2fMbps", bandwidth.PeakMbps)
+		}
+		rows = append(rows, summaryRow{pick("网络峰值带宽", "Peak Bandwidth"), value})
+	}
+	if ranking := summary.ISPRanking; ranking != nil {
+		value := na
+		if len(ranking.Carriers) > 0 {
+			names := make([]string, 0, len(ranking.Carriers))
+			for _, carrier := range ranking.Carriers {
+				names = append(names, carrierName(carrier.Carrier, zh))
+			}
+			value = strings.Join(names, " > ")
+		}
+		rows = append(rows, summaryRow{pick("国内三大运营商推荐排名", "Domestic Carrier Ranking"), value})
+	}
+	if len(rows) == 0 {
+		return pick("无足够数据生成摘要。", "Insufficient data for summary.")
+	}
+	var sb strings.Builder
+	sb.WriteString(pick("| 测试项目 | 结果 |\n", "| Test Item | Result |\n"))
+	sb.WriteString("|:---------|:-------|\n")
+	for _, r := range rows {
+		sb.WriteString("| ")
+		sb.WriteString(r.label)
+		sb.WriteString(" | ")
+		sb.WriteString(r.value)
+		sb.WriteString(" |\n")
+	}
+	return sb.String()
+}
+
+func carrierName(carrier string, zh bool) string {
+	names := map[string][2]string{
+		"ct": {"电信", "China Telecom"},
+		"cu": {"联通", "China Unicom"},
+		"cm": {"移动", "China Mobile"},
+	}
+	name, ok := names[carrier]
+	if !ok {
+		return carrier
+	}
+	if zh {
+		return name[0]
+	}
+	return name[1]
+}
@@ -0,0 +1,215 @@
+package analysis
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/oneclickvirt/ecs/internal/params"
+)
+
+// useCPUStats installs a cached CPU statistics payload so tests never reach
+// the network.
+func useCPUStats(t *testing.T, entries ...cpuStatsEntry) {
+	t.Helper()
+	cpuStatsMu.Lock()
+	previous, previousErr, previousExpiry := cachedCPUStats, cpuStatsErr, cpuStatsExpireAt
+	cachedCPUStats, cpuStatsErr = &cpuStatsPayload{CPUStatistics: entries}, nil
+	cpuStatsExpireAt = time.Now().Add(time.Hour)
+	cpuStatsMu.Unlock()
+	t.Cleanup(func() {
+		cpuStatsMu.Lock()
+		cachedCPUStats, cpuStatsErr, cpuStatsExpireAt = previous, previousErr, previousExpiry
+		cpuStatsMu.Unlock()
+	})
+}
+
+func component(t *testing.T, name string, payload any) Component {
+	t.Helper()
+	data, err := json.Marshal(payload)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return Component{Name: name, Payload: data}
+}
+
+func TestSummarizeComponentsUsesComponentPayloads(t *testing.T) {
+	useCPUStats(t, cpuStatsEntry{CPUPrefix: "AMD EPYC 7763", CPUModel: "AMD EPYC 7763 64-Core Processor", Rank: 12, MaxSingle: 3000, MaxMulti: 8000})
+	route := func(carrier, class string) map[string]any {
+		return map[string]any{"target": map[string]any{"carrier": carrier}, "route_class": class}
+	}
+	components := []Component{
+		component(t, "basics", map[string]any{"cpu": map[string]any{"model": "AMD EPYC 7763 64-Core Processor"}}),
+		component(t, "cputest", map[string]any{"effective_threads": 4, "events_per_second": 6000}),
+		component(t, "memorytest", map[string]any{"sequential_read_mbps": 36000, "sequential_write_mbps": 20000, "copy_mbps": 18000}),
+		component(t, "disktest", map[string]any{"metrics": []map[string]any{
+			{"scenario_id": "4k-q1-read", "direction": "read", "bandwidth_bytes_per_second": 40 << 20},
+			{"scenario_id": "4k-q32-read", "direction": "read", "bandwidth_bytes_per_second": 300 << 20},
+			{"scenario_id": "1m-q8-read", "direction": "read", "bandwidth_bytes_per_second": 2000 << 20},
+			{"scenario_id": "4k-q32-write", "direction": "write", "bandwidth_bytes_per_second": 900 << 20},
+		}}),
+		component(t, "speed.registry", map[string]any{
+			"benchmarks":         []map[string]any{{"download_mbps": 850.5, "upload_mbps": 420}},
+			"private_benchmarks": []map[string]any{{"download_mbps": 1200, "upload_mbps": 1800}},
+		}),
+		component(t, "nt3.province_routes", []map[string]any{
+			route("ct", "chinanet_163"), route("cu", "cu_9929"), route("cm", "cm_cmi"), route("ct", "cn2_gt"),
+		}),
+	}
+	summary := SummarizeComponents(nil, components)
+	if summary.CPU == nil || summary.CPU.ScoreType != "multi" || summary.CPU.Rank != 12 || summary.CPU.Percent != 75 {
+		t.Fatalf("unexpected CPU summary: %#v", summary.CPU)
+	}
+	if summary.Memory == nil || summary.Memory.DDR != "DDR4" || summary.Memory.Channels != "dual" || summary.Memory.BelowAverage {
+		t.Fatalf("unexpected memory summary: %#v", summary.Memory)
+	}
+	if summary.Disk == nil || summary.Disk.ReadMBps != 300 || summary.Disk.Type != "nvme_ssd" || summary.Disk.Paths != 1 {
+		t.Fatalf("unexpected disk summary: %#v", summary.Disk)
+	}
+	if summary.Bandwidth == nil || summary.Bandwidth.PeakMbps != 1800 {
+		t.Fatalf("unexpected bandwidth summary: %#v", summary.Bandwidth)
+	}
+	ranking := summary.ISPRanking
+	if ranking == nil || len(ranking.Carriers) != 3 || ranking.Carriers[0].Carrier != "cu" || ranking.Carriers[1].Carrier != "ct" || ranking.Carriers[1].Route != "cn2_gt" || ranking.Carriers[2].Carrier != "cm" {
+		t.Fatalf("unexpected ISP ranking: %#v", ranking)
+	}
+
+	english := summary.Markdown("en")
+	for _, want := range []string{"| CPU Rank | #12 (75.00% of max) |", "| Memory | DDR4 Dual-Channel (pass) |", "| Disk IO | NVMe SSD 1 path(s) (pass) |", "| Peak Bandwidth | > 1.80Gbps |", "| Domestic Carrier Ranking | China Unicom > China Telecom > China Mobile |"} {
+		if !strings.Contains(english, want) {
+			t.Fatalf("English summary missing %q:\n%s", want, english)
+		}
+	}
+	if chinese := summary.Markdown("zh"); !strings.Contains(chinese, "| 国内三大运营商推荐排名 | 联通 > 电信 > 移动 |") || !strings.Contains(chinese, "| 硬盘IO | NVMe SSD 1路 (达标) |") {
+		t.Fatalf("unexpected Chinese summary:\n%s", chinese)
+	}
+}
+
+func TestLoadCPUStatsReadsOfflineOverlay(t *testing.T) {
+	useCPUStats(t)
+	cpuStatsMu.Lock()
+	cpuStatsExpireAt = time.Time{}
+	cpuStatsMu.Unlock()
+	stats := `[{"cpu_prefix":"EPYC 7763","cpu_model":"AMD EPYC 7763 64-Core Processor","sample_count":3,"max_single_score":0,"max_multi_score":8000,"avg_single_score":0,"avg_multi_score":7000,"rank":12,"typical_cores":64,"typical_threads":128}]` + "\n"
+	dir := t.TempDir()
+	hash := sha256.Sum256([]byte(stats))
+	manifest := `{"schema":"goecs-data/v1","generated_at":"2026-07-19T00:00:00Z","files":{"cpu-stats.json":{"sha256":"` + hex.EncodeToString(hash[:]) + `","count":1}}}`
+	for name, content := range map[string]string{"cpu-stats.json": stats, "manifest.json": manifest} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	config := params.NewConfig("test")
+	config.DataOffline, config.DataOverlayDir = true, dir
+	components := []Component{
+		component(t, "basics", map[string]any{"cpu": map[string]any{"model": "AMD EPYC 7763 64-Core Processor"}}),
+		component(t, "cputest", map[string]any{"effective_threads": 8, "events_per_second": 4000}),
+	}
+	if cpu := SummarizeComponents(config, components).CPU; cpu == nil || cpu.Rank != 12 || cpu.Percent != 50 {
+		t.Fatalf("unexpected CPU summary: %#v", cpu)
+	}
+}
+
+func TestSummarizeComponentsReportsUnavailableCPUStats(t *testing.T) {
+	useCPUStats(t)
+	cpuStatsMu.Lock()
+	cpuStatsExpireAt = time.Time{}
+	cpuStatsMu.Unlock()
+	config := params.NewConfig("test")
+	config.DataOffline = true
+	components := []Component{
+		component(t, "cputest", map[string]any{"effective_threads": 1, "events_per_second": 1000}),
+	}
+	summary := SummarizeComponents(config, components)
+	if summary.CPU == nil || summary.CPU.Rank != 0 || summary.CPU.RankError == "" {
+		t.Fatalf("unexpected CPU summary: %#v", summary.CPU)
+	}
+	if text := summary.Markdown("en"); !strings.Contains(text, "| CPU Rank | CPU statistics unavailable |") {
+		t.Fatalf("unexpected summary text:\n%s", text)
+	}
+}
+
+func TestSummarizeComponentsMarksRunTestsWithoutDataAsUnavailable(t *testing.T) {
+	useCPUStats(t)
+	components := []Component{
+		{Name: "cputest"},
+		{Name: "disktest"},
+		component(t, "disktest.deep_multi", map[string]any{"paths": []map[string]any{{"metrics": []map[string]any{{"scenario_id": "4k-q1-read", "direction": "read", "bandwidth_bytes_per_second": 5 << 20}}}, {"status": "error"}}}),
+	}
+	summary := SummarizeComponents(nil, components)
+	if summary.CPU == nil || summary.CPU.Rank != 0 || summary.Memory != nil || summary.Bandwidth != nil || summary.ISPRanking != nil {
+		t.Fatalf("unexpected summary: %#v", summary)
+	}
+	if summary.Disk == nil || summary.Disk.Paths != 1 || summary.Disk.Type != "low_performance" || !summary.Disk.BelowAverage {
+		t.Fatalf("unexpected disk summary: %#v", summary.Disk)
+	}
+	text := summary.Markdown("en")
+	if !strings.Contains(text, "| CPU Rank | N/A |") || !strings.Contains(text, "| Disk IO | Low-Perf Disk 1 path(s) (below avg) |") {
+		t.Fatalf("unexpected summary text:\n%s", text)
+	}
+	if empty := SummarizeComponents(nil, nil).Markdown("zh"); empty != "无足够数据生成摘要。" {
+		t.Fatalf("unexpected empty summary: %q", empty)
+	}
+}
+
+func TestGenerateSummaryFromLegacyText(t *testing.T) {
+	useCPUStats(t)
+	config := params.NewConfig("test")
+	config.Language = "zh"
+	config.CpuTestStatus, config.MemoryTestStatus, config.DiskTestStatus = false, true, true
+	config.SpeedTestStatus, config.BacktraceStatus = false, true
+	output := strings.Join([]string{
+		"---------------------内存测试---------------------",
+		"Copy:   40960.5 MB/s",
+		"---------------------硬盘测试---------------------",
+		"/root   4k   120.50 MB/s(30.1k)   80.00 MB/s(20.0k)",
+		"-------------------上游及回程线路检测-------------------",
+		"北京电信 [精品线路]",
+		"北京联通 [普通线路]",
+		"北京移动 [优质线路]",
+	}, "\n")
+	want := "| 测试项目 | 结果 |\n|:---------|:-------|\n" +
+		"| 内存 | DDR4 双通道 (达标) |\n" +
+		"| 硬盘IO | SATA SSD 1路 (达标) |\n" +
+		"| 国内三大运营商推荐排名 | 电信 > 移动 > 联通 |\n"
+	if got := GenerateSummary(config, output); got != want {
+		t.Fatalf("unexpected summary:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestRenderSummaryRendersSavedSummaryInConfigLanguage(t *testing.T) {
+	data, err := json.Marshal(&Summary{SchemaVersion: SummarySchema, Bandwidth: &BandwidthSummary{PeakMbps: 1800}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	config := params.NewConfig("test")
+	config.Language = "en"
+	if text := RenderSummary(config, data); !strings.Contains(text, "| Peak Bandwidth | > 1.80Gbps |") {
+		t.Fatalf("unexpected rendered summary:\n%s", text)
+	}
+	if text := RenderSummary(config, json.RawMessage(`{"schema_version":"other/v1"}`)); text != "" {
+		t.Fatalf("a summary of another schema was rendered: %q", text)
+	}
+}
+
+func TestDerivedReportMetrics(t *testing.T) {
+	if got := MemoryBandwidthMbps(json.RawMessage(`{"sequential_read_mbps":100,"sequential_write_mbps":300,"copy_mbps":200}`)); got != 300 {
+		t.Fatalf("memory bandwidth = %v", got)
+	}
+	metrics := []DiskMetric{
+		{ScenarioID: "1m-q8-read", Direction: "read", BandwidthBytesPerSecond: 500 << 20},
+		{ScenarioID: "4k-q1-read", Direction: "read", BandwidthBytesPerSecond: 20 << 20},
+		{ScenarioID: "4k-q1-write", Direction: "write", BandwidthBytesPerSecond: 90 << 20},
+	}
+	if got := DiskReadMbps(metrics); got != 20 {
+		t.Fatalf("4K read bandwidth = %v", got)
+	}
+	if got := DiskReadMbps(metrics[:1]); got != 500 {
+		t.Fatalf("fallback read bandwidth = %v", got)
+	}
+}
@@ -34,21 +34,14 @@ var (
 )
 
 const (
-	cpuStatsPrimaryURL    = "https://raw.githubusercontent.com/oneclickvirt/ecs/ranks/cpu_statistics.json"
-	cpuStatsFallbackURL   = "https://github.com/oneclickvirt/ecs/raw/refs/heads/ranks/cpu_statistics.json"
-	cpuCDNProbeTestURL    = "https://raw.githubusercontent.com/spiritLHLS/ecs/main/back/test"
-	cpuStatsCacheTTL      = 30 * time.Minute
-	cpuStatsFailCacheTTL  = 5 * time.Minute
-	cpuStatsRequestTimout = 6 * time.Second
+	// cpuStatsFile is the data snapshot file data-sync builds from the
+	// ranks branch of this repository.
+	cpuStatsFile         = "cpu-stats.json"
+	cpuStatsCacheTTL     = 30 * time.Minute
+	cpuStatsFailCacheTTL = 5 * time.Minute
+	cpuStatsLoadTimeout  = 20 * time.Second
 )
 
-var cpuStatsCDNList = []string{
-	"https://cdn.spiritlhl.net/",
-	"http://cdn3.spiritlhl.net/",
-	"http://cdn1.spiritlhl.net/",
-	"http://cdn2.spiritlhl.net/",
-}
-
 type cpuStatsEntry struct {
 	CPUPrefix     string  `json:"cpu_prefix"`
 	CPUModel      string  `json:"cpu_model"`
@@ -63,12 +56,13 @@ type cpuStatsEntry struct {
 }
 
 type cpuStatsPayload struct {
-	CPUStatistics []cpuStatsEntry `json:"cpu_statistics"`
+	CPUStatistics []cpuStatsEntry
 }
 
 var (
 	cpuStatsMu       sync.Mutex
 	cachedCPUStats   *cpuStatsPayload
+	cpuStatsErr      error
 	cpuStatsExpireAt time.Time
 )
 
@@ -269,38 +263,46 @@ func extractMaxMemoryBandwidth(output string) float64 {
 	return maxMbps
 }
 
-// inferMemoryDDRAndChannels converts a memory bandwidth (MB/s) to a human-readable
-// DDR type + channel string using the thresholds from README_NEW_USER.
+// memoryTier converts a memory bandwidth (MB/s) to a DDR type and a channel
+// count ("single" or "dual") using the thresholds from README_NEW_USER.
 //
 //	DDR3 single: 10240–17408 MB/s
 //	DDR4 single: 17408–34816 MB/s
 //	DDR4 dual:   34816–51200 MB/s
 //	DDR5 single: 51200–77824 MB/s
 //	DDR5 dual:   ≥77824 MB/s
-func inferMemoryDDRAndChannels(mbps float64, lang string) string {
-	type tier struct {
-		minMbps         float64
-		ddr, chZh, chEn string
-	}
-	tiers := []tier{
-		{77824, "DDR5", "双通道", "Dual-Channel"},
-		{51200, "DDR5", "单通道", "Single-Channel"},
-		{34816, "DDR4", "双通道", "Dual-Channel"},
-		{17408, "DDR4", "单通道", "Single-Channel"},
-		{0, "DDR3", "单通道", "Single-Channel"},
+func memoryTier(mbps float64) (ddr, channels string) {
+	tiers := []struct {
+		minMbps       float64
+		ddr, channels string
+	}{
+		{77824, "DDR5", "dual"},
+		{51200, "DDR5", "single"},
+		{34816, "DDR4", "dual"},
+		{17408, "DDR4", "single"},
 	}
 	for _, t := range tiers {
 		if mbps >= t.minMbps {
-			if lang == "zh" {
-				return t.ddr + " " + t.chZh
-			}
-			return t.ddr + " " + t.chEn
+			return t.ddr, t.channels
 		}
 	}
+	return "DDR3", "single"
+}
+
+// inferMemoryDDRAndChannels renders memoryTier as a human-readable DDR type +
+// channel string.
+func inferMemoryDDRAndChannels(mbps float64, lang string) string {
+	ddr, channels := memoryTier(mbps)
+	if channels == "dual" {
+		if lang == "zh" {
+			return ddr + " 双通道"
+		}
+		return ddr + " Dual-Channel"
+	}
 	if lang == "zh" {
-		return "DDR3 单通道"
+		return ddr + " 单通道"
 	}
-	return "DDR3 Single-Channel"
+	return ddr + " Single-Channel"
 }
 
 // extractDiskTypeAndCount scans all disk-test section(s) for fio 4K rows and
@@ -359,21 +361,32 @@ func extractDiskTypeAndCount(output string) (readMbps float64, pathCount int) {
 	return readMbps, pathCount
 }
 
-// inferDiskType classifies a disk by its 4K (or sequential fallback) read speed.
+// diskTypeCode classifies a disk by its 4K (or sequential fallback) read speed.
 //
 //	NVMe SSD : ≥200 MB/s
 //	SATA SSD : 50–200 MB/s
 //	HDD      : 10–50 MB/s
-func inferDiskType(readMbps float64, lang string) string {
+func diskTypeCode(readMbps float64) string {
 	switch {
 	case readMbps >= 200:
-		return "NVMe SSD"
+		return "nvme_ssd"
 	case readMbps >= 50:
-		if lang == "zh" {
-			return "SATA SSD"
-		}
-		return "SATA SSD"
+		return "sata_ssd"
 	case readMbps >= 10:
+		return "hdd"
+	default:
+		return "low_performance"
+	}
+}
+
+// inferDiskType renders diskTypeCode for the summary table.
+func inferDiskType(readMbps float64, lang string) string {
+	switch diskTypeCode(readMbps) {
+	case "nvme_ssd":
+		return "NVMe SSD"
+	case "sata_ssd":
+		return "SATA SSD"
+	case "hdd":
 		return "HDD"
 	default:
 		if lang == "zh" {
@@ -383,15 +396,16 @@ func inferDiskType(readMbps float64, lang string) string {
 	}
 }
 
-// extractISPRanking parses the backtrace section and returns a ranking string
-// like "电信 > 联通 > 移动" based on the best route quality detected per ISP.
+// extractISPRanking parses the backtrace section and ranks the carriers by
+// the best route quality detected for each.
 // Quality tiers: [精品线路]=3, [优质线路]=2, [普通线路]=1.
-func extractISPRanking(output, lang string) string {
+func extractISPRanking(output string) *ISPRanking {
 	content := extractAllSectionContent(output, "上游及回程线路检测", "Upstream")
 	if content == "" {
-		return ""
+		return &ISPRanking{}
 	}
-	scores := map[string]int{"电信": 0, "联通": 0, "移动": 0}
+	carriers := map[string]string{"电信": "ct", "联通": "cu", "移动": "cm"}
+	scores := make(map[string]int, len(carriers))
 	for _, raw := range strings.Split(content, "\n") {
 		line := stripAnsiCodes(raw)
 		var q int
@@ -405,36 +419,24 @@ func extractISPRanking(output, lang string) string {
 		default:
 			continue
 		}
-		for isp := range scores {
-			if strings.Contains(line, isp) && q > scores[isp] {
-				scores[isp] = q
+		for isp, carrier := range carriers {
+			if strings.Contains(line, isp) && q > scores[carrier] {
+				scores[carrier] = q
 			}
 		}
 	}
-	if scores["电信"] == 0 && scores["联通"] == 0 && scores["移动"] == 0 {
-		return ""
-	}
-	isps := []string{"电信", "联通", "移动"}
-	order := map[string]int{"电信": 0, "联通": 1, "移动": 2}
-	sort.Slice(isps, func(i, j int) bool {
-		si, sj := scores[isps[i]], scores[isps[j]]
-		if si != sj {
-			return si > sj
-		}
-		return order[isps[i]] < order[isps[j]]
-	})
-	return strings.Join(isps, " > ")
+	return rankCarriers(scores)
 }
 
 // extractCPURankCondensed returns "CPU排名 #N 为满血性能的XX.XX%" (zh) or the
 // English equivalent, using the same CPU stats lookup as summarizeCPUWithRanking.
-func extractCPURankCondensed(finalOutput, lang string) string {
+func extractCPURankCondensed(config *params.Config, finalOutput, lang string) string {
 	model := extractCPUModel(finalOutput)
 	single, singleOK, multi, multiOK := extractCPUScores(finalOutput)
 	if !singleOK && !multiOK {
 		return ""
 	}
-	stats := loadCPUStats()
+	stats, _ := loadCPUStats(config)
 	entry := matchCPUStatsEntry(model, stats)
 	if entry == nil || entry.Rank <= 0 {
 		return ""
@@ -491,184 +493,64 @@ func sectionExists(output, markerZh, markerEn string) bool {
 // summaryRow holds a localised label/value pair for the markdown summary table.
 type summaryRow struct{ label, value string }
 
-// GenerateSummary creates a Markdown table post-test summary from final output.
+// GenerateSummary creates a Markdown table post-test summary from final output,
+// for the streaming text workflow. Runs rendered from component payloads are
+// summarized with SummarizeComponents instead.
 func GenerateSummary(config *params.Config, finalOutput string) string {
-	lang := config.Language
-	rows := make([]summaryRow, 0, 5)
+	return SummarizeText(config, finalOutput).Markdown(config.Language)
+}
 
-	// helper: localised N/A string
-	na := func() string {
-		if lang == "zh" {
-			return "无有效数据"
-		}
-		return "N/A"
-	}
+// SummarizeText fills a Summary from the rendered legacy text. A section is
+// reported as N/A when its header is present but no value could be parsed,
+// and omitted when the test did not run at all.
+func SummarizeText(config *params.Config, finalOutput string) *Summary {
+	summary := &Summary{SchemaVersion: SummarySchema}
 
 	// 1. CPU rank and full-blood percentage
 	if config.CpuTestStatus {
-		model := extractCPUModel(finalOutput)
 		single, singleOK, multi, multiOK := extractCPUScores(finalOutput)
+		cpuSection := sectionExists(finalOutput, "CPU测试", "CPU-Test")
 		if singleOK || multiOK {
-			stats := loadCPUStats()
-			entry := matchCPUStatsEntry(model, stats)
-			if entry != nil && entry.Rank > 0 {
-				var score, maxScore float64
-				if singleOK && entry.MaxSingle > 0 {
-					score, maxScore = single, entry.MaxSingle
-				} else if multiOK && entry.MaxMulti > 0 {
-					score, maxScore = multi, entry.MaxMulti
-				}
-				if maxScore > 0 {
-					pct := score / maxScore * 100
-					if lang == "zh" {
-						rows = append(rows, summaryRow{"CPU排名", fmt.Sprintf("#%d  满血性能 %.2f%%", entry.Rank, pct)})
-					} else {
-						rows = append(rows, summaryRow{"CPU Rank", fmt.Sprintf("#%d (%.2f%% of max)", entry.Rank, pct)})
-					}
-				} else if lang == "zh" {
-					rows = append(rows, summaryRow{"CPU排名", na()})
-				} else {
-					rows = append(rows, summaryRow{"CPU Rank", na()})
-				}
-			} else if sectionExists(finalOutput, "CPU测试", "CPU-Test") {
-				if lang == "zh" {
-					rows = append(rows, summaryRow{"CPU排名", na()})
-				} else {
-					rows = append(rows, summaryRow{"CPU Rank", na()})
-				}
-			}
-		} else if sectionExists(finalOutput, "CPU测试", "CPU-Test") {
-			if lang == "zh" {
-				rows = append(rows, summaryRow{"CPU排名", na()})
-			} else {
-				rows = append(rows, summaryRow{"CPU Rank", na()})
+			cpu := rankCPU(config, extractCPUModel(finalOutput), single, singleOK, multi, multiOK)
+			if cpu.Rank > 0 || cpuSection {
+				summary.CPU = cpu
 			}
+		} else if cpuSection {
+			summary.CPU = &CPUSummary{}
 		}
-		// If section not present at all, the test simply wasn't run – omit silently.
 	}
 
 	// 2. Memory DDR type and channels, with average-level check
 	if config.MemoryTestStatus {
-		bw := extractMaxMemoryBandwidth(finalOutput)
-		if bw > 0 {
-			mem := inferMemoryDDRAndChannels(bw, lang)
-			// README_NEW_USER threshold: < 10240 MB/s (≈10 GB/s) indicates overselling risk
-			const memAvgThreshMbps = 10240.0
-			if lang == "zh" {
-				if bw >= memAvgThreshMbps {
-					rows = append(rows, summaryRow{"内存", mem + " (达标)"})
-				} else {
-					rows = append(rows, summaryRow{"内存", mem + " (未达标)"})
-				}
-			} else {
-				if bw >= memAvgThreshMbps {
-					rows = append(rows, summaryRow{"Memory", mem + " (pass)"})
-				} else {
-					rows = append(rows, summaryRow{"Memory", mem + " (below avg)"})
-				}
-			}
-		} else if sectionExists(finalOutput, "内存测试", "Memory-Test") {
-			if lang == "zh" {
-				rows = append(rows, summaryRow{"内存", na()})
-			} else {
-				rows = append(rows, summaryRow{"Memory", na()})
-			}
+		if bw := extractMaxMemoryBandwidth(finalOutput); bw > 0 || sectionExists(finalOutput, "内存测试", "Memory-Test") {
+			summary.Memory = summarizeMemory(bw)
 		}
 	}
 
 	// 3. Disk type and path count, with average-level check
 	if config.DiskTestStatus {
 		readMbps, pathCount := extractDiskTypeAndCount(finalOutput)
-		if readMbps > 0 || pathCount > 0 {
-			if pathCount <= 0 {
-				pathCount = 1
-			}
-			dtype := inferDiskType(readMbps, lang)
-			// README_NEW_USER: < 10 MB/s = poor performance / severe overselling
-			diskOK := readMbps >= 10
-			var qual string
-			if lang == "zh" {
-				if diskOK {
-					qual = " (达标)"
-				} else {
-					qual = " (未达标)"
-				}
-				rows = append(rows, summaryRow{"硬盘IO", fmt.Sprintf("%s %d路%s", dtype, pathCount, qual)})
-			} else {
-				if diskOK {
-					qual = " (pass)"
-				} else {
-					qual = " (below avg)"
-				}
-				rows = append(rows, summaryRow{"Disk IO", fmt.Sprintf("%s %d path(s)%s", dtype, pathCount, qual)})
-			}
-		} else if sectionExists(finalOutput, "硬盘测试", "Disk-Test") {
-			if lang == "zh" {
-				rows = append(rows, summaryRow{"硬盘IO", na()})
-			} else {
-				rows = append(rows, summaryRow{"Disk IO", na()})
-			}
+		if readMbps > 0 || pathCount > 0 || sectionExists(finalOutput, "硬盘测试", "Disk-Test") {
+			summary.Disk = summarizeDisk(readMbps, pathCount)
 		}
 	}
 
 	// 4. Network peak bandwidth
 	if config.SpeedTestStatus {
 		bwVals := parseFloatsByRegex(finalOutput, mbpsRe)
-		var bwVal string
-		if len(bwVals) > 0 {
-			sort.Float64s(bwVals)
-			maxV := bwVals[len(bwVals)-1]
-			if maxV >= 1000 {
-				bwVal = fmt.Sprintf("> %.2fGbps", maxV/1000)
-			} else {
-				bwVal = fmt.Sprintf("> %.2fMbps", maxV)
-			}
-		}
-		if bwVal != "" {
-			if lang == "zh" {
-				rows = append(rows, summaryRow{"网络峰值带宽", bwVal})
-			} else {
-				rows = append(rows, summaryRow{"Peak Bandwidth", bwVal})
-			}
-		} else if sectionExists(finalOutput, "测速", "Speed-Test") {
-			if lang == "zh" {
-				rows = append(rows, summaryRow{"网络峰值带宽", na()})
-			} else {
-				rows = append(rows, summaryRow{"Peak Bandwidth", na()})
+		if len(bwVals) > 0 || sectionExists(finalOutput, "测速", "Speed-Test") {
+			summary.Bandwidth = &BandwidthSummary{}
+			for _, v := range bwVals {
+				summary.Bandwidth.PeakMbps = max(summary.Bandwidth.PeakMbps, v)
 			}
 		}
 	}
 
 	// 5. Domestic ISP ranking — only meaningful in Chinese mode (backtrace targets CN ISPs)
-	if lang == "zh" && config.BacktraceStatus {
-		if ranking := extractISPRanking(finalOutput, lang); ranking != "" {
-			rows = append(rows, summaryRow{"国内三大运营商推荐排名", ranking})
-		} else if sectionExists(finalOutput, "上游及回程线路检测", "") {
-			rows = append(rows, summaryRow{"国内三大运营商推荐排名", na()})
-		}
-	}
-
-	if len(rows) == 0 {
-		if lang == "zh" {
-			return "无足够数据生成摘要。"
+	if config.Language == "zh" && config.BacktraceStatus {
+		if ranking := extractISPRanking(finalOutput); len(ranking.Carriers) > 0 || sectionExists(finalOutput, "上游及回程线路检测", "") {
+			summary.ISPRanking = ranking
 		}
-		return "Insufficient data for summary."
 	}
-
-	// Render as Markdown table
-	var sb strings.Builder
-	if lang == "zh" {
-		sb.WriteString("| 测试项目 | 结果 |\n")
-	} else {
-		sb.WriteString("| Test Item | Result |\n")
-	}
-	sb.WriteString("|:---------|:-------|\n")
-	for _, r := range rows {
-		sb.WriteString("| ")
-		sb.WriteString(r.label)
-		sb.WriteString(" | ")
-		sb.WriteString(r.value)
-		sb.WriteString(" |\n")
-	}
-	return sb.String()
+	return summary
 }
@@ -0,0 +1,165 @@
+package data
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// DefaultCacheMaxAge is how long a cached response is reused before it is
+// revalidated with the server.
+const DefaultCacheMaxAge = time.Hour
+
+// DefaultCacheDir returns the per-user snapshot cache, or "" when the
+// platform has no user cache directory.
+func DefaultCacheDir() string {
+	base, err := os.UserCacheDir()
+	if err != nil || base == "" {
+		return ""
+	}
+	return filepath.Join(base, "goecs", "data")
+}
+
+// cacheMeta is stored beside each cached body. SHA256 guards against a body
+// and meta pair left mismatched by an interrupted write.
+type cacheMeta struct {
+	URL          string    `json:"url"`
+	ETag         string    `json:"etag,omitempty"`
+	LastModified string    `json:"last_modified,omitempty"`
+	FetchedAt    time.Time `json:"fetched_at"`
+	SHA256       string    `json:"sha256"`
+}
+
+type cacheEntry struct {
+	meta cacheMeta
+	data []byte
+}
+
+// fetchedFile is one response. cached reports that data came from CacheDir;
+// entry, when set, is written to the cache once the whole set verifies.
+type fetchedFile struct {
+	data   []byte
+	cached bool
+	entry  *cacheEntry
+}
+
+// get fetches url through the cache. An entry younger than CacheMaxAge is
+// returned without a request and an older one is revalidated, so a zero
+// CacheMaxAge revalidates every time. With cacheOnly no request is made.
+func (l *Loader) get(ctx context.Context, url string, cacheOnly bool) (fetchedFile, error) {
+	if l.CacheDir == "" {
+		if cacheOnly {
+			return fetchedFile{}, errors.New("data cache is disabled")
+		}
+		data, err := l.fetch(ctx, url)
+		return fetchedFile{data: data}, err
+	}
+	cached, cachedErr := l.readCache(url)
+	if cacheOnly {
+		if cachedErr != nil {
+			return fetchedFile{}, cachedErr
+		}
+		return fetchedFile{data: cached.data, cached: true}, nil
+	}
+	if cachedErr == nil {
+		if age := time.Since(cached.meta.FetchedAt); age >= 0 && age < l.CacheMaxAge {
+			return fetchedFile{data: cached.data, cached: true}, nil
+		}
+	} else {
+		cached = nil
+	}
+	response, err := l.fetchConditional(ctx, url, cached)
+	if err != nil {
+		return fetchedFile{}, err
+	}
+	now := time.Now().UTC()
+	if response.notModified {
+		refreshed := *cached
+		refreshed.meta.FetchedAt = now
+		return fetchedFile{data: cached.data, cached: true, entry: &refreshed}, nil
+	}
+	hash := sha256.Sum256(response.data)
+	return fetchedFile{data: response.data, entry: &cacheEntry{
+		meta: cacheMeta{
+			URL: url, ETag: response.etag, LastModified: response.lastModified,
+			FetchedAt: now, SHA256: hex.EncodeToString(hash[:]),
+		},
+		data: response.data,
+	}}, nil
+}
+
+func (l *Loader) cachePaths(url string) (string, string) {
+	hash := sha256.Sum256([]byte(url))
+	key := hex.EncodeToString(hash[:16])
+	return filepath.Join(l.CacheDir, key+".data"), filepath.Join(l.CacheDir, key+".meta.json")
+}
+
+func (l *Loader) readCache(url string) (*cacheEntry, error) {
+	dataPath, metaPath := l.cachePaths(url)
+	metaData, err := os.ReadFile(metaPath)
+	if err != nil {
+		return nil, err
+	}
+	var meta cacheMeta
+	if err := json.Unmarshal(metaData, &meta); err != nil {
+		return nil, err
+	}
+	if meta.URL != url {
+		return nil, errors.New("cache entry belongs to another URL")
+	}
+	data, err := os.ReadFile(dataPath)
+	if err != nil {
+		return nil, err
+	}
+	if verify(data, meta.SHA256) != nil {
+		return nil, errors.New("cache entry is corrupt")
+	}
+	return &cacheEntry{meta: meta, data: data}, nil
+}
+
+// storeCache is best-effort: a read-only or full disk only costs the next
+// run a download.
+func (l *Loader) storeCache(entries []*cacheEntry) {
+	if l.CacheDir == "" {
+		return
+	}
+	if err := os.MkdirAll(l.CacheDir, 0o700); err != nil {
+		return
+	}
+	for _, entry := range entries {
+		if entry == nil {
+			continue
+		}
+		metaData, err := json.Marshal(entry.meta)
+		if err != nil {
+			continue
+		}
+		dataPath, metaPath := l.cachePaths(entry.meta.URL)
+		if writeCacheFile(dataPath, entry.data) != nil {
+			continue
+		}
+		_ = writeCacheFile(metaPath, metaData)
+	}
+}
+
+func writeCacheFile(path string, data []byte) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".cache-*")
+	if err != nil {
+		return err
+	}
+	tmpName := tmp.Name()
+	defer os.Remove(tmpName)
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmpName, path)
+}
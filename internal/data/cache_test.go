@@ -0,0 +1,109 @@
+package data
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+)
+
+func TestLoaderCacheRevalidatesAndServesOffline(t *testing.T) {
+	payload := []byte("[{\"id\":\"cached\",\"name\":\"Cached\",\"host\":\"cached.example\",\"port\":443}]\n")
+	hash := sha256.Sum256(payload)
+	manifest := fmt.Sprintf(`{"schema":"goecs-data/v1","generated_at":"2026-07-19T00:00:00Z","files":{"tcp-targets.json":{"sha256":"%s","count":1}}}`, hex.EncodeToString(hash[:]))
+	var requests, notModified atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		etag := `"` + r.URL.Path + `"`
+		if r.Header.Get("If-None-Match") == etag {
+			notModified.Add(1)
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		w.Header().Set("ETag", etag)
+		switch r.URL.Path {
+		case "/manifest.json":
+			_, _ = w.Write([]byte(manifest))
+		case "/tcp-targets.json":
+			_, _ = w.Write(payload)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	newLoader := func() *Loader {
+		loader := NewLoader(server.Client(), server.URL)
+		loader.RawBase = ""
+		loader.CacheDir = t.TempDir()
+		return loader
+	}
+	loader := newLoader()
+	result, err := loader.Load(context.Background(), "tcp-targets.json")
+	if err != nil || result.Source != "cdn" || requests.Load() != 2 {
+		t.Fatalf("first load = %#v err=%v requests=%d", result, err, requests.Load())
+	}
+
+	// Within the max-age nothing is requested.
+	result, err = loader.Load(context.Background(), "tcp-targets.json")
+	if err != nil || result.Source != "cache" || result.Fallback != "" || !result.UsedRemote || requests.Load() != 2 {
+		t.Fatalf("fresh cache load = %#v err=%v requests=%d", result, err, requests.Load())
+	}
+
+	// Past the max-age each file is revalidated with its ETag.
+	loader.CacheMaxAge = 0
+	result, err = loader.Load(context.Background(), "tcp-targets.json")
+	if err != nil || result.Source != "cache" || notModified.Load() != 2 {
+		t.Fatalf("revalidated load = %#v err=%v 304s=%d", result, err, notModified.Load())
+	}
+
+	// With the network down the verified set is used before the embedded one.
+	server.Close()
+	result, err = loader.Load(context.Background(), "tcp-targets.json")
+	if err != nil || result.Source != "cache" || result.Fallback != "cache" || result.UsedRemote || string(result.Data) != string(payload) {
+		t.Fatalf("offline load = %#v err=%v", result, err)
+	}
+
+	// An empty cache still ends at the embedded snapshot.
+	result, err = newLoader().Load(context.Background(), "tcp-targets.json")
+	if err != nil || result.Source != "embedded" {
+		t.Fatalf("offline load without cache = %#v err=%v", result, err)
+	}
+}
+
+func TestLoaderCacheKeepsLastVerifiedSet(t *testing.T) {
+	good := []byte("[{\"id\":\"good\",\"name\":\"Good\",\"host\":\"good.example\",\"port\":443}]\n")
+	hash := sha256.Sum256(good)
+	manifest := fmt.Sprintf(`{"schema":"goecs-data/v1","generated_at":"2026-07-19T00:00:00Z","files":{"tcp-targets.json":{"sha256":"%s","count":1}}}`, hex.EncodeToString(hash[:]))
+	var corrupt atomic.Bool
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/manifest.json":
+			_, _ = w.Write([]byte(manifest))
+		case "/tcp-targets.json":
+			if corrupt.Load() {
+				_, _ = w.Write([]byte("[]\n"))
+				return
+			}
+			_, _ = w.Write(good)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+	loader := NewLoader(server.Client(), server.URL)
+	loader.RawBase = ""
+	loader.CacheDir = t.TempDir()
+	loader.CacheMaxAge = 0
+	if _, err := loader.Load(context.Background(), "tcp-targets.json"); err != nil {
+		t.Fatal(err)
+	}
+	// A mirror serving a bad payload must not overwrite the cached good one.
+	corrupt.Store(true)
+	result, err := loader.Load(context.Background(), "tcp-targets.json")
+	if err != nil || result.Fallback != "cache" || string(result.Data) != string(good) {
+		t.Fatalf("load with corrupt mirror = %#v err=%v", result, err)
+	}
+}
@@ -2,6 +2,7 @@ package data
 
 import (
 	"context"
+	"crypto/ed25519"
 	"crypto/sha256"
 	"embed"
 	"encoding/hex"
@@ -15,6 +16,7 @@ import (
 	"path/filepath"
 	"sort"
 	"strings"
+	"sync"
 	"time"
 )
 
@@ -39,6 +41,7 @@ const (
 // unsupported payload into a report or runtime path.
 var knownFiles = []string{
 	"bgp-asn-map.json",
+	"cpu-stats.json",
 	"dnsbl-zones.json",
 	"media-providers.json",
 	"openspeedtest-servers.json",
@@ -52,6 +55,33 @@ func KnownFiles() []string {
 	return append([]string(nil), knownFiles...)
 }
 
+// SnapshotFiles returns the known files the embedded manifest lists. A known
+// file that no committed snapshot carries yet is loaded only on request, so
+// its absence cannot fail the rest of the snapshot; it joins the set as soon
+// as a data-sync run commits it.
+func SnapshotFiles() []string {
+	listed := embeddedManifestFiles()
+	files := make([]string, 0, len(knownFiles))
+	for _, name := range knownFiles {
+		if _, ok := listed[name]; ok {
+			files = append(files, name)
+		}
+	}
+	return files
+}
+
+var embeddedManifestFiles = sync.OnceValue(func() map[string]FileMeta {
+	data, err := embedded.ReadFile(manifestPath)
+	if err != nil {
+		return nil
+	}
+	var manifest Manifest
+	if json.Unmarshal(data, &manifest) != nil {
+		return nil
+	}
+	return manifest.Files
+})
+
 type FileMeta struct {
 	SHA256 string `json:"sha256"`
 	Count  int    `json:"count"`
@@ -64,10 +94,23 @@ type Manifest struct {
 	Files       map[string]FileMeta `json:"files"`
 }
 
+// Loader fetches snapshot files. When PublicKey is set, a remote manifest is
+// trusted only after its manifest.json.sig verifies; otherwise the embedded
+// snapshot is used. RequireSignature without a PublicKey skips remote and
+// cached manifests altogether. When CacheDir is set, responses younger than
+// CacheMaxAge are reused without a request, older ones are revalidated with
+// ETag and If-Modified-Since, and the cached set is used when the network is
+// down. OverlayDir is a local directory whose own manifest.json covers any
+// subset of the known files; see loadOverlay.
 type Loader struct {
-	Client  *http.Client
-	CDNBase string
-	RawBase string
+	Client           *http.Client
+	CDNBase          string
+	RawBase          string
+	PublicKey        ed25519.PublicKey
+	RequireSignature bool
+	CacheDir         string
+	CacheMaxAge      time.Duration
+	OverlayDir       string
 }
 
 type Result struct {
@@ -77,13 +120,14 @@ type Result struct {
 	UsedRemote bool
 	Fallback   string
 	Source     string
+	Signature  string
 }
 
 func NewLoader(client *http.Client, cdnBase string) *Loader {
 	if client == nil {
 		client = &http.Client{Timeout: 12 * time.Second}
 	}
-	return &Loader{Client: client, CDNBase: strings.TrimRight(cdnBase, "/"), RawBase: strings.TrimRight(dataBaseURL, "/")}
+	return &Loader{Client: client, CDNBase: strings.TrimRight(cdnBase, "/"), RawBase: strings.TrimRight(dataBaseURL, "/"), PublicKey: embeddedPublicKey(), RequireSignature: requireManifestSignature == "true", CacheMaxAge: DefaultCacheMaxAge}
 }
 
 func (l *Loader) Load(ctx context.Context, name string) (Result, error) {
@@ -100,19 +144,81 @@ func (l *Loader) Load(ctx context.Context, name string) (Result, error) {
 
 // LoadMany loads an internally consistent set of files. All returned results
 // are verified against the same manifest; if any requested payload fails, the
-// complete candidate is rejected before trying the next manifest source.
+// complete candidate is rejected before trying the next manifest source. When
+// every remote source fails, the last verified set in CacheDir is tried before
+// the embedded snapshot. Files listed in the OverlayDir manifest replace the
+// whole chain for those names.
 func (l *Loader) LoadMany(ctx context.Context, names []string) (map[string]Result, error) {
 	names, err := normalizedDataNames(names)
 	if err != nil {
 		return nil, err
 	}
+	results, remaining, err := l.loadOverlay(names)
+	if err != nil {
+		return nil, err
+	}
+	if len(remaining) == 0 {
+		return results, nil
+	}
+	chained, err := l.loadChain(ctx, remaining)
+	if err != nil {
+		return nil, err
+	}
+	if results == nil {
+		return chained, nil
+	}
+	for name, result := range chained {
+		results[name] = result
+	}
+	return results, nil
+}
+
+func (l *Loader) loadChain(ctx context.Context, names []string) (map[string]Result, error) {
+	results, lastErr := l.loadRemoteMany(ctx, names, false)
+	if results != nil {
+		return results, nil
+	}
+	if l.CacheDir != "" {
+		if results, _ := l.loadRemoteMany(ctx, names, true); results != nil {
+			return results, nil
+		}
+	}
+	embeddedResults, embeddedErr := l.loadEmbeddedMany(names)
+	if embeddedErr != nil && lastErr != nil {
+		return nil, fmt.Errorf("remote data failed: %v; embedded data failed: %w", lastErr, embeddedErr)
+	}
+	return embeddedResults, embeddedErr
+}
+
+// loadRemoteMany tries each manifest source in turn. With cacheOnly set no
+// request is made and only copies already in CacheDir are considered.
+func (l *Loader) loadRemoteMany(ctx context.Context, names []string, cacheOnly bool) (map[string]Result, error) {
+	if l.PublicKey == nil && l.RequireSignature {
+		return nil, errors.New("no manifest public key is built in; unsigned remote data is not trusted")
+	}
 	var lastErr error
 	for _, manifestBase := range l.manifestBases() {
-		manifestData, err := l.fetch(ctx, strings.TrimRight(manifestBase, "/")+"/manifest.json")
+		var pending []*cacheEntry
+		manifestFile, err := l.get(ctx, strings.TrimRight(manifestBase, "/")+"/manifest.json", cacheOnly)
 		if err != nil {
 			lastErr = err
 			continue
 		}
+		pending = append(pending, manifestFile.entry)
+		manifestData := manifestFile.data
+		signature := SignatureUnsigned
+		if l.PublicKey != nil {
+			signatureFile, err := l.get(ctx, strings.TrimRight(manifestBase, "/")+"/"+ManifestSignatureName, cacheOnly)
+			if err == nil {
+				err = VerifyManifestSignature(l.PublicKey, manifestData, signatureFile.data)
+			}
+			if err != nil {
+				lastErr = fmt.Errorf("verify manifest signature from %s: %w", manifestBase, err)
+				continue
+			}
+			pending = append(pending, signatureFile.entry)
+			signature = SignatureVerified
+		}
 		var m Manifest
 		if err := json.Unmarshal(manifestData, &m); err != nil {
 			lastErr = fmt.Errorf("decode remote manifest from %s: %w", manifestBase, err)
@@ -135,7 +241,8 @@ func (l *Loader) LoadMany(ctx context.Context, names []string) (map[string]Resul
 			}
 			var loaded bool
 			for _, dataBase := range l.bases() {
-				data, dataErr := l.fetch(ctx, strings.TrimRight(dataBase, "/")+"/"+name)
+				file, dataErr := l.get(ctx, strings.TrimRight(dataBase, "/")+"/"+name, cacheOnly)
+				data := file.data
 				if dataErr == nil {
 					dataErr = verify(data, meta.SHA256)
 				}
@@ -156,7 +263,14 @@ func (l *Loader) LoadMany(ctx context.Context, names []string) (map[string]Resul
 				} else if manifestFallback {
 					fallback = "cdn_manifest"
 				}
-				candidate[name] = Result{Name: name, Data: data, Manifest: m, UsedRemote: true, Source: dataSource, Fallback: fallback}
+				if file.cached {
+					dataSource = "cache"
+				}
+				if cacheOnly {
+					fallback = "cache"
+				}
+				pending = append(pending, file.entry)
+				candidate[name] = Result{Name: name, Data: data, Manifest: m, UsedRemote: !cacheOnly, Source: dataSource, Fallback: fallback, Signature: signature}
 				loaded = true
 				break
 			}
@@ -166,14 +280,13 @@ func (l *Loader) LoadMany(ctx context.Context, names []string) (map[string]Resul
 			}
 		}
 		if candidateValid && len(candidate) == len(names) {
+			// Only a fully verified set replaces what is cached, so an
+			// offline run never sees a half-updated generation.
+			l.storeCache(pending)
 			return candidate, nil
 		}
 	}
-	embeddedResults, embeddedErr := l.loadEmbeddedMany(names)
-	if embeddedErr != nil && lastErr != nil {
-		return nil, fmt.Errorf("remote data failed: %v; embedded data failed: %w", lastErr, embeddedErr)
-	}
-	return embeddedResults, embeddedErr
+	return nil, lastErr
 }
 
 func (l *Loader) loadEmbedded(name string) (Result, error) {
@@ -219,7 +332,7 @@ func (l *Loader) loadEmbeddedMany(names []string) (map[string]Result, error) {
 		if err := verifySchema(name, data); err != nil {
 			return nil, fmt.Errorf("verify embedded %s schema: %w", name, err)
 		}
-		results[name] = Result{Name: name, Data: data, Manifest: m, Fallback: "embedded", Source: "embedded"}
+		results[name] = Result{Name: name, Data: data, Manifest: m, Fallback: "embedded", Source: "embedded", Signature: SignatureEmbedded}
 	}
 	return results, nil
 }
@@ -260,6 +373,20 @@ func sourceForBase(cdnBase, rawBase, base string) string {
 }
 
 func (l *Loader) fetch(ctx context.Context, name string) ([]byte, error) {
+	response, err := l.fetchConditional(ctx, name, nil)
+	return response.data, err
+}
+
+type fetchResponse struct {
+	data         []byte
+	etag         string
+	lastModified string
+	notModified  bool
+}
+
+// fetchConditional sends the validators of cached, when present, so an
+// unchanged file costs a 304 instead of a full download.
+func (l *Loader) fetchConditional(ctx context.Context, name string, cached *cacheEntry) (fetchResponse, error) {
 	if ctx == nil {
 		ctx = context.Background()
 	}
@@ -267,25 +394,36 @@ func (l *Loader) fetch(ctx context.Context, name string) ([]byte, error) {
 	defer cancel()
 	req, err := http.NewRequestWithContext(requestCtx, http.MethodGet, name, nil)
 	if err != nil {
-		return nil, err
+		return fetchResponse{}, err
 	}
 	req.Header.Set("User-Agent", "oneclickvirt-goecs-data/1")
+	if cached != nil {
+		if cached.meta.ETag != "" {
+			req.Header.Set("If-None-Match", cached.meta.ETag)
+		}
+		if cached.meta.LastModified != "" {
+			req.Header.Set("If-Modified-Since", cached.meta.LastModified)
+		}
+	}
 	resp, err := l.Client.Do(req)
 	if err != nil {
-		return nil, err
+		return fetchResponse{}, err
 	}
 	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusNotModified && cached != nil {
+		return fetchResponse{notModified: true}, nil
+	}
 	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("HTTP %d", resp.StatusCode)
+		return fetchResponse{}, fmt.Errorf("HTTP %d", resp.StatusCode)
 	}
 	data, err := io.ReadAll(io.LimitReader(resp.Body, maxPayloadSize+1))
 	if err != nil {
-		return nil, err
+		return fetchResponse{}, err
 	}
 	if len(data) > maxPayloadSize {
-		return nil, fmt.Errorf("response exceeds %d bytes", maxPayloadSize)
+		return fetchResponse{}, fmt.Errorf("response exceeds %d bytes", maxPayloadSize)
 	}
-	return data, nil
+	return fetchResponse{data: data, etag: resp.Header.Get("ETag"), lastModified: resp.Header.Get("Last-Modified")}, nil
 }
 
 func (l *Loader) bases() []string {
@@ -429,6 +567,30 @@ func verifySchema(name string, data []byte) error {
 			}
 			seen[record.ASN] = struct{}{}
 		}
+	case "cpu-stats.json":
+		var records []struct {
+			CPUModel  string  `json:"cpu_model"`
+			Rank      int     `json:"rank"`
+			MaxSingle float64 `json:"max_single_score"`
+			MaxMulti  float64 `json:"max_multi_score"`
+		}
+		if err := json.Unmarshal(data, &records); err != nil {
+			return err
+		}
+		if len(records) == 0 {
+			return errors.New("no CPU statistics")
+		}
+		seen := make(map[string]struct{}, len(records))
+		for _, record := range records {
+			key := strings.ToLower(strings.TrimSpace(record.CPUModel))
+			if key == "" || record.Rank <= 0 || (record.MaxSingle <= 0 && record.MaxMulti <= 0) {
+				return errors.New("invalid CPU statistics fields")
+			}
+			if _, exists := seen[key]; exists {
+				return errors.New("duplicate CPU model")
+			}
+			seen[key] = struct{}{}
+		}
 	case "speedtest-servers.json":
 		var records []struct {
 			ID     string `json:"id"`
@@ -229,6 +229,9 @@ func TestVerifySchemaRejectsFieldDrift(t *testing.T) {
 	if err := verifySchema("media-providers.json", []byte(`[{"id":"one","name":"One","groups":["global"],"supports_ipv6":true}]`)); err != nil {
 		t.Fatal(err)
 	}
+	if err := verifySchema("cpu-stats.json", []byte(`[]`)); err == nil {
+		t.Fatal("expected empty CPU statistics to fail")
+	}
 }
 
 func TestAllEmbeddedFilesValidate(t *testing.T) {
@@ -241,7 +244,7 @@ func TestAllEmbeddedFilesValidate(t *testing.T) {
 		t.Fatal(err)
 	}
 	loader := NewLoader(http.DefaultClient, "")
-	results, err := loader.loadEmbeddedMany(KnownFiles())
+	results, err := loader.loadEmbeddedMany(SnapshotFiles())
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -0,0 +1,97 @@
+package data
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// loadOverlay returns the requested files that OverlayDir provides and the
+// names still to be loaded from the normal chain. An overlay file that fails
+// any check is an error rather than a silent fallback, since the caller asked
+// for that data explicitly.
+func (l *Loader) loadOverlay(names []string) (map[string]Result, []string, error) {
+	if l.OverlayDir == "" {
+		return nil, names, nil
+	}
+	m, err := readOverlayManifest(l.OverlayDir)
+	if err != nil {
+		return nil, nil, err
+	}
+	results := make(map[string]Result)
+	remaining := make([]string, 0, len(names))
+	for _, name := range names {
+		meta, ok := m.Files[name]
+		if !ok {
+			remaining = append(remaining, name)
+			continue
+		}
+		data, err := readOverlayFile(l.OverlayDir, name, meta)
+		if err != nil {
+			return nil, nil, err
+		}
+		results[name] = Result{Name: name, Data: data, Manifest: m, Source: "overlay"}
+	}
+	return results, remaining, nil
+}
+
+// ValidateOverlay checks every known file listed in the manifest of dir, so
+// a broken overlay is reported before any test starts.
+func ValidateOverlay(dir string) error {
+	m, err := readOverlayManifest(dir)
+	if err != nil {
+		return err
+	}
+	listed := 0
+	for _, name := range knownFiles {
+		meta, ok := m.Files[name]
+		if !ok {
+			continue
+		}
+		listed++
+		if _, err := readOverlayFile(dir, name, meta); err != nil {
+			return err
+		}
+	}
+	if listed == 0 {
+		return fmt.Errorf("overlay manifest in %s lists none of the known data files", dir)
+	}
+	return nil
+}
+
+func readOverlayManifest(dir string) (Manifest, error) {
+	manifestData, err := os.ReadFile(filepath.Join(dir, "manifest.json"))
+	if err != nil {
+		return Manifest{}, fmt.Errorf("read overlay manifest: %w", err)
+	}
+	var m Manifest
+	if err := json.Unmarshal(manifestData, &m); err != nil {
+		return Manifest{}, fmt.Errorf("decode overlay manifest: %w", err)
+	}
+	if !supportedSchema(m.Schema) || m.GeneratedAt.IsZero() || m.Files == nil {
+		return Manifest{}, errors.New("overlay manifest is missing schema, generated_at or files")
+	}
+	return m, nil
+}
+
+func readOverlayFile(dir, name string, meta FileMeta) ([]byte, error) {
+	data, err := os.ReadFile(filepath.Join(dir, name))
+	if err != nil {
+		return nil, fmt.Errorf("read overlay %s: %w", name, err)
+	}
+	if len(data) > maxPayloadSize {
+		return nil, fmt.Errorf("overlay %s exceeds %d bytes", name, maxPayloadSize)
+	}
+	if err := verify(data, meta.SHA256); err != nil {
+		return nil, fmt.Errorf("verify overlay %s: %w", name, err)
+	}
+	if err := verifyCount(data, meta.Count); err != nil {
+		return nil, fmt.Errorf("verify overlay %s: %w", name, err)
+	}
+	if err := verifySchema(name, data); err != nil {
+		return nil, fmt.Errorf("verify overlay %s schema: %w", name, err)
+	}
+	return data, nil
+}
@@ -0,0 +1,83 @@
+package data
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func writeOverlay(t *testing.T, files map[string]string, hashes map[string]string) string {
+	t.Helper()
+	dir := t.TempDir()
+	entries := make([]string, 0, len(files))
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		hash := sha256.Sum256([]byte(content))
+		sum := hex.EncodeToString(hash[:])
+		if override, ok := hashes[name]; ok {
+			sum = override
+		}
+		entries = append(entries, fmt.Sprintf(`%q:{"sha256":%q,"count":1}`, name, sum))
+	}
+	manifest := `{"schema":"goecs-data/v1","generated_at":"2026-07-19T00:00:00Z","files":{` + strings.Join(entries, ",") + `}}`
+	if err := os.WriteFile(filepath.Join(dir, "manifest.json"), []byte(manifest), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return dir
+}
+
+func TestLoaderOverlaysListedFilesOnly(t *testing.T) {
+	targets := "[{\"id\":\"pop\",\"name\":\"Our PoP\",\"host\":\"pop.internal.example\",\"port\":443}]\n"
+	loader := NewLoader(nil, "")
+	loader.RawBase = ""
+	loader.OverlayDir = writeOverlay(t, map[string]string{"tcp-targets.json": targets}, nil)
+	if err := ValidateOverlay(loader.OverlayDir); err != nil {
+		t.Fatal(err)
+	}
+	results, err := loader.LoadMany(context.Background(), []string{"tcp-targets.json", "dnsbl-zones.json"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	overlaid, inherited := results["tcp-targets.json"], results["dnsbl-zones.json"]
+	if overlaid.Source != "overlay" || string(overlaid.Data) != targets || overlaid.Manifest.Files["tcp-targets.json"].Count != 1 {
+		t.Fatalf("overlay result = %#v", overlaid)
+	}
+	if inherited.Source != "embedded" || len(inherited.Data) == 0 {
+		t.Fatalf("file missing from the overlay was not inherited: %#v", inherited)
+	}
+}
+
+func TestLoaderRejectsInvalidOverlay(t *testing.T) {
+	valid := "[{\"id\":\"pop\",\"name\":\"Our PoP\",\"host\":\"pop.internal.example\",\"port\":443}]\n"
+	for _, test := range []struct {
+		name   string
+		files  map[string]string
+		hashes map[string]string
+	}{
+		{name: "hash", files: map[string]string{"tcp-targets.json": valid}, hashes: map[string]string{"tcp-targets.json": strings.Repeat("0", 64)}},
+		{name: "count", files: map[string]string{"tcp-targets.json": "[]\n"}},
+		{name: "schema", files: map[string]string{"tcp-targets.json": "[{\"id\":\"pop\",\"name\":\"\",\"host\":\"pop.example\",\"port\":443}]\n"}},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			loader := NewLoader(nil, "")
+			loader.RawBase = ""
+			loader.OverlayDir = writeOverlay(t, test.files, test.hashes)
+			if err := ValidateOverlay(loader.OverlayDir); err == nil {
+				t.Fatal("invalid overlay passed validation")
+			}
+			if _, err := loader.Load(context.Background(), "tcp-targets.json"); err == nil {
+				t.Fatal("invalid overlay was loaded or silently replaced")
+			}
+		})
+	}
+	if err := ValidateOverlay(writeOverlay(t, map[string]string{"private.json": valid}, nil)); err == nil {
+		t.Fatal("overlay without known files passed validation")
+	}
+}
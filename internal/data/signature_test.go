@@ -0,0 +1,118 @@
+package data
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/pem"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestLoaderRequiresValidManifestSignature(t *testing.T) {
+	_, key, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, otherKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	payload := []byte("[{\"id\":\"signed\",\"name\":\"Signed\",\"host\":\"signed.example\",\"port\":443}]\n")
+	hash := sha256.Sum256(payload)
+	manifest := []byte(fmt.Sprintf(`{"schema":"goecs-data/v1","generated_at":"2026-07-19T00:00:00Z","files":{"tcp-targets.json":{"sha256":"%s","count":1}}}`, hex.EncodeToString(hash[:])))
+	for _, test := range []struct {
+		name      string
+		signature []byte
+		want      string
+	}{
+		{name: "valid", signature: SignManifest(key, manifest), want: SignatureVerified},
+		{name: "other key", signature: SignManifest(otherKey, manifest), want: SignatureEmbedded},
+		{name: "missing", want: SignatureEmbedded},
+		{name: "malformed", signature: []byte("not-base64\n"), want: SignatureEmbedded},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				switch {
+				case r.URL.Path == "/manifest.json":
+					_, _ = w.Write(manifest)
+				case r.URL.Path == "/"+ManifestSignatureName && test.signature != nil:
+					_, _ = w.Write(test.signature)
+				case r.URL.Path == "/tcp-targets.json":
+					_, _ = w.Write(payload)
+				default:
+					http.NotFound(w, r)
+				}
+			}))
+			defer server.Close()
+			loader := NewLoader(server.Client(), server.URL)
+			loader.RawBase = ""
+			loader.PublicKey = key.Public().(ed25519.PublicKey)
+			result, err := loader.Load(context.Background(), "tcp-targets.json")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if result.Signature != test.want || result.UsedRemote != (test.want == SignatureVerified) {
+				t.Fatalf("signature = %q remote=%v, want %q", result.Signature, result.UsedRemote, test.want)
+			}
+
+			// Without a built-in key the same manifest is accepted unsigned.
+			loader.PublicKey = nil
+			result, err = loader.Load(context.Background(), "tcp-targets.json")
+			if err != nil || result.Signature != SignatureUnsigned || !result.UsedRemote {
+				t.Fatalf("unsigned load = %#v err=%v", result, err)
+			}
+
+			// A release build without a key falls back to the snapshot.
+			loader.RequireSignature = true
+			result, err = loader.Load(context.Background(), "tcp-targets.json")
+			if err != nil || result.Signature != SignatureEmbedded || result.UsedRemote {
+				t.Fatalf("required signature load = %#v err=%v", result, err)
+			}
+		})
+	}
+}
+
+func TestEmbeddedPublicKeyFailsClosedWhenMalformed(t *testing.T) {
+	original := manifestPublicKey
+	t.Cleanup(func() { manifestPublicKey = original })
+	manifestPublicKey = ""
+	if embeddedPublicKey() != nil {
+		t.Fatal("empty key must disable verification")
+	}
+	manifestPublicKey = "not base64"
+	key := embeddedPublicKey()
+	if key == nil || VerifyManifestSignature(key, []byte("{}"), []byte("AA==")) == nil {
+		t.Fatal("malformed key must reject every manifest")
+	}
+}
+
+func TestParseSigningKeyAcceptsPEMAndBase64(t *testing.T) {
+	_, key, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	der, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for name, data := range map[string][]byte{
+		"pem":         pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der}),
+		"seed":        []byte(base64.StdEncoding.EncodeToString(key.Seed()) + "\n"),
+		"private key": []byte(base64.StdEncoding.EncodeToString(key)),
+	} {
+		parsed, err := ParseSigningKey(data)
+		if err != nil || !parsed.Equal(key) {
+			t.Fatalf("%s: parsed=%v err=%v", name, parsed, err)
+		}
+	}
+	if _, err := ParseSigningKey([]byte(base64.StdEncoding.EncodeToString([]byte("short")))); err == nil {
+		t.Fatal("short key was accepted")
+	}
+}
@@ -0,0 +1,233 @@
+package datasync
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// healthHistoryPath is relative to the snapshot directory. It sits in a
+// subdirectory so neither the runtime embed pattern nor the manifest treat it
+// as a data file.
+const healthHistoryPath = "health/history.json"
+
+const (
+	// DefaultHealthFailures is how many consecutive failed probes turn an
+	// available node unavailable.
+	DefaultHealthFailures = 3
+	// DefaultHealthRecoveries is how many consecutive successful probes turn
+	// an unavailable node available again.
+	DefaultHealthRecoveries = 2
+)
+
+type healthHistory struct {
+	Schema string                           `json:"schema"`
+	Files  map[string]map[string]nodeHealth `json:"files"`
+}
+
+// nodeHealth is the published status of one node and the probe streak that
+// may flip it. Streaks stop counting at the threshold that flips the node and
+// UpSince only moves when a node comes back, so a run in which every node
+// answers as before rewrites the same history.
+type nodeHealth struct {
+	Status    string    `json:"status"`
+	Successes int       `json:"successes,omitempty"`
+	Failures  int       `json:"failures,omitempty"`
+	UpSince   time.Time `json:"up_since,omitzero"`
+}
+
+// healthTracker applies probe hysteresis during one synchronization run.
+type healthTracker struct {
+	failures   int
+	recoveries int
+	now        time.Time
+	outputDir  string
+	previous   map[string]map[string]nodeHealth
+	// published caches the snapshot statuses read by initial, per file.
+	published map[string]map[string]string
+}
+
+// loadHealthTracker reads the history next to the snapshot. Nodes missing
+// from the history start from the status the snapshot publishes for them, so
+// a lost history does not flip every node on a single probe.
+func loadHealthTracker(outputDir string, options Options, now time.Time) *healthTracker {
+	tracker := &healthTracker{failures: options.HealthFailures, recoveries: options.HealthRecoveries, now: now, outputDir: outputDir}
+	if tracker.failures <= 0 {
+		tracker.failures = DefaultHealthFailures
+	}
+	if tracker.recoveries <= 0 {
+		tracker.recoveries = DefaultHealthRecoveries
+	}
+	data, err := os.ReadFile(filepath.Join(outputDir, healthHistoryPath))
+	if err != nil {
+		return tracker
+	}
+	var history healthHistory
+	if json.Unmarshal(data, &history) == nil && history.Schema == schemaVersion {
+		tracker.previous = history.Files
+	}
+	return tracker
+}
+
+// apply replaces the single-probe status of each node in value with the
+// status its streak allows and returns the updated history for file. Values
+// without a per-node status are returned unchanged with a nil history.
+func (tracker *healthTracker) apply(file string, value any) (any, map[string]nodeHealth) {
+	if tracker == nil {
+		return value, nil
+	}
+	switch nodes := value.(type) {
+	case []map[string]any:
+		keys := healthKeys(nodes)
+		next := make(map[string]nodeHealth, len(nodes))
+		for index, node := range nodes {
+			state := tracker.step(tracker.initial(file, keys[index]), fmt.Sprint(node["status"]) == "available")
+			node["status"] = state.Status
+			next[keys[index]] = state
+		}
+		return nodes, next
+	case []transferTarget:
+		records := make([]map[string]any, len(nodes))
+		for index, node := range nodes {
+			records[index] = map[string]any{"id": node.ID, "host": node.Host}
+		}
+		keys := healthKeys(records)
+		next := make(map[string]nodeHealth, len(nodes))
+		for index := range nodes {
+			state := tracker.step(tracker.initial(file, keys[index]), nodes[index].Status == "available")
+			nodes[index].Status = state.Status
+			next[keys[index]] = state
+		}
+		return nodes, next
+	default:
+		return value, nil
+	}
+}
+
+// initial returns the state a node enters this run with: its history, or
+// else the status the current snapshot publishes for it. A node in neither
+// has an empty status.
+func (tracker *healthTracker) initial(file, key string) nodeHealth {
+	if state, ok := tracker.previous[file][key]; ok {
+		return state
+	}
+	if tracker.published == nil {
+		tracker.published = make(map[string]map[string]string)
+	}
+	statuses, ok := tracker.published[file]
+	if !ok {
+		statuses = publishedStatuses(filepath.Join(tracker.outputDir, file))
+		tracker.published[file] = statuses
+	}
+	return nodeHealth{Status: statuses[key]}
+}
+
+// publishedStatuses maps the nodes of a snapshot file to their status. An
+// unreadable file publishes nothing.
+func publishedStatuses(path string) map[string]string {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil
+	}
+	var nodes []map[string]any
+	if json.Unmarshal(data, &nodes) != nil {
+		return nil
+	}
+	keys := healthKeys(nodes)
+	statuses := make(map[string]string, len(nodes))
+	for index, node := range nodes {
+		if status, _ := node["status"].(string); status == "available" || status == "unavailable" {
+			statuses[keys[index]] = status
+		}
+	}
+	return statuses
+}
+
+// healthKeys returns a distinct history key for each node: its id, or its
+// host or name when it has none. Repeated keys get a "#N" suffix in list
+// order so nodes never share a streak.
+func healthKeys(nodes []map[string]any) []string {
+	keys := make([]string, len(nodes))
+	seen := make(map[string]int, len(nodes))
+	for index, node := range nodes {
+		key := "node"
+		for _, field := range []string{"id", "host", "name"} {
+			if value, ok := node[field]; ok && value != nil {
+				if text := strings.TrimSpace(fmt.Sprint(value)); text != "" {
+					key = text
+					break
+				}
+			}
+		}
+		seen[key]++
+		if seen[key] > 1 {
+			key = fmt.Sprintf("%s#%d", key, seen[key])
+		}
+		keys[index] = key
+	}
+	return keys
+}
+
+// step folds one probe result into a node's history. A node without a status
+// takes the probe result directly.
+func (tracker *healthTracker) step(state nodeHealth, up bool) nodeHealth {
+	if up {
+		if state.Successes == 0 || state.UpSince.IsZero() {
+			state.UpSince = tracker.now
+		}
+		state.Successes = min(state.Successes+1, tracker.recoveries)
+		state.Failures = 0
+	} else {
+		state.Failures = min(state.Failures+1, tracker.failures)
+		state.Successes = 0
+	}
+	switch {
+	case state.Status == "":
+		state.Status = "unavailable"
+		if up {
+			state.Status = "available"
+		}
+	case up && state.Status != "available" && state.Successes >= tracker.recoveries:
+		state.Status = "available"
+	case !up && state.Status != "unavailable" && state.Failures >= tracker.failures:
+		state.Status = "unavailable"
+	}
+	return state
+}
+
+// save writes the history for the staged files. Files that kept their
+// current snapshot keep their previous history, and files no longer produced
+// are dropped.
+func (tracker *healthTracker) save(outputDir string, staged map[string]stagedFile) error {
+	history := healthHistory{Schema: schemaVersion, Files: make(map[string]map[string]nodeHealth)}
+	for name, file := range staged {
+		switch {
+		case file.health != nil:
+			history.Files[name] = file.health
+		case tracker.previous[name] != nil:
+			history.Files[name] = tracker.previous[name]
+		}
+	}
+	path := filepath.Join(outputDir, healthHistoryPath)
+	if len(history.Files) == 0 {
+		if err := os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return fmt.Errorf("remove health history: %w", err)
+		}
+		return nil
+	}
+	data, err := json.MarshalIndent(history, "", "  ")
+	if err != nil {
+		return fmt.Errorf("encode health history: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return fmt.Errorf("create health history directory: %w", err)
+	}
+	if err := atomicWrite(path, append(data, '\n')); err != nil {
+		return fmt.Errorf("write health history: %w", err)
+	}
+	return nil
+}
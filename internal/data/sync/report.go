@@ -0,0 +1,198 @@
+package datasync
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// Report describes what a synchronization run changed, or would change in a
+// dry run, so scheduled snapshot updates can be reviewed without reading raw
+// JSON diffs.
+type Report struct {
+	DryRun  bool
+	Changed bool
+	Files   []FileReport
+	// Removed lists files in the current manifest that no source produces.
+	Removed []string
+}
+
+// FileReport compares one staged file with the current snapshot. Record keys
+// come from the id, zone, asn or code field. Fallback holds the update error
+// when the current file was kept instead. Available counts are only set for
+// registries with a status field; MinimumCount and MinimumAvailable are the
+// guard floors derived from MaxDrop. Guard lists the guard failures.
+type FileReport struct {
+	Name              string
+	Source            string
+	Fallback          string
+	PreviousCount     int
+	Count             int
+	MinimumCount      int
+	HasAvailability   bool
+	PreviousAvailable int
+	Available         int
+	MinimumAvailable  int
+	MaxDrop           float64
+	Added             []string
+	Removed           []string
+	Changed           []string
+	Guard             []string
+}
+
+// buildReport diffs staged files against outputDir. It only reads the
+// current snapshot; a missing or unreadable file is treated as empty.
+func buildReport(outputDir string, specs []sourceSpec, staged map[string]stagedFile, fallbacks map[string]string, dryRun bool) *Report {
+	report := &Report{DryRun: dryRun, Files: make([]FileReport, 0, len(specs))}
+	var current manifest
+	if data, err := os.ReadFile(filepath.Join(outputDir, "manifest.json")); err == nil {
+		_ = json.Unmarshal(data, &current)
+	}
+	for _, spec := range specs {
+		file := staged[spec.file]
+		entry := FileReport{
+			Name:          spec.file,
+			Source:        file.source,
+			Fallback:      fallbacks[spec.file],
+			PreviousCount: current.Files[spec.file].Count,
+			Count:         file.count,
+			MaxDrop:       spec.maximumDrop(),
+		}
+		entry.MinimumCount = minimumAfterDrop(entry.PreviousCount, entry.MaxDrop)
+		previousData, _ := os.ReadFile(filepath.Join(outputDir, spec.file))
+		entry.Added, entry.Removed, entry.Changed = diffRecords(previousData, file.data)
+		previousAvailable, previousHasStatus, _ := countAvailableRecords(previousData)
+		available, hasStatus, _ := countAvailableRecords(file.data)
+		if previousHasStatus || hasStatus {
+			entry.HasAvailability = true
+			entry.PreviousAvailable, entry.Available = previousAvailable, available
+			entry.MinimumAvailable = minimumAfterDrop(previousAvailable, entry.MaxDrop)
+		}
+		report.Files = append(report.Files, entry)
+	}
+	for name := range current.Files {
+		if _, ok := staged[name]; !ok {
+			report.Removed = append(report.Removed, name)
+		}
+	}
+	sort.Strings(report.Removed)
+	return report
+}
+
+func (report *Report) file(name string) *FileReport {
+	for index := range report.Files {
+		if report.Files[index].Name == name {
+			return &report.Files[index]
+		}
+	}
+	return nil
+}
+
+// diffRecords returns the sorted keys of records added, removed and changed
+// between two JSON arrays.
+func diffRecords(previous, current []byte) (added, removed, changed []string) {
+	before := keyedRecords(previous)
+	after := keyedRecords(current)
+	for key, record := range after {
+		old, ok := before[key]
+		switch {
+		case !ok:
+			added = append(added, key)
+		case !bytes.Equal(old, record):
+			changed = append(changed, key)
+		}
+	}
+	for key := range before {
+		if _, ok := after[key]; !ok {
+			removed = append(removed, key)
+		}
+	}
+	sort.Strings(added)
+	sort.Strings(removed)
+	sort.Strings(changed)
+	return added, removed, changed
+}
+
+// keyedRecords indexes a JSON array by each record's identifying field,
+// using the compacted record itself for records without one.
+func keyedRecords(data []byte) map[string][]byte {
+	var records []json.RawMessage
+	if len(data) == 0 || json.Unmarshal(data, &records) != nil {
+		return nil
+	}
+	result := make(map[string][]byte, len(records))
+	for _, record := range records {
+		var compacted bytes.Buffer
+		if json.Compact(&compacted, record) != nil {
+			continue
+		}
+		// UseNumber keeps large ASNs from printing in exponent form.
+		var fields map[string]any
+		decoder := json.NewDecoder(bytes.NewReader(record))
+		decoder.UseNumber()
+		_ = decoder.Decode(&fields)
+		key := compacted.String()
+		for _, name := range []string{"id", "zone", "asn", "code"} {
+			if value, ok := fields[name]; ok && value != nil {
+				key = fmt.Sprint(value)
+				break
+			}
+		}
+		result[key] = compacted.Bytes()
+	}
+	return result
+}
+
+// WriteText renders the report for review.
+func (report *Report) WriteText(w io.Writer) error {
+	var b strings.Builder
+	changedFiles := 0
+	for _, file := range report.Files {
+		if len(file.Added)+len(file.Removed)+len(file.Changed) > 0 {
+			changedFiles++
+		}
+	}
+	verb := "changed"
+	if report.DryRun {
+		verb = "would change"
+	}
+	if !report.Changed {
+		fmt.Fprintf(&b, "data snapshot unchanged (%d files checked)\n", len(report.Files))
+	} else {
+		fmt.Fprintf(&b, "data snapshot %s: %d of %d files\n", verb, changedFiles, len(report.Files))
+	}
+	for _, file := range report.Files {
+		fmt.Fprintf(&b, "\n%s\n", file.Name)
+		fmt.Fprintf(&b, "  source: %s\n", file.Source)
+		if file.Fallback != "" {
+			fmt.Fprintf(&b, "  kept current snapshot: %s\n", file.Fallback)
+		}
+		fmt.Fprintf(&b, "  records: %d -> %d (%+d, guard minimum %d at %.0f%% max drop)\n", file.PreviousCount, file.Count, file.Count-file.PreviousCount, file.MinimumCount, file.MaxDrop*100)
+		if file.HasAvailability {
+			fmt.Fprintf(&b, "  available: %d -> %d (%+d, guard minimum %d)\n", file.PreviousAvailable, file.Available, file.Available-file.PreviousAvailable, file.MinimumAvailable)
+		}
+		writeKeys(&b, "added", file.Added)
+		writeKeys(&b, "removed", file.Removed)
+		writeKeys(&b, "changed", file.Changed)
+		for _, failure := range file.Guard {
+			fmt.Fprintf(&b, "  GUARD FAILED: %s\n", failure)
+		}
+	}
+	if len(report.Removed) > 0 {
+		fmt.Fprintf(&b, "\nfiles removed from the manifest: %s\n", strings.Join(report.Removed, ", "))
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+func writeKeys(b *strings.Builder, label string, keys []string) {
+	if len(keys) == 0 {
+		return
+	}
+	fmt.Fprintf(b, "  %s (%d): %s\n", label, len(keys), strings.Join(keys, ", "))
+}
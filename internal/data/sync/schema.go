@@ -64,10 +64,37 @@ func strictJSONArray[T any](data []byte) ([]T, error) {
 }
 
 func validateTCPTargetSchema(data []byte) error {
-	records, err := strictJSONArray[tcpTarget](data)
+	keys, err := validateTCPTargetRecords(data)
+	if err != nil {
+		return err
+	}
+	return validateStableUniqueKeys(keys)
+}
+
+// ValidateTCPTargets applies the tcp-targets.json record rules to a
+// user-supplied target list. Custom lists keep their author's order, so only
+// the stable sort requirement of the generated snapshot is relaxed; IDs must
+// still be unique.
+func ValidateTCPTargets(data []byte) error {
+	keys, err := validateTCPTargetRecords(data)
 	if err != nil {
 		return err
 	}
+	seen := make(map[string]struct{}, len(keys))
+	for index, key := range keys {
+		if _, exists := seen[key]; exists {
+			return fmt.Errorf("record %d duplicates id %q", index, key)
+		}
+		seen[key] = struct{}{}
+	}
+	return nil
+}
+
+func validateTCPTargetRecords(data []byte) ([]string, error) {
+	records, err := strictJSONArray[tcpTarget](data)
+	if err != nil {
+		return nil, err
+	}
 	seenEndpoints := make(map[string]struct{}, len(records))
 	keys := make([]string, 0, len(records))
 	for index, record := range records {
@@ -76,16 +103,16 @@ func validateTCPTargetSchema(data []byte) error {
 		record.Host = strings.ToLower(strings.TrimSpace(record.Host))
 		record.Category = strings.TrimSpace(record.Category)
 		if record.ID == "" || record.Name == "" || record.Category == "" || !validSchemaHost(record.Host) || record.Port < 1 || record.Port > 65535 {
-			return fmt.Errorf("record %d has empty or invalid required fields", index)
+			return nil, fmt.Errorf("record %d has empty or invalid required fields", index)
 		}
 		endpoint := net.JoinHostPort(record.Host, strconv.Itoa(record.Port))
 		if _, exists := seenEndpoints[endpoint]; exists {
-			return fmt.Errorf("record %d duplicates endpoint %q", index, endpoint)
+			return nil, fmt.Errorf("record %d duplicates endpoint %q", index, endpoint)
 		}
 		seenEndpoints[endpoint] = struct{}{}
 		keys = append(keys, record.ID)
 	}
-	return validateStableUniqueKeys(keys)
+	return keys, nil
 }
 
 func validateProvinceRouteSchema(data []byte) error {
@@ -219,6 +246,34 @@ func validateMediaProviderSchema(data []byte) error {
 	return validateStableUniqueKeys(keys)
 }
 
+func validateCPUStatsSchema(data []byte) error {
+	records, err := strictJSONArray[cpuStat](data)
+	if err != nil {
+		return err
+	}
+	if len(records) == 0 {
+		return errors.New("no CPU statistics")
+	}
+	seen := make(map[string]struct{}, len(records))
+	for index, record := range records {
+		model := strings.ToLower(record.CPUModel)
+		if model == "" || model != strings.ToLower(strings.TrimSpace(record.CPUModel)) || record.Rank <= 0 || (record.MaxSingle <= 0 && record.MaxMulti <= 0) {
+			return fmt.Errorf("record %d has an empty model, rank or score", index)
+		}
+		if _, exists := seen[model]; exists {
+			return errors.New("CPU models are duplicated")
+		}
+		seen[model] = struct{}{}
+		if index > 0 {
+			previous := records[index-1]
+			if record.Rank < previous.Rank || record.Rank == previous.Rank && model < strings.ToLower(previous.CPUModel) {
+				return errors.New("CPU records are not stably sorted")
+			}
+		}
+	}
+	return nil
+}
+
 func validateStableUniqueKeys(keys []string) error {
 	for index, key := range keys {
 		if strings.TrimSpace(key) == "" {
@@ -0,0 +1,179 @@
+package datasync
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+type sourceKind struct {
+	transform func([]byte) (any, int, error)
+	health    func(context.Context, any) (any, error)
+}
+
+// sourceKinds are the parsers a sources file can select with "kind". Each
+// default source uses the kind of the same name; "json" passes through an
+// array that is already in goecs format.
+var sourceKinds = map[string]sourceKind{
+	"tcpbench":  {transform: parseTCPTargets},
+	"provinces": {transform: parseProvinceRoutes},
+	"speedtest": {transform: parseSpeedtestServers, health: probeSpeedtestServers},
+	"transfer":  {transform: parseTransferTargets, health: probeTransferTargets},
+	"dnsbl":     {transform: parseDNSBL, health: probeDNSBLZones},
+	"asn":       {transform: parseASNMap},
+	"media":     {transform: parseProviderNames},
+	"cpustats":  {transform: parseCPUStatistics},
+	"json":      {transform: passJSONArray},
+}
+
+// outputSchemas validate the files goecs reads, keyed by output file rather
+// than by kind, so a "json" source or a different parser cannot write an
+// unchecked payload under a known name.
+var outputSchemas = map[string]func([]byte) error{
+	"tcp-targets.json":           validateTCPTargetSchema,
+	"province-routes.json":       validateProvinceRouteSchema,
+	"speedtest-servers.json":     validateSpeedtestServerSchema,
+	"openspeedtest-servers.json": validateTransferTargetSchema,
+	"dnsbl-zones.json":           validateDNSBLZoneSchema,
+	"bgp-asn-map.json":           validateASNMapSchema,
+	"media-providers.json":       validateMediaProviderSchema,
+	"cpu-stats.json":             validateCPUStatsSchema,
+}
+
+// sourceConfig is one entry of a sources file. Name defaults to the kind,
+// health defaults to on for kinds that have a probe, and max_drop defaults
+// to defaultMaxDrop.
+type sourceConfig struct {
+	Name         string   `yaml:"name"`
+	Kind         string   `yaml:"kind"`
+	File         string   `yaml:"file"`
+	URL          string   `yaml:"url"`
+	FallbackURLs []string `yaml:"fallback_urls"`
+	Path         string   `yaml:"path"`
+	Minimum      int      `yaml:"minimum"`
+	Health       *bool    `yaml:"health"`
+	MaxDrop      *float64 `yaml:"max_drop"`
+}
+
+// loadSourceSpecs reads a YAML or JSON list of sources so the snapshot can be
+// built from internal mirrors or with per-source fallback URLs. Output-file
+// rules and drop guards are left to synchronize, which applies them to every
+// spec alike.
+func loadSourceSpecs(path string) ([]sourceSpec, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("read sources: %w", err)
+	}
+	defer file.Close()
+	data, err := io.ReadAll(io.LimitReader(file, 1<<20))
+	if err != nil {
+		return nil, fmt.Errorf("read sources: %w", err)
+	}
+	specs, err := parseSourceSpecs(data)
+	if err != nil {
+		return nil, fmt.Errorf("sources %s: %w", path, err)
+	}
+	return specs, nil
+}
+
+func parseSourceSpecs(data []byte) ([]sourceSpec, error) {
+	// JSON is valid YAML, so one strict decoder covers both formats.
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	decoder.KnownFields(true)
+	var configs []sourceConfig
+	if err := decoder.Decode(&configs); err != nil {
+		if errors.Is(err, io.EOF) {
+			return nil, errors.New("no sources listed")
+		}
+		return nil, err
+	}
+	if len(configs) == 0 {
+		return nil, errors.New("no sources listed")
+	}
+	specs := make([]sourceSpec, 0, len(configs))
+	seen := make(map[string]struct{}, len(configs))
+	for index, config := range configs {
+		spec, err := config.spec()
+		if err != nil {
+			return nil, fmt.Errorf("source %d: %w", index+1, err)
+		}
+		if _, exists := seen[spec.name]; exists {
+			return nil, fmt.Errorf("duplicate source name %q", spec.name)
+		}
+		seen[spec.name] = struct{}{}
+		specs = append(specs, spec)
+	}
+	return specs, nil
+}
+
+func (config sourceConfig) spec() (sourceSpec, error) {
+	kindName := strings.TrimSpace(config.Kind)
+	kind, ok := sourceKinds[kindName]
+	if !ok {
+		return sourceSpec{}, fmt.Errorf("unknown kind %q (want one of %s)", kindName, strings.Join(sourceKindNames(), ", "))
+	}
+	spec := sourceSpec{
+		name:           strings.TrimSpace(config.Name),
+		file:           strings.TrimSpace(config.File),
+		url:            strings.TrimSpace(config.URL),
+		path:           strings.TrimSpace(config.Path),
+		minimum:        config.Minimum,
+		transform:      kind.transform,
+		health:         kind.health,
+		validateOutput: outputSchemas[strings.TrimSpace(config.File)],
+	}
+	if spec.name == "" {
+		spec.name = kindName
+	}
+	if spec.minimum < 1 {
+		return sourceSpec{}, fmt.Errorf("%s: minimum must be at least 1", spec.name)
+	}
+	if (spec.url == "") == (spec.path == "") {
+		return sourceSpec{}, fmt.Errorf("%s: set exactly one of url or path", spec.name)
+	}
+	if spec.path != "" && len(config.FallbackURLs) > 0 {
+		return sourceSpec{}, fmt.Errorf("%s: fallback_urls require url", spec.name)
+	}
+	if spec.url != "" {
+		for _, location := range append([]string{spec.url}, config.FallbackURLs...) {
+			location = strings.TrimSpace(location)
+			if parsed, err := url.Parse(location); err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
+				return sourceSpec{}, fmt.Errorf("%s: %q is not an http(s) URL", spec.name, location)
+			}
+			if location != spec.url {
+				spec.fallbacks = append(spec.fallbacks, location)
+			}
+		}
+	}
+	if config.Health != nil && !*config.Health {
+		spec.health = nil
+	} else if config.Health != nil && kind.health == nil {
+		return sourceSpec{}, fmt.Errorf("%s: kind %s has no health check", spec.name, kindName)
+	}
+	if config.MaxDrop != nil {
+		// Zero would mean "use the default" downstream, so an explicit limit
+		// has to allow at least some loss.
+		if *config.MaxDrop <= 0 || *config.MaxDrop >= 1 {
+			return sourceSpec{}, fmt.Errorf("%s: max_drop must be greater than 0 and less than 1", spec.name)
+		}
+		spec.maxDrop = *config.MaxDrop
+	}
+	return spec, nil
+}
+
+func sourceKindNames() []string {
+	names := make([]string, 0, len(sourceKinds))
+	for name := range sourceKinds {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
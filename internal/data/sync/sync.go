@@ -4,6 +4,7 @@ import (
 	"bufio"
 	"bytes"
 	"context"
+	"crypto/ed25519"
 	"crypto/sha256"
 	"encoding/hex"
 	"encoding/json"
@@ -26,6 +27,7 @@ import (
 	"sync"
 	"time"
 
+	datarepo "github.com/oneclickvirt/ecs/internal/data"
 	"golang.org/x/net/publicsuffix"
 )
 
@@ -42,20 +44,38 @@ var (
 	client = &http.Client{Timeout: 20 * time.Second}
 )
 
+// defaultMaxDrop is the largest fractional loss of records, or of available
+// nodes, tolerated against the current snapshot when a source sets no limit.
+const defaultMaxDrop = 0.35
+
+// sourceSpec describes one generated file. A remote source is fetched from
+// url and then from each fallback in order; a local source reads path.
+// maxDrop overrides defaultMaxDrop when positive.
 type sourceSpec struct {
 	name           string
 	file           string
 	url            string
+	fallbacks      []string
+	path           string
 	minimum        int
+	maxDrop        float64
 	transform      func([]byte) (any, int, error)
 	health         func(context.Context, any) (any, error)
 	validateOutput func([]byte) error
 }
 
+func (spec sourceSpec) maximumDrop() float64 {
+	if spec.maxDrop > 0 {
+		return spec.maxDrop
+	}
+	return defaultMaxDrop
+}
+
 type stagedFile struct {
 	data   []byte
 	count  int
 	source string
+	health map[string]nodeHealth
 }
 
 type fileManifest struct {
@@ -89,6 +109,21 @@ type asnName struct {
 	Name string `json:"name"`
 }
 
+// cpuStat is one CPU model of the published ranking, keeping the field
+// names of the ranks branch so analysis can decode either form.
+type cpuStat struct {
+	CPUPrefix      string  `json:"cpu_prefix"`
+	CPUModel       string  `json:"cpu_model"`
+	SampleCount    int     `json:"sample_count"`
+	MaxSingle      float64 `json:"max_single_score"`
+	MaxMulti       float64 `json:"max_multi_score"`
+	AvgSingle      float64 `json:"avg_single_score"`
+	AvgMulti       float64 `json:"avg_multi_score"`
+	Rank           int     `json:"rank"`
+	TypicalCores   int     `json:"typical_cores"`
+	TypicalThreads int     `json:"typical_threads"`
+}
+
 type transferTarget struct {
 	ID       string `json:"id"`
 	Host     string `json:"host"`
@@ -125,10 +160,52 @@ type provinceCarrierTarget struct {
 // command. Keeping it in the package avoids duplicating release metadata.
 func Version() string { return syncVersion }
 
+// Options adjusts a synchronization run.
+type Options struct {
+	// SignKey, when set, signs manifest.json into manifest.json.sig. Without
+	// it a rewritten manifest drops any previous signature, which would no
+	// longer match.
+	SignKey ed25519.PrivateKey
+	// DryRun runs every fetch, health check and guard but leaves outputDir
+	// untouched; the report then describes the update that would be made.
+	DryRun bool
+	// HealthFailures and HealthRecoveries are the consecutive failed or
+	// successful probes needed to flip a speedtest or transfer node's status;
+	// zero selects DefaultHealthFailures and DefaultHealthRecoveries.
+	HealthFailures   int
+	HealthRecoveries int
+	// SourcesFile replaces the built-in source list with a YAML or JSON list
+	// of sources; see sourceConfig for the fields.
+	SourcesFile string
+}
+
 // Sync fetches, validates and atomically updates the goecs data snapshot.
 // Callers should provide a context with an appropriate overall deadline.
 func Sync(ctx context.Context, outputDir string) (bool, error) {
-	return synchronize(ctx, outputDir, defaultSourceSpecs())
+	return SyncWithOptions(ctx, outputDir, Options{})
+}
+
+// SyncWithOptions is Sync with explicit options.
+func SyncWithOptions(ctx context.Context, outputDir string, options Options) (bool, error) {
+	report, err := SyncReport(ctx, outputDir, options)
+	if err != nil {
+		return false, err
+	}
+	return report.Changed, nil
+}
+
+// SyncReport is SyncWithOptions returning a per-file change report. When a
+// guard refuses the update the report is returned with the error, so the
+// rejected change can still be reviewed.
+func SyncReport(ctx context.Context, outputDir string, options Options) (*Report, error) {
+	specs := defaultSourceSpecs()
+	if options.SourcesFile != "" {
+		var err error
+		if specs, err = loadSourceSpecs(options.SourcesFile); err != nil {
+			return nil, err
+		}
+	}
+	return synchronizeReport(ctx, outputDir, specs, options)
 }
 
 // DefaultOutputDir is the checked-in snapshot location used by the command
@@ -137,54 +214,112 @@ const DefaultOutputDir = "internal/data/snapshot"
 
 func defaultSourceSpecs() []sourceSpec {
 	return []sourceSpec{
-		{name: "tcpbench", file: "tcp-targets.json", url: "https://raw.githubusercontent.com/se-tang/TCPbench/main/backend/scripts/run.sh", minimum: 50, transform: parseTCPTargets, validateOutput: validateTCPTargetSchema},
-		{name: "provinces", file: "province-routes.json", url: "https://raw.githubusercontent.com/xykt/NetQuality/main/ref/province.json", minimum: 31, transform: parseProvinceRoutes, validateOutput: validateProvinceRouteSchema},
-		{name: "speedtest", file: "speedtest-servers.json", url: "https://raw.githubusercontent.com/xykt/NetQuality/main/ref/speedtest_cn.json", minimum: 10, transform: parseSpeedtestServers, health: probeSpeedtestServers, validateOutput: validateSpeedtestServerSchema},
-		{name: "transfer", file: "openspeedtest-servers.json", url: "https://raw.githubusercontent.com/xykt/NetQuality/main/ref/iperf.json", minimum: 5, transform: parseTransferTargets, health: probeTransferTargets, validateOutput: validateTransferTargetSchema},
-		{name: "dnsbl", file: "dnsbl-zones.json", url: "https://raw.githubusercontent.com/xykt/IPQuality/main/ref/dnsbl.list", minimum: 100, transform: parseDNSBL, health: probeDNSBLZones, validateOutput: validateDNSBLZoneSchema},
-		{name: "asn", file: "bgp-asn-map.json", url: "https://raw.githubusercontent.com/xykt/NetQuality/main/ref/AS_Mapping.txt", minimum: 50, transform: parseASNMap, validateOutput: validateASNMapSchema},
-		{name: "media", file: "media-providers.json", url: "https://raw.githubusercontent.com/HsukqiLee/MediaUnlockTest/main/pkg/providers/lists.go", minimum: 100, transform: parseProviderNames, validateOutput: validateMediaProviderSchema},
+		newSourceSpec("tcpbench", "tcp-targets.json", "https://raw.githubusercontent.com/se-tang/TCPbench/main/backend/scripts/run.sh", 50),
+		newSourceSpec("provinces", "province-routes.json", "https://raw.githubusercontent.com/xykt/NetQuality/main/ref/province.json", 31),
+		newSourceSpec("speedtest", "speedtest-servers.json", "https://raw.githubusercontent.com/xykt/NetQuality/main/ref/speedtest_cn.json", 10),
+		newSourceSpec("transfer", "openspeedtest-servers.json", "https://raw.githubusercontent.com/xykt/NetQuality/main/ref/iperf.json", 5),
+		newSourceSpec("dnsbl", "dnsbl-zones.json", "https://raw.githubusercontent.com/xykt/IPQuality/main/ref/dnsbl.list", 100),
+		newSourceSpec("asn", "bgp-asn-map.json", "https://raw.githubusercontent.com/xykt/NetQuality/main/ref/AS_Mapping.txt", 50),
+		newSourceSpec("media", "media-providers.json", "https://raw.githubusercontent.com/HsukqiLee/MediaUnlockTest/main/pkg/providers/lists.go", 100),
+		newSourceSpec("cpustats", "cpu-stats.json", "https://raw.githubusercontent.com/oneclickvirt/ecs/ranks/cpu_statistics.json", 50, "https://github.com/oneclickvirt/ecs/raw/refs/heads/ranks/cpu_statistics.json"),
 	}
 }
 
-func synchronize(ctx context.Context, outputDir string, specs []sourceSpec) (bool, error) {
+// newSourceSpec builds a spec named after its kind with the kind's parser and
+// health probe and the output schema of its file.
+func newSourceSpec(kind, file, url string, minimum int, fallbacks ...string) sourceSpec {
+	parser := sourceKinds[kind]
+	return sourceSpec{name: kind, file: file, url: url, fallbacks: fallbacks, minimum: minimum, transform: parser.transform, health: parser.health, validateOutput: outputSchemas[file]}
+}
+
+func synchronize(ctx context.Context, outputDir string, specs []sourceSpec, options Options) (bool, error) {
+	report, err := synchronizeReport(ctx, outputDir, specs, options)
+	if err != nil {
+		return false, err
+	}
+	return report.Changed, nil
+}
+
+func synchronizeReport(ctx context.Context, outputDir string, specs []sourceSpec, options Options) (*Report, error) {
 	if len(specs) == 0 {
-		return false, errors.New("no data sources configured")
+		return nil, errors.New("no data sources configured")
 	}
 	staged := make(map[string]stagedFile, len(specs))
+	fallbacks := make(map[string]string)
 	changedAt := time.Now().UTC()
+	tracker := loadHealthTracker(outputDir, options, changedAt)
 	for _, spec := range specs {
-		if spec.name == "" || spec.url == "" || spec.transform == nil {
-			return false, errors.New("data source is missing name, URL, or transform")
+		if spec.name == "" || (spec.url == "" && spec.path == "") || spec.transform == nil {
+			return nil, errors.New("data source is missing name, URL or path, or transform")
+		}
+		if spec.path != "" && (spec.url != "" || len(spec.fallbacks) > 0) {
+			return nil, fmt.Errorf("source %s sets both a local path and URLs", spec.name)
+		}
+		if spec.maxDrop < 0 || spec.maxDrop >= 1 {
+			return nil, fmt.Errorf("source %s maximum drop must be between zero and one", spec.name)
 		}
 		if spec.file == "" || filepath.Base(spec.file) != spec.file || filepath.Ext(spec.file) != ".json" || spec.file == "manifest.json" {
-			return false, fmt.Errorf("source %s has invalid output file %q", spec.name, spec.file)
+			return nil, fmt.Errorf("source %s has invalid output file %q", spec.name, spec.file)
 		}
 		if _, exists := staged[spec.file]; exists {
-			return false, fmt.Errorf("duplicate output file %q", spec.file)
+			return nil, fmt.Errorf("duplicate output file %q", spec.file)
 		}
-		file, err := stageRemoteSource(ctx, spec)
+		file, err := stageRemoteSource(ctx, spec, tracker)
 		if err != nil {
 			current, currentErr := loadCurrentSource(outputDir, spec)
 			if currentErr != nil {
-				return false, fmt.Errorf("update %s failed: %v; current snapshot unavailable: %w", spec.name, err, currentErr)
+				return nil, fmt.Errorf("update %s failed: %v; current snapshot unavailable: %w", spec.name, err, currentErr)
 			}
 			file = current
+			fallbacks[spec.file] = err.Error()
 		}
 		staged[spec.file] = file
 	}
+	if err := keepUnlistedKnownFiles(outputDir, staged); err != nil {
+		return nil, err
+	}
 
-	if err := os.MkdirAll(outputDir, 0o755); err != nil {
-		return false, fmt.Errorf("create output directory: %w", err)
+	report := buildReport(outputDir, specs, staged, fallbacks, options.DryRun)
+	// Guards run per source so each one is held to its own drop limit. Every
+	// failure is recorded in the report; the first one is returned.
+	var guardErr error
+	limits := make(map[string]float64, len(specs))
+	for _, spec := range specs {
+		limits[spec.file] = spec.maximumDrop()
+		if err := validateCountDrops(outputDir, map[string]stagedFile{spec.file: staged[spec.file]}, limits[spec.file]); err != nil {
+			report.file(spec.file).Guard = append(report.file(spec.file).Guard, err.Error())
+			if guardErr == nil {
+				guardErr = fmt.Errorf("quantity guard: %w", err)
+			}
+		}
 	}
-	if err := validateCountDrops(outputDir, staged, 0.35); err != nil {
-		return false, fmt.Errorf("quantity guard: %w", err)
+	for _, name := range availabilityFiles {
+		limit, ok := limits[name]
+		if !ok {
+			limit = defaultMaxDrop
+		}
+		if err := validateAvailabilityDrop(outputDir, name, staged, limit); err != nil {
+			if file := report.file(name); file != nil {
+				file.Guard = append(file.Guard, err.Error())
+			}
+			if guardErr == nil {
+				guardErr = fmt.Errorf("availability guard: %w", err)
+			}
+		}
 	}
-	if err := validateAvailabilityDrops(outputDir, staged, 0.35); err != nil {
-		return false, fmt.Errorf("availability guard: %w", err)
+	if guardErr != nil {
+		return report, guardErr
 	}
 	if semanticDataEqual(outputDir, staged) {
-		return false, nil
+		if options.DryRun {
+			return report, nil
+		}
+		changed, err := refreshSignature(outputDir, options.SignKey)
+		if err != nil {
+			return nil, err
+		}
+		report.Changed = changed
+		return report, tracker.save(outputDir, staged)
 	}
 	m := manifest{Schema: schemaVersion, GeneratedAt: changedAt, Files: make(map[string]fileManifest, len(staged))}
 	for name, file := range staged {
@@ -193,31 +328,74 @@ func synchronize(ctx context.Context, outputDir string, specs []sourceSpec) (boo
 	}
 	manifestData, err := json.MarshalIndent(m, "", "  ")
 	if err != nil {
-		return false, fmt.Errorf("encode manifest: %w", err)
+		return nil, fmt.Errorf("encode manifest: %w", err)
 	}
 	manifestData = append(manifestData, '\n')
 	if err := validateManifest(manifestData, staged); err != nil {
-		return false, fmt.Errorf("validate manifest: %w", err)
+		return nil, fmt.Errorf("validate manifest: %w", err)
+	}
+	report.Changed = true
+	if options.DryRun {
+		return report, nil
 	}
 
-	if err := commitSnapshot(outputDir, staged, manifestData, nil); err != nil {
-		return false, err
+	if err := os.MkdirAll(outputDir, 0o755); err != nil {
+		return nil, fmt.Errorf("create output directory: %w", err)
 	}
-	return true, nil
+	var signature []byte
+	if options.SignKey != nil {
+		signature = datarepo.SignManifest(options.SignKey, manifestData)
+	}
+	if err := commitSnapshot(outputDir, staged, manifestData, signature, nil); err != nil {
+		return nil, err
+	}
+	return report, tracker.save(outputDir, staged)
 }
 
-func stageRemoteSource(ctx context.Context, spec sourceSpec) (stagedFile, error) {
-	raw, err := fetch(ctx, spec.url)
-	if err != nil {
-		return stagedFile{}, fmt.Errorf("fetch: %w", err)
+// refreshSignature signs an unchanged manifest whose signature is missing or
+// made with another key, so adding -sign-key does not wait for a data change.
+func refreshSignature(outputDir string, key ed25519.PrivateKey) (bool, error) {
+	if key == nil {
+		return false, nil
 	}
-	value, count, err := spec.transform(raw)
+	manifestData, err := os.ReadFile(filepath.Join(outputDir, "manifest.json"))
 	if err != nil {
-		return stagedFile{}, fmt.Errorf("parse: %w", err)
+		return false, fmt.Errorf("read current manifest: %w", err)
 	}
-	if count < spec.minimum {
-		return stagedFile{}, fmt.Errorf("got %d records, require at least %d", count, spec.minimum)
+	signaturePath := filepath.Join(outputDir, datarepo.ManifestSignatureName)
+	if current, err := os.ReadFile(signaturePath); err == nil && datarepo.VerifyManifestSignature(key.Public().(ed25519.PublicKey), manifestData, current) == nil {
+		return false, nil
+	}
+	if err := atomicWrite(signaturePath, datarepo.SignManifest(key, manifestData)); err != nil {
+		return false, fmt.Errorf("write %s: %w", datarepo.ManifestSignatureName, err)
+	}
+	return true, nil
+}
+
+func stageRemoteSource(ctx context.Context, spec sourceSpec, tracker *healthTracker) (stagedFile, error) {
+	var value any
+	var count int
+	var source string
+	var failures error
+	for _, location := range spec.locations() {
+		var err error
+		value, count, err = readSource(ctx, spec, location)
+		if err == nil {
+			source = location
+			break
+		}
+		if len(spec.locations()) == 1 {
+			return stagedFile{}, err
+		}
+		failures = errors.Join(failures, fmt.Errorf("%s: %w", location, err))
 	}
+	if source == "" {
+		return stagedFile{}, failures
+	}
+	// Health probes test the parsed endpoints rather than the list mirror,
+	// so they run once on the first list that parses.
+	var err error
+	var history map[string]nodeHealth
 	if spec.health != nil {
 		value, err = spec.health(ctx, value)
 		if err != nil {
@@ -230,6 +408,7 @@ func stageRemoteSource(ctx context.Context, spec sourceSpec) (stagedFile, error)
 		if count < spec.minimum {
 			return stagedFile{}, fmt.Errorf("health check retained %d records, require at least %d", count, spec.minimum)
 		}
+		value, history = tracker.apply(spec.file, value)
 	}
 	encoded, err := json.MarshalIndent(value, "", "  ")
 	if err != nil {
@@ -239,7 +418,53 @@ func stageRemoteSource(ctx context.Context, spec sourceSpec) (stagedFile, error)
 	if err := validateStagedSource(spec, encoded, count); err != nil {
 		return stagedFile{}, err
 	}
-	return stagedFile{data: encoded, count: count, source: spec.url}, nil
+	return stagedFile{data: encoded, count: count, source: source, health: history}, nil
+}
+
+func (spec sourceSpec) locations() []string {
+	if spec.path != "" {
+		return []string{spec.path}
+	}
+	return append([]string{spec.url}, spec.fallbacks...)
+}
+
+// readSource loads and parses one location of spec, enforcing its minimum.
+func readSource(ctx context.Context, spec sourceSpec, location string) (any, int, error) {
+	var raw []byte
+	var err error
+	if spec.path != "" {
+		raw, err = readLocalSource(location)
+	} else {
+		raw, err = fetch(ctx, location)
+	}
+	if err != nil {
+		return nil, 0, fmt.Errorf("fetch: %w", err)
+	}
+	value, count, err := spec.transform(raw)
+	if err != nil {
+		return nil, 0, fmt.Errorf("parse: %w", err)
+	}
+	if count < spec.minimum {
+		return nil, 0, fmt.Errorf("got %d records, require at least %d", count, spec.minimum)
+	}
+	return value, count, nil
+}
+
+// readLocalSource applies the same size and emptiness limits as fetch.
+func readLocalSource(path string) ([]byte, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	data, err := io.ReadAll(io.LimitReader(file, 8<<20))
+	if err != nil {
+		return nil, err
+	}
+	if len(bytes.TrimSpace(data)) == 0 {
+		return nil, errors.New("empty file")
+	}
+	return data, nil
 }
 
 func loadCurrentSource(outputDir string, spec sourceSpec) (stagedFile, error) {
@@ -385,14 +610,19 @@ func validateCountDrops(dir string, staged map[string]stagedFile, maximumDrop fl
 		if !ok || previous.Count <= 0 || file.count >= previous.Count {
 			continue
 		}
-		minimumAllowed := int(math.Ceil(float64(previous.Count) * (1 - maximumDrop)))
-		if file.count < minimumAllowed {
+		if file.count < minimumAfterDrop(previous.Count, maximumDrop) {
 			return fmt.Errorf("%s dropped from %d to %d records", name, previous.Count, file.count)
 		}
 	}
 	return nil
 }
 
+// minimumAfterDrop is the smallest count the drop guards accept after
+// previous records when at most maximumDrop of them may disappear.
+func minimumAfterDrop(previous int, maximumDrop float64) int {
+	return int(math.Ceil(float64(previous) * (1 - maximumDrop)))
+}
+
 func supportedCurrentSchema(schema string) bool {
 	return schema == schemaVersion || schema == legacySchemaVersion
 }
@@ -402,39 +632,48 @@ func supportedCurrentSchema(schema string) bool {
 // are not sufficient because a large registry could otherwise collapse to a
 // handful of endpoints and still pass.
 func validateAvailabilityDrops(dir string, staged map[string]stagedFile, maximumDrop float64) error {
+	for _, name := range availabilityFiles {
+		if err := validateAvailabilityDrop(dir, name, staged, maximumDrop); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// availabilityFiles are the health-checked outputs that carry a status field.
+var availabilityFiles = []string{"speedtest-servers.json", "openspeedtest-servers.json"}
+
+func validateAvailabilityDrop(dir, name string, staged map[string]stagedFile, maximumDrop float64) error {
 	if maximumDrop < 0 || maximumDrop >= 1 {
 		return errors.New("maximum availability drop must be between zero and one")
 	}
-	for _, name := range []string{"speedtest-servers.json", "openspeedtest-servers.json"} {
-		currentData, err := os.ReadFile(filepath.Join(dir, name))
-		if errors.Is(err, os.ErrNotExist) {
-			continue
-		}
-		if err != nil {
-			return err
-		}
-		previousAvailable, previousHasStatus, err := countAvailableRecords(currentData)
-		if err != nil {
-			return fmt.Errorf("decode current %s: %w", name, err)
-		}
-		if !previousHasStatus || previousAvailable == 0 {
-			continue
-		}
-		candidate, ok := staged[name]
-		if !ok {
-			return fmt.Errorf("staged snapshot is missing %s", name)
-		}
-		currentAvailable, currentHasStatus, err := countAvailableRecords(candidate.data)
-		if err != nil {
-			return fmt.Errorf("decode staged %s: %w", name, err)
-		}
-		if !currentHasStatus {
-			return fmt.Errorf("staged %s has no availability status", name)
-		}
-		minimumAllowed := int(math.Ceil(float64(previousAvailable) * (1 - maximumDrop)))
-		if currentAvailable < minimumAllowed {
-			return fmt.Errorf("%s available nodes dropped from %d to %d", name, previousAvailable, currentAvailable)
-		}
+	currentData, err := os.ReadFile(filepath.Join(dir, name))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	previousAvailable, previousHasStatus, err := countAvailableRecords(currentData)
+	if err != nil {
+		return fmt.Errorf("decode current %s: %w", name, err)
+	}
+	if !previousHasStatus || previousAvailable == 0 {
+		return nil
+	}
+	candidate, ok := staged[name]
+	if !ok {
+		return fmt.Errorf("staged snapshot is missing %s", name)
+	}
+	currentAvailable, currentHasStatus, err := countAvailableRecords(candidate.data)
+	if err != nil {
+		return fmt.Errorf("decode staged %s: %w", name, err)
+	}
+	if !currentHasStatus {
+		return fmt.Errorf("staged %s has no availability status", name)
+	}
+	if currentAvailable < minimumAfterDrop(previousAvailable, maximumDrop) {
+		return fmt.Errorf("%s available nodes dropped from %d to %d", name, previousAvailable, currentAvailable)
 	}
 	return nil
 }
@@ -913,6 +1152,41 @@ func parseASNMap(data []byte) (any, int, error) {
 	return result, len(result), nil
 }
 
+// parseCPUStatistics keeps ranked models with at least one score, one record
+// per model (the best ranked), ordered by rank.
+func parseCPUStatistics(data []byte) (any, int, error) {
+	var input struct {
+		CPUStatistics []cpuStat `json:"cpu_statistics"`
+	}
+	if err := json.Unmarshal(data, &input); err != nil {
+		return nil, 0, err
+	}
+	best := make(map[string]cpuStat, len(input.CPUStatistics))
+	for _, item := range input.CPUStatistics {
+		item.CPUModel = strings.TrimSpace(item.CPUModel)
+		item.CPUPrefix = strings.TrimSpace(item.CPUPrefix)
+		if item.CPUModel == "" || item.Rank <= 0 || (item.MaxSingle <= 0 && item.MaxMulti <= 0) {
+			continue
+		}
+		key := strings.ToLower(item.CPUModel)
+		if previous, exists := best[key]; exists && previous.Rank <= item.Rank {
+			continue
+		}
+		best[key] = item
+	}
+	result := make([]cpuStat, 0, len(best))
+	for _, item := range best {
+		result = append(result, item)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].Rank != result[j].Rank {
+			return result[i].Rank < result[j].Rank
+		}
+		return strings.ToLower(result[i].CPUModel) < strings.ToLower(result[j].CPUModel)
+	})
+	return result, len(result), nil
+}
+
 func parseTransferTargets(data []byte) (any, int, error) {
 	var input []struct {
 		Code        int    `json:"code"`
@@ -1104,12 +1378,13 @@ type snapshotBackup struct {
 }
 
 // commitSnapshot writes all payloads, removes obsolete generated JSON files,
-// and writes the manifest last. Every touched file participates in rollback.
-func commitSnapshot(outputDir string, staged map[string]stagedFile, manifestData []byte, writer func(string, []byte) error) error {
-	return commitSnapshotWithRemove(outputDir, staged, manifestData, writer, nil)
+// and writes the manifest and then its signature last; a nil signature
+// removes the previous one. Every touched file participates in rollback.
+func commitSnapshot(outputDir string, staged map[string]stagedFile, manifestData, signature []byte, writer func(string, []byte) error) error {
+	return commitSnapshotWithRemove(outputDir, staged, manifestData, signature, writer, nil)
 }
 
-func commitSnapshotWithRemove(outputDir string, staged map[string]stagedFile, manifestData []byte, writer func(string, []byte) error, remover func(string) error) error {
+func commitSnapshotWithRemove(outputDir string, staged map[string]stagedFile, manifestData, signature []byte, writer func(string, []byte) error, remover func(string) error) error {
 	if writer == nil {
 		writer = atomicWrite
 	}
@@ -1126,10 +1401,10 @@ func commitSnapshotWithRemove(outputDir string, staged map[string]stagedFile, ma
 		return err
 	}
 
-	names := make([]string, 0, len(payloadNames)+len(staleNames)+1)
+	names := make([]string, 0, len(payloadNames)+len(staleNames)+2)
 	names = append(names, payloadNames...)
 	names = append(names, staleNames...)
-	names = append(names, "manifest.json")
+	names = append(names, "manifest.json", datarepo.ManifestSignatureName)
 	backups := make(map[string]snapshotBackup, len(names))
 	for _, name := range names {
 		path := filepath.Join(outputDir, name)
@@ -1179,6 +1454,45 @@ func commitSnapshotWithRemove(outputDir string, staged map[string]stagedFile, ma
 	if err := writer(filepath.Join(outputDir, "manifest.json"), manifestData); err != nil {
 		return rollback("write", "manifest.json", err)
 	}
+	signaturePath := filepath.Join(outputDir, datarepo.ManifestSignatureName)
+	if signature != nil {
+		if err := writer(signaturePath, signature); err != nil {
+			return rollback("write", datarepo.ManifestSignatureName, err)
+		}
+	} else if err := remover(signaturePath); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return rollback("remove", datarepo.ManifestSignatureName, err)
+	}
+	return nil
+}
+
+// keepUnlistedKnownFiles stages the current copy of every goecs data file a
+// sources file does not list, so a partial sources file updates its own
+// entries without deleting the rest of the snapshot from disk and manifest.
+func keepUnlistedKnownFiles(outputDir string, staged map[string]stagedFile) error {
+	manifestData, err := os.ReadFile(filepath.Join(outputDir, "manifest.json"))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("read current manifest: %w", err)
+	}
+	var current manifest
+	if err := json.Unmarshal(manifestData, &current); err != nil {
+		return fmt.Errorf("decode current manifest: %w", err)
+	}
+	for _, name := range datarepo.KnownFiles() {
+		if _, listed := staged[name]; listed {
+			continue
+		}
+		if _, tracked := current.Files[name]; !tracked {
+			continue
+		}
+		file, err := loadCurrentSource(outputDir, sourceSpec{name: name, file: name, minimum: 1, validateOutput: outputSchemas[name]})
+		if err != nil {
+			return fmt.Errorf("keep unlisted %s: %w", name, err)
+		}
+		staged[name] = file
+	}
 	return nil
 }
 
@@ -3,6 +3,8 @@ package datasync
 import (
 	"bytes"
 	"context"
+	"crypto/ed25519"
+	"crypto/rand"
 	"crypto/sha256"
 	"encoding/hex"
 	"encoding/json"
@@ -13,8 +15,11 @@ import (
 	"net/http/httptest"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 	"time"
+
+	datarepo "github.com/oneclickvirt/ecs/internal/data"
 )
 
 func TestParseTCPTargets(t *testing.T) {
@@ -128,7 +133,7 @@ func TestSynchronizeFirstRunAndSemanticNoOp(t *testing.T) {
 
 	dir := filepath.Join(t.TempDir(), "data")
 	specs := []sourceSpec{{name: "fixture", file: "fixture.json", url: server.URL, minimum: 2, transform: passJSONArray}}
-	changed, err := synchronize(context.Background(), dir, specs)
+	changed, err := synchronize(context.Background(), dir, specs, Options{})
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -155,7 +160,7 @@ func TestSynchronizeFirstRunAndSemanticNoOp(t *testing.T) {
 		t.Fatal("manifest SHA-256 does not match generated data")
 	}
 
-	changed, err = synchronize(context.Background(), dir, specs)
+	changed, err = synchronize(context.Background(), dir, specs, Options{})
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -183,7 +188,7 @@ func TestSynchronizeRemovesSourceNoLongerInManifest(t *testing.T) {
 		{name: "current", file: "current.json", url: server.URL, minimum: 1, transform: passJSONArray},
 		{name: "obsolete", file: "obsolete.json", url: server.URL, minimum: 1, transform: passJSONArray},
 	}
-	if changed, err := synchronize(context.Background(), dir, initial); err != nil || !changed {
+	if changed, err := synchronize(context.Background(), dir, initial, Options{}); err != nil || !changed {
 		t.Fatalf("write initial snapshot: changed=%v err=%v", changed, err)
 	}
 	untouched := []string{"local-config.yaml", "local-config.json"}
@@ -194,7 +199,7 @@ func TestSynchronizeRemovesSourceNoLongerInManifest(t *testing.T) {
 	}
 
 	payload = []byte(`[{"id":"updated"}]`)
-	if changed, err := synchronize(context.Background(), dir, initial[:1]); err != nil || !changed {
+	if changed, err := synchronize(context.Background(), dir, initial[:1], Options{}); err != nil || !changed {
 		t.Fatalf("write reduced snapshot: changed=%v err=%v", changed, err)
 	}
 	if _, err := os.Stat(filepath.Join(dir, "obsolete.json")); !errors.Is(err, os.ErrNotExist) {
@@ -222,6 +227,49 @@ func TestSynchronizeRemovesSourceNoLongerInManifest(t *testing.T) {
 	}
 }
 
+func TestSynchronizeKeepsKnownFilesMissingFromSources(t *testing.T) {
+	payloads := map[string][]byte{
+		"/tcp":   []byte(`[{"id":"one","name":"One","host":"one.example","port":443,"category":"global"}]`),
+		"/extra": []byte(`[{"id":"one"}]`),
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, request *http.Request) {
+		_, _ = w.Write(payloads[request.URL.Path])
+	}))
+	defer server.Close()
+
+	dir := t.TempDir()
+	full := []sourceSpec{
+		{name: "tcpbench", file: "tcp-targets.json", url: server.URL + "/tcp", minimum: 1, transform: passJSONArray, validateOutput: outputSchemas["tcp-targets.json"]},
+		{name: "extra", file: "extra.json", url: server.URL + "/extra", minimum: 1, transform: passJSONArray},
+	}
+	if changed, err := synchronize(context.Background(), dir, full, Options{}); err != nil || !changed {
+		t.Fatalf("write initial snapshot: changed=%v err=%v", changed, err)
+	}
+	before, err := os.ReadFile(filepath.Join(dir, "tcp-targets.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	payloads["/extra"] = []byte(`[{"id":"two"}]`)
+	if changed, err := synchronize(context.Background(), dir, full[1:], Options{}); err != nil || !changed {
+		t.Fatalf("write partial snapshot: changed=%v err=%v", changed, err)
+	}
+	after, err := os.ReadFile(filepath.Join(dir, "tcp-targets.json"))
+	if err != nil || !bytes.Equal(before, after) {
+		t.Fatalf("unlisted known file changed: data=%q err=%v", after, err)
+	}
+	manifestData, err := os.ReadFile(filepath.Join(dir, "manifest.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var current manifest
+	if err := json.Unmarshal(manifestData, &current); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := current.Files["tcp-targets.json"]; !ok || len(current.Files) != 2 {
+		t.Fatalf("manifest dropped the unlisted known file: %+v", current.Files)
+	}
+}
+
 func TestSynchronizeDoesNotOverwriteOnQuantityDrop(t *testing.T) {
 	payload := []byte(`[{"id":1},{"id":2},{"id":3},{"id":4},{"id":5},{"id":6},{"id":7},{"id":8},{"id":9},{"id":10}]`)
 	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
@@ -231,7 +279,7 @@ func TestSynchronizeDoesNotOverwriteOnQuantityDrop(t *testing.T) {
 
 	dir := t.TempDir()
 	specs := []sourceSpec{{name: "fixture", file: "fixture.json", url: server.URL, minimum: 1, transform: passJSONArray}}
-	if _, err := synchronize(context.Background(), dir, specs); err != nil {
+	if _, err := synchronize(context.Background(), dir, specs, Options{}); err != nil {
 		t.Fatal(err)
 	}
 	beforeData, err := os.ReadFile(filepath.Join(dir, "fixture.json"))
@@ -244,7 +292,7 @@ func TestSynchronizeDoesNotOverwriteOnQuantityDrop(t *testing.T) {
 	}
 
 	payload = []byte(`[{"id":1},{"id":2},{"id":3},{"id":4},{"id":5},{"id":6}]`)
-	if _, err := synchronize(context.Background(), dir, specs); err == nil {
+	if _, err := synchronize(context.Background(), dir, specs, Options{}); err == nil {
 		t.Fatal("expected a 40 percent quantity drop to be rejected")
 	}
 	afterData, err := os.ReadFile(filepath.Join(dir, "fixture.json"))
@@ -277,7 +325,7 @@ func TestSynchronizeRejectsEncodedSchemaOrCountMismatch(t *testing.T) {
 		t.Run(test.name, func(t *testing.T) {
 			dir := t.TempDir()
 			specs := []sourceSpec{{name: test.name, file: "fixture.json", url: server.URL, minimum: 1, transform: test.transform}}
-			if _, err := synchronize(context.Background(), dir, specs); err == nil {
+			if _, err := synchronize(context.Background(), dir, specs, Options{}); err == nil {
 				t.Fatal("expected invalid generated schema to fail")
 			}
 			if _, err := os.Stat(filepath.Join(dir, "manifest.json")); !os.IsNotExist(err) {
@@ -322,14 +370,23 @@ func TestCommittedDataMatchesManifest(t *testing.T) {
 	if err := json.Unmarshal(manifestData, &current); err != nil {
 		t.Fatal(err)
 	}
-	specs := defaultSourceSpecs()
-	if len(current.Files) != len(specs) {
-		t.Fatalf("manifest has %d files, want %d", len(current.Files), len(specs))
+	// Known files the snapshot does not list yet have not been committed by a
+	// sync run; every other source must be present and meet its minimum.
+	pending := make(map[string]bool)
+	for _, name := range datarepo.KnownFiles() {
+		pending[name] = true
+	}
+	for _, name := range datarepo.SnapshotFiles() {
+		delete(pending, name)
 	}
+	specs := defaultSourceSpecs()
 	staged := make(map[string]stagedFile, len(specs))
 	for _, spec := range specs {
 		meta, ok := current.Files[spec.file]
 		if !ok {
+			if pending[spec.file] {
+				continue
+			}
 			t.Fatalf("manifest is missing %s", spec.file)
 		}
 		if meta.Source != spec.url || meta.Count < spec.minimum {
@@ -376,6 +433,7 @@ func TestDefaultOutputSchemasRejectEmptyFieldsAndTypeDrift(t *testing.T) {
 		"dnsbl-zones.json":           []byte(`[{"zone":"dnsbl.example","ipv6":true}]`),
 		"bgp-asn-map.json":           []byte(`[{"asn":64500,"name":""}]`),
 		"media-providers.json":       []byte(`[{"id":"one","name":"One","unexpected":"field"}]`),
+		"cpu-stats.json":             []byte(`[{"cpu_prefix":"","cpu_model":"One","sample_count":1,"max_single_score":0,"max_multi_score":0,"avg_single_score":0,"avg_multi_score":0,"rank":1,"typical_cores":1,"typical_threads":1}]`),
 	}
 	for _, spec := range defaultSourceSpecs() {
 		t.Run(spec.file, func(t *testing.T) {
@@ -407,7 +465,7 @@ func TestSynchronizeDoesNotOverwriteOnOutputSchemaDrift(t *testing.T) {
 		name: "fixture", file: "tcp-targets.json", url: server.URL, minimum: 1,
 		transform: transform, validateOutput: validateTCPTargetSchema,
 	}}
-	if _, err := synchronize(context.Background(), dir, specs); err != nil {
+	if _, err := synchronize(context.Background(), dir, specs, Options{}); err != nil {
 		t.Fatal(err)
 	}
 	beforeData, err := os.ReadFile(filepath.Join(dir, "tcp-targets.json"))
@@ -419,7 +477,7 @@ func TestSynchronizeDoesNotOverwriteOnOutputSchemaDrift(t *testing.T) {
 		t.Fatal(err)
 	}
 	payload = []byte(`[{"id":"one","name":"","host":"one.example","port":443,"category":"global"}]`)
-	changed, err := synchronize(context.Background(), dir, specs)
+	changed, err := synchronize(context.Background(), dir, specs, Options{})
 	if err != nil || changed {
 		t.Fatalf("schema drift should reuse the current valid snapshot: changed=%v err=%v", changed, err)
 	}
@@ -449,13 +507,13 @@ func TestSynchronizeUpdatesHealthySourceWhileReusingFailedSource(t *testing.T) {
 		{name: "failed", file: "failed.json", url: server.URL + "/failed", minimum: 1, transform: transform, validateOutput: validateTCPTargetSchema},
 	}
 	dir := t.TempDir()
-	if changed, err := synchronize(context.Background(), dir, specs); err != nil || !changed {
+	if changed, err := synchronize(context.Background(), dir, specs, Options{}); err != nil || !changed {
 		t.Fatalf("initial sync failed: changed=%v err=%v", changed, err)
 	}
 	failedBefore, _ := os.ReadFile(filepath.Join(dir, "failed.json"))
 	payloads["/healthy"] = []byte(`[{"id":"updated","name":"Updated","host":"updated.example","port":443,"category":"global"}]`)
 	payloads["/failed"] = []byte(`[{"id":"two","name":"","host":"two.example","port":443,"category":"global"}]`)
-	if changed, err := synchronize(context.Background(), dir, specs); err != nil || !changed {
+	if changed, err := synchronize(context.Background(), dir, specs, Options{}); err != nil || !changed {
 		t.Fatalf("partial source refresh failed: changed=%v err=%v", changed, err)
 	}
 	failedAfter, _ := os.ReadFile(filepath.Join(dir, "failed.json"))
@@ -622,6 +680,30 @@ func TestParseTransferTargetsDeduplicatesAndValidatesPorts(t *testing.T) {
 	}
 }
 
+func TestParseCPUStatisticsKeepsBestRankPerModel(t *testing.T) {
+	value, count, err := parseCPUStatistics([]byte(`{"cpu_statistics":[
+		{"cpu_model":"Intel Xeon E5-2680 v4","rank":40,"max_single_score":900,"max_multi_score":12000},
+		{"cpu_model":" AMD EPYC 7763 ","rank":3,"max_multi_score":80000},
+		{"cpu_model":"intel xeon e5-2680 v4","rank":20,"max_single_score":950},
+		{"cpu_model":"Unscored","rank":5},
+		{"cpu_model":"","rank":1,"max_single_score":100}
+	]}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	stats := value.([]cpuStat)
+	if count != 2 || stats[0].CPUModel != "AMD EPYC 7763" || stats[1].Rank != 20 {
+		t.Fatalf("unexpected CPU statistics: %#v", stats)
+	}
+	encoded, err := json.Marshal(stats)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := validateCPUStatsSchema(encoded); err != nil {
+		t.Fatal(err)
+	}
+}
+
 func TestParseProviderNamesIgnoresCommentsAndUsesAST(t *testing.T) {
 	value, count, err := parseProviderNames([]byte(`package providers
 	type TestItem struct{}
@@ -711,7 +793,7 @@ func TestCommitSnapshotRollsBackWriteFailure(t *testing.T) {
 		}
 		return atomicWrite(path, data)
 	}
-	if err := commitSnapshot(dir, staged, []byte("new-manifest\n"), writer); err == nil {
+	if err := commitSnapshot(dir, staged, []byte("new-manifest\n"), nil, writer); err == nil {
 		t.Fatal("expected injected write failure")
 	}
 	for name, want := range paths {
@@ -748,7 +830,7 @@ func TestCommitSnapshotRollsBackRemoveFailure(t *testing.T) {
 		}
 		return os.Remove(path)
 	}
-	if err := commitSnapshotWithRemove(dir, staged, []byte("new-manifest\n"), nil, remover); err == nil {
+	if err := commitSnapshotWithRemove(dir, staged, []byte("new-manifest\n"), nil, nil, remover); err == nil {
 		t.Fatal("expected injected remove failure")
 	}
 	for name, want := range paths {
@@ -774,3 +856,414 @@ func mustEncodeManifest(t *testing.T, names ...string) []byte {
 	}
 	return append(encoded, '\n')
 }
+
+func TestValidateTCPTargetsAllowsUnsortedUniqueIDs(t *testing.T) {
+	unsorted := []byte(`[{"id":"zeta","name":"Zeta","host":"zeta.test","port":443,"category":"custom"},{"id":"alpha","name":"Alpha","host":"alpha.test","port":443,"category":"custom"}]`)
+	if err := ValidateTCPTargets(unsorted); err != nil {
+		t.Fatal(err)
+	}
+	if err := validateTCPTargetSchema(unsorted); err == nil {
+		t.Fatal("snapshot schema accepted unsorted IDs")
+	}
+	duplicate := []byte(`[{"id":"same","name":"A","host":"a.test","port":443,"category":"custom"},{"id":"same","name":"B","host":"b.test","port":443,"category":"custom"}]`)
+	if err := ValidateTCPTargets(duplicate); err == nil {
+		t.Fatal("duplicate custom IDs were accepted")
+	}
+}
+
+func TestSynchronizeSignsManifest(t *testing.T) {
+	payload := []byte(`[{"id":"one"}]`)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		_, _ = w.Write(payload)
+	}))
+	defer server.Close()
+	_, key, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	dir := filepath.Join(t.TempDir(), "data")
+	specs := []sourceSpec{{name: "fixture", file: "fixture.json", url: server.URL, minimum: 1, transform: passJSONArray}}
+	if changed, err := synchronize(context.Background(), dir, specs, Options{}); err != nil || !changed {
+		t.Fatalf("unsigned first run changed=%v err=%v", changed, err)
+	}
+	signaturePath := filepath.Join(dir, datarepo.ManifestSignatureName)
+	if _, err := os.Stat(signaturePath); !errors.Is(err, os.ErrNotExist) {
+		t.Fatalf("unsigned run wrote a signature: %v", err)
+	}
+
+	// Unchanged data is still signed once a key is supplied.
+	if changed, err := synchronize(context.Background(), dir, specs, Options{SignKey: key}); err != nil || !changed {
+		t.Fatalf("signing unchanged data changed=%v err=%v", changed, err)
+	}
+	manifestData, err := os.ReadFile(filepath.Join(dir, "manifest.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	signature, err := os.ReadFile(signaturePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := datarepo.VerifyManifestSignature(key.Public().(ed25519.PublicKey), manifestData, signature); err != nil {
+		t.Fatal(err)
+	}
+	if changed, err := synchronize(context.Background(), dir, specs, Options{SignKey: key}); err != nil || changed {
+		t.Fatalf("valid signature was rewritten: changed=%v err=%v", changed, err)
+	}
+
+	// A rewritten manifest without a key must not keep the stale signature.
+	payload = []byte(`[{"id":"one"},{"id":"two"}]`)
+	if changed, err := synchronize(context.Background(), dir, specs, Options{}); err != nil || !changed {
+		t.Fatalf("unsigned update changed=%v err=%v", changed, err)
+	}
+	if _, err := os.Stat(signaturePath); !errors.Is(err, os.ErrNotExist) {
+		t.Fatalf("stale signature survived a manifest rewrite: %v", err)
+	}
+}
+
+func TestParseSourceSpecsAcceptsYAMLAndJSON(t *testing.T) {
+	yamlSpecs, err := parseSourceSpecs([]byte(`
+- kind: speedtest
+  file: speedtest-servers.json
+  url: https://mirror.internal/speedtest_cn.json
+  fallback_urls:
+    - https://raw.githubusercontent.com/xykt/NetQuality/main/ref/speedtest_cn.json
+  minimum: 10
+  health: false
+  max_drop: 0.2
+- name: extra
+  kind: json
+  file: extra.json
+  path: testdata/extra.json
+  minimum: 1
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	speedtest := yamlSpecs[0]
+	if speedtest.name != "speedtest" || speedtest.health != nil || speedtest.maxDrop != 0.2 || len(speedtest.fallbacks) != 1 || speedtest.validateOutput == nil {
+		t.Fatalf("unexpected speedtest spec: %+v", speedtest)
+	}
+	if extra := yamlSpecs[1]; extra.path != "testdata/extra.json" || extra.maximumDrop() != defaultMaxDrop {
+		t.Fatalf("unexpected local spec: %+v", extra)
+	}
+	jsonSpecs, err := parseSourceSpecs([]byte(`[{"kind":"dnsbl","file":"dnsbl-zones.json","url":"https://mirror.internal/dnsbl.list","minimum":100}]`))
+	if err != nil || len(jsonSpecs) != 1 || jsonSpecs[0].health == nil {
+		t.Fatalf("JSON sources = %+v err=%v", jsonSpecs, err)
+	}
+}
+
+func TestParseSourceSpecsValidatesKnownOutputFiles(t *testing.T) {
+	for _, name := range datarepo.KnownFiles() {
+		if outputSchemas[name] == nil {
+			t.Errorf("%s has no output schema", name)
+		}
+	}
+	specs, err := parseSourceSpecs([]byte(`[{"name":"mirror","kind":"json","file":"cpu-stats.json","url":"https://mirror.internal/cpu.json","minimum":1}]`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if specs[0].validateOutput == nil {
+		t.Fatal("json source writing cpu-stats.json has no output schema")
+	}
+	if err := specs[0].validateOutput([]byte(`[{"cpu_model":""}]`)); err == nil {
+		t.Fatal("json source accepted an invalid cpu-stats.json")
+	}
+}
+
+func TestParseSourceSpecsRejectsInvalidEntries(t *testing.T) {
+	for name, input := range map[string]string{
+		"empty list":        `[]`,
+		"unknown kind":      `[{"kind":"rss","file":"a.json","url":"https://a.test","minimum":1}]`,
+		"unknown field":     `[{"kind":"json","file":"a.json","url":"https://a.test","minimum":1,"minimun":2}]`,
+		"no location":       `[{"kind":"json","file":"a.json","minimum":1}]`,
+		"url and path":      `[{"kind":"json","file":"a.json","url":"https://a.test","path":"a","minimum":1}]`,
+		"bad fallback":      `[{"kind":"json","file":"a.json","url":"https://a.test","fallback_urls":["ftp://a.test"],"minimum":1}]`,
+		"no minimum":        `[{"kind":"json","file":"a.json","url":"https://a.test"}]`,
+		"max drop too high": `[{"kind":"json","file":"a.json","url":"https://a.test","minimum":1,"max_drop":1}]`,
+		"health on no-op":   `[{"kind":"json","file":"a.json","url":"https://a.test","minimum":1,"health":true}]`,
+		"duplicate names":   `[{"kind":"json","file":"a.json","url":"https://a.test","minimum":1},{"kind":"json","file":"b.json","url":"https://b.test","minimum":1}]`,
+	} {
+		if _, err := parseSourceSpecs([]byte(input)); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+}
+
+func TestSynchronizeConfiguredSourcesUseFallbacksAndLocalFiles(t *testing.T) {
+	broken := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusBadGateway)
+	}))
+	defer broken.Close()
+	mirror := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		_, _ = w.Write([]byte(`[{"id":"one"},{"id":"two"}]`))
+	}))
+	defer mirror.Close()
+	local := filepath.Join(t.TempDir(), "local.json")
+	if err := os.WriteFile(local, []byte(`[{"id":"three"}]`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	sources := filepath.Join(t.TempDir(), "sources.yaml")
+	config := fmt.Sprintf(`
+- name: remote
+  kind: json
+  file: remote.json
+  url: %s
+  fallback_urls: [%s]
+  minimum: 2
+- name: local
+  kind: json
+  file: local.json
+  path: %s
+  minimum: 1
+`, broken.URL, mirror.URL, local)
+	if err := os.WriteFile(sources, []byte(config), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	dir := filepath.Join(t.TempDir(), "data")
+	if changed, err := SyncWithOptions(context.Background(), dir, Options{SourcesFile: sources}); err != nil || !changed {
+		t.Fatalf("changed=%v err=%v", changed, err)
+	}
+	manifestData, err := os.ReadFile(filepath.Join(dir, "manifest.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var generated manifest
+	if err := json.Unmarshal(manifestData, &generated); err != nil {
+		t.Fatal(err)
+	}
+	if generated.Files["remote.json"].Source != mirror.URL || generated.Files["remote.json"].Count != 2 {
+		t.Fatalf("remote entry should come from the fallback: %+v", generated.Files["remote.json"])
+	}
+	if generated.Files["local.json"].Source != local || generated.Files["local.json"].Count != 1 {
+		t.Fatalf("unexpected local entry: %+v", generated.Files["local.json"])
+	}
+
+	duplicate := strings.Replace(config, "file: local.json", "file: remote.json", 1)
+	if err := os.WriteFile(sources, []byte(duplicate), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := SyncWithOptions(context.Background(), dir, Options{SourcesFile: sources}); err == nil || !strings.Contains(err.Error(), "duplicate output file") {
+		t.Fatalf("expected duplicate output file rejection, got %v", err)
+	}
+}
+
+func TestSynchronizeAppliesPerSourceMaximumDrop(t *testing.T) {
+	payload := []byte(`[{"id":"1"},{"id":"2"},{"id":"3"},{"id":"4"},{"id":"5"},{"id":"6"},{"id":"7"},{"id":"8"},{"id":"9"},{"id":"10"}]`)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		_, _ = w.Write(payload)
+	}))
+	defer server.Close()
+	dir := filepath.Join(t.TempDir(), "data")
+	specs := []sourceSpec{{name: "fixture", file: "fixture.json", url: server.URL, minimum: 1, transform: passJSONArray}}
+	if _, err := synchronize(context.Background(), dir, specs, Options{}); err != nil {
+		t.Fatal(err)
+	}
+	payload = []byte(`[{"id":"1"},{"id":"2"},{"id":"3"},{"id":"4"},{"id":"5"},{"id":"6"},{"id":"7"},{"id":"8"}]`)
+	specs[0].maxDrop = 0.1
+	if _, err := synchronize(context.Background(), dir, specs, Options{}); err == nil || !strings.Contains(err.Error(), "quantity guard") {
+		t.Fatalf("expected a 20%% drop to exceed a 10%% limit, got %v", err)
+	}
+	specs[0].maxDrop = 0
+	if changed, err := synchronize(context.Background(), dir, specs, Options{}); err != nil || !changed {
+		t.Fatalf("expected a 20%% drop within the default limit: changed=%v err=%v", changed, err)
+	}
+}
+
+func TestDryRunReportsRecordDiffWithoutWriting(t *testing.T) {
+	payload := []byte(`[{"zone":"a.example","ipv4":true},{"zone":"b.example","ipv4":true},{"zone":"c.example","ipv4":true}]`)
+	failing := false
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		if failing {
+			w.WriteHeader(http.StatusBadGateway)
+			return
+		}
+		_, _ = w.Write(payload)
+	}))
+	defer server.Close()
+	dir := filepath.Join(t.TempDir(), "data")
+	specs := []sourceSpec{
+		{name: "zones", file: "zones.json", url: server.URL, minimum: 1, transform: passJSONArray},
+	}
+	if _, err := synchronize(context.Background(), dir, specs, Options{}); err != nil {
+		t.Fatal(err)
+	}
+	before, err := os.ReadFile(filepath.Join(dir, "zones.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	payload = []byte(`[{"zone":"a.example","ipv4":false},{"zone":"b.example","ipv4":true},{"zone":"d.example","ipv4":true}]`)
+	report, err := synchronizeReport(context.Background(), dir, specs, Options{DryRun: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	after, _ := os.ReadFile(filepath.Join(dir, "zones.json"))
+	if !bytes.Equal(before, after) {
+		t.Fatal("dry run rewrote the snapshot")
+	}
+	file := report.Files[0]
+	if !report.Changed || !report.DryRun || strings.Join(file.Added, ",") != "d.example" || strings.Join(file.Removed, ",") != "c.example" || strings.Join(file.Changed, ",") != "a.example" {
+		t.Fatalf("unexpected report: %+v", report)
+	}
+	var text bytes.Buffer
+	if err := report.WriteText(&text); err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{"would change: 1 of 1 files", "records: 3 -> 3 (+0, guard minimum 2", "added (1): d.example"} {
+		if !strings.Contains(text.String(), want) {
+			t.Fatalf("report text missing %q:\n%s", want, text.String())
+		}
+	}
+
+	failing = true
+	report, err = synchronizeReport(context.Background(), dir, specs, Options{DryRun: true})
+	if err != nil || report.Changed || !strings.Contains(report.Files[0].Fallback, "HTTP 502") {
+		t.Fatalf("fallback report = %+v err=%v", report, err)
+	}
+}
+
+func TestReportRecordsGuardFailures(t *testing.T) {
+	payload := []byte(`[{"id":"1"},{"id":"2"},{"id":"3"},{"id":"4"}]`)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		_, _ = w.Write(payload)
+	}))
+	defer server.Close()
+	dir := filepath.Join(t.TempDir(), "data")
+	specs := []sourceSpec{{name: "fixture", file: "fixture.json", url: server.URL, minimum: 1, transform: passJSONArray}}
+	if _, err := synchronize(context.Background(), dir, specs, Options{}); err != nil {
+		t.Fatal(err)
+	}
+	payload = []byte(`[{"id":"1"}]`)
+	report, err := synchronizeReport(context.Background(), dir, specs, Options{DryRun: true})
+	if err == nil || !strings.Contains(err.Error(), "quantity guard") {
+		t.Fatalf("expected a quantity guard error, got %v", err)
+	}
+	if report == nil || len(report.Files[0].Guard) != 1 || report.Files[0].MinimumCount != 3 || len(report.Files[0].Removed) != 3 {
+		t.Fatalf("guard failure missing from report: %+v", report)
+	}
+}
+
+func TestHealthTrackerRequiresConsecutiveProbesToFlip(t *testing.T) {
+	tracker := &healthTracker{failures: 3, recoveries: 2, now: time.Unix(1700000000, 0).UTC()}
+	state := tracker.step(nodeHealth{}, true)
+	if state.Status != "available" || !state.UpSince.Equal(tracker.now) {
+		t.Fatalf("first probe should set the status directly: %+v", state)
+	}
+	for probe, want := range []string{"available", "available", "unavailable", "unavailable", "available"} {
+		up := probe >= 3
+		state = tracker.step(state, up)
+		if state.Status != want {
+			t.Fatalf("probe %d (up=%v) = %+v, want %s", probe+1, up, state, want)
+		}
+	}
+	state = tracker.step(state, false)
+	state = tracker.step(state, true)
+	if state.Status != "available" || state.Failures != 0 || state.Successes != 1 {
+		t.Fatalf("an interrupted failure streak must not flip the node: %+v", state)
+	}
+}
+
+func TestHealthTrackerSeedsNewNodesFromSnapshot(t *testing.T) {
+	dir := t.TempDir()
+	snapshot := `[{"id":"kept","status":"available"},{"host":"a.example","status":"available"},{"host":"b.example","status":"unavailable"}]`
+	if err := os.WriteFile(filepath.Join(dir, "nodes.json"), []byte(snapshot), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	tracker := loadHealthTracker(dir, Options{}, time.Unix(1700000000, 0).UTC())
+	nodes := []map[string]any{
+		{"id": "kept", "status": "unavailable"},
+		{"host": "a.example", "status": "unavailable"},
+		{"host": "b.example", "status": "available"},
+		{"id": "new", "status": "unavailable"},
+	}
+	_, history := tracker.apply("nodes.json", nodes)
+	for index, want := range []string{"available", "available", "unavailable", "unavailable"} {
+		if nodes[index]["status"] != want {
+			t.Fatalf("node %d = %v, want %s", index, nodes[index]["status"], want)
+		}
+	}
+	if len(history) != 4 || history["a.example"].Failures != 1 || history["b.example"].Successes != 1 {
+		t.Fatalf("nodes without an id must keep separate streaks: %+v", history)
+	}
+}
+
+func TestHealthKeysAreDistinct(t *testing.T) {
+	keys := healthKeys([]map[string]any{{"id": "x"}, {"id": "x"}, {"host": "h"}, {"id": nil, "name": "n"}, {}, {}})
+	want := []string{"x", "x#2", "h", "n", "node", "node#2"}
+	if strings.Join(keys, ",") != strings.Join(want, ",") {
+		t.Fatalf("healthKeys = %v, want %v", keys, want)
+	}
+}
+
+func TestSynchronizeKeepsFlakyNodesAvailableAcrossRuns(t *testing.T) {
+	payload := []byte(`[{"id":"a","host":"a"},{"id":"flaky","host":"b"},{"id":"c","host":"c"},{"id":"d","host":"d"}]`)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		_, _ = w.Write(payload)
+	}))
+	defer server.Close()
+	flakyUp := true
+	health := func(_ context.Context, value any) (any, error) {
+		servers := value.([]map[string]any)
+		for _, server := range servers {
+			server["status"] = "available"
+			if server["id"] == "flaky" && !flakyUp {
+				server["status"] = "unavailable"
+			}
+		}
+		return servers, nil
+	}
+	transform := func(data []byte) (any, int, error) {
+		var servers []map[string]any
+		err := json.Unmarshal(data, &servers)
+		return servers, len(servers), err
+	}
+	dir := filepath.Join(t.TempDir(), "data")
+	specs := []sourceSpec{{name: "speedtest", file: "speedtest-servers.json", url: server.URL, minimum: 1, transform: transform, health: health}}
+	options := Options{HealthFailures: 2, HealthRecoveries: 1}
+	statusOf := func() string {
+		var servers []map[string]any
+		data, err := os.ReadFile(filepath.Join(dir, "speedtest-servers.json"))
+		if err != nil || json.Unmarshal(data, &servers) != nil {
+			t.Fatalf("read snapshot: %v", err)
+		}
+		return fmt.Sprint(servers[1]["status"])
+	}
+	if _, err := synchronize(context.Background(), dir, specs, options); err != nil {
+		t.Fatal(err)
+	}
+	flakyUp = false
+	if changed, err := synchronize(context.Background(), dir, specs, options); err != nil || changed || statusOf() != "available" {
+		t.Fatalf("one failed probe flipped the node: changed=%v err=%v status=%s", changed, err, statusOf())
+	}
+	if changed, err := synchronize(context.Background(), dir, specs, options); err != nil || !changed || statusOf() != "unavailable" {
+		t.Fatalf("two failed probes should flip the node: changed=%v err=%v status=%s", changed, err, statusOf())
+	}
+	flakyUp = true
+	if changed, err := synchronize(context.Background(), dir, specs, options); err != nil || !changed || statusOf() != "available" {
+		t.Fatalf("one success should restore the node: changed=%v err=%v status=%s", changed, err, statusOf())
+	}
+	before, err := os.ReadFile(filepath.Join(dir, healthHistoryPath))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := synchronize(context.Background(), dir, specs, options); err != nil {
+		t.Fatal(err)
+	}
+	after, err := os.ReadFile(filepath.Join(dir, healthHistoryPath))
+	if err != nil || !bytes.Equal(before, after) {
+		t.Fatalf("an unchanged run rewrote the health history:\n%s\n%s", before, after)
+	}
+
+	var history healthHistory
+	data, err := os.ReadFile(filepath.Join(dir, healthHistoryPath))
+	if err != nil || json.Unmarshal(data, &history) != nil {
+		t.Fatalf("read health history: %v", err)
+	}
+	if flaky := history.Files["speedtest-servers.json"]["flaky"]; flaky.Status != "available" || flaky.Successes != 1 || flaky.UpSince.IsZero() {
+		t.Fatalf("unexpected history: %+v", history.Files)
+	}
+	manifestData, err := os.ReadFile(filepath.Join(dir, "manifest.json"))
+	if err != nil || bytes.Contains(manifestData, []byte("history")) {
+		t.Fatalf("health history leaked into the manifest: %s", manifestData)
+	}
+}
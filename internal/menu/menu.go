@@ -188,14 +188,41 @@ func applyMenuResult(preCheck utils.NetCheckResult, config *params.Config, resul
 		if config.SpeedTestStatus {
 			config.OnlyChinaTest = utils.CheckChina(config.EnableLogger, config.Language)
 		}
-		config.ValidateParams()
+		config.ValidateParams().Print(os.Stderr, config.Language)
 		return
 	}
 
 	config.Choice = result.choice
-	switch result.choice {
-	case "0":
+	if result.choice == "0" {
 		os.Exit(0)
+	}
+	setPresetStatus(preCheck, config, result.choice)
+	config.RestoreUserSetParams(savedParams)
+	config.AnalyzeResult = result.mainAnalyze
+	config.EnableUpload = result.mainUpload
+	if result.choice == "1" && config.SpeedTestStatus {
+		config.OnlyChinaTest = utils.CheckChina(config.EnableLogger, config.Language)
+	}
+	config.ValidateParams().Print(os.Stderr, config.Language)
+}
+
+// ApplyPreset applies menu option choice ("1" to "10") without the
+// interactive menu, as selected by -profile. Parameters set on the command
+// line or in a config file still take precedence over the preset.
+func ApplyPreset(preCheck utils.NetCheckResult, config *params.Config, choice string) {
+	savedParams := config.SaveUserSetParams()
+	resetMenuSelectedStatuses(config)
+	config.Choice = choice
+	setPresetStatus(preCheck, config, choice)
+	config.RestoreUserSetParams(savedParams)
+	if choice == "1" && config.SpeedTestStatus {
+		config.OnlyChinaTest = utils.CheckChina(config.EnableLogger, config.Language)
+	}
+	config.ValidateParams().Print(os.Stderr, config.Language)
+}
+
+func setPresetStatus(preCheck utils.NetCheckResult, config *params.Config, choice string) {
+	switch choice {
 	case "1":
 		SetFullTestStatus(preCheck, config)
 	case "2":
@@ -218,13 +245,6 @@ func applyMenuResult(preCheck utils.NetCheckResult, config *params.Config, resul
 		config.Nt3Location = "ALL"
 		SetRouteTestStatus(config)
 	}
-	config.RestoreUserSetParams(savedParams)
-	config.AnalyzeResult = result.mainAnalyze
-	config.EnableUpload = result.mainUpload
-	if result.choice == "1" && config.SpeedTestStatus {
-		config.OnlyChinaTest = utils.CheckChina(config.EnableLogger, config.Language)
-	}
-	config.ValidateParams()
 }
 
 // HandleMenuMode handles menu selection using the interactive TUI
@@ -179,3 +179,20 @@ func TestCustomAdvancedIncludesAIOnlyUnlockRegion(t *testing.T) {
 		t.Fatalf("AI-only unlock region is missing: %#v", setting.options)
 	}
 }
+
+func TestApplyPresetKeepsUserSetParams(t *testing.T) {
+	cfg := params.NewConfig("test")
+	cfg.ParseFlags([]string{"-menu=false", "-profile", "route", "-nt3loc", "SH", "-web=false"})
+
+	ApplyPreset(utils.NetCheckResult{Connected: true}, cfg, cfg.Choice)
+
+	if cfg.Choice != "10" || !cfg.BacktraceStatus || !cfg.Nt3Status || !cfg.PingTestStatus || cfg.CpuTestStatus {
+		t.Fatalf("route preset was not applied: %+v", cfg)
+	}
+	if cfg.WebTestStatus {
+		t.Fatalf("explicit -web=false should survive the preset")
+	}
+	if cfg.Nt3Location != "ALL" {
+		t.Fatalf("Nt3Location = %q, the route preset always tests every location", cfg.Nt3Location)
+	}
+}
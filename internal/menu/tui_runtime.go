@@ -332,6 +332,8 @@ func applyCustomResult(result tuiResult, preCheck utils.NetCheckResult, config *
 			config.DataOffline = a.boolVal
 		case "datacdn":
 			config.DataCDNBase = strings.TrimSpace(a.textVal)
+		case "datacache":
+			config.DataCacheDir = strings.TrimSpace(a.textVal)
 		}
 	}
 
@@ -0,0 +1,288 @@
+package params
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// builtinProfiles names the interactive menu presets so they can be
+// selected with -profile; the values are the menu choices, which are also
+// accepted as profile names.
+var builtinProfiles = map[string]string{
+	"full":            "1",
+	"minimal":         "2",
+	"standard":        "3",
+	"network-focused": "4",
+	"unlock-focused":  "5",
+	"network-only":    "6",
+	"unlock-only":     "7",
+	"hardware-only":   "8",
+	"ip-quality":      "9",
+	"route":           "10",
+}
+
+// flagAliases maps alternative flag names to the name SaveUserSetParams
+// records, so a config file and the command line agree on what is set.
+var flagAliases = map[string]string{
+	"h":             "help",
+	"v":             "version",
+	"l":             "lang",
+	"cpu-method":    "cpum",
+	"cpu-thread":    "cput",
+	"memory-method": "memorym",
+	"disk-method":   "diskm",
+	"nt3-location":  "nt3loc",
+	"nt3-type":      "nt3t",
+	"analyze":       "analysis",
+}
+
+// commandLineOnlyFlags select what to run rather than how, and cannot be
+// set from a config file.
+var commandLineOnlyFlags = map[string]bool{"help": true, "version": true, "config": true, "profile": true}
+
+// configLayer is one mapping of canonical flag names to config file values.
+type configLayer map[string]*yaml.Node
+
+type configProfile struct {
+	preset   string
+	settings configLayer
+}
+
+// applyConfigFile applies -config and -profile beneath the flags given on
+// the command line or in GOECS_ variables. The file's top-level keys are
+// flag names and "profile" picks the profile used when -profile is absent.
+// Its "profiles" mapping holds named sets of flags, each optionally starting
+// from a built-in preset with "preset". Profile values override the top
+// level and flags already set override both, as with SaveUserSetParams and
+// RestoreUserSetParams. Applied flags are recorded in UserSetFlags so a
+// menu preset chosen later keeps them too.
+func (c *Config) applyConfigFile() error {
+	settings := configLayer{}
+	profiles := map[string]configProfile{}
+	profile := strings.TrimSpace(c.Profile)
+	if c.ConfigFile != "" {
+		data, err := os.ReadFile(c.ConfigFile)
+		if err != nil {
+			return fmt.Errorf("read config: %w", err)
+		}
+		var defaultProfile string
+		settings, profiles, defaultProfile, err = c.parseConfigFile(data)
+		if err != nil {
+			return fmt.Errorf("config %s: %w", c.ConfigFile, err)
+		}
+		if profile == "" {
+			profile = defaultProfile
+		}
+	}
+	if profile != "" {
+		selected, err := resolveProfile(profile, profiles)
+		if err != nil {
+			return err
+		}
+		for name, value := range selected.settings {
+			settings[name] = value
+		}
+		if selected.preset != "" {
+			c.Choice = selected.preset
+		}
+	}
+	names := make([]string, 0, len(settings))
+	for name := range settings {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if c.alreadySet(name) {
+			continue
+		}
+		if err := c.setFlagFromNode(name, settings[name]); err != nil {
+			return fmt.Errorf("config %s: %s: %w", c.ConfigFile, name, err)
+		}
+		c.UserSetFlags[name] = true
+	}
+	return nil
+}
+
+func (c *Config) parseConfigFile(data []byte) (configLayer, map[string]configProfile, string, error) {
+	// JSON is valid YAML, so one decoder covers both formats.
+	var document yaml.Node
+	if err := yaml.Unmarshal(data, &document); err != nil {
+		return nil, nil, "", err
+	}
+	if len(document.Content) == 0 {
+		return nil, nil, "", errors.New("no settings")
+	}
+	root := document.Content[0]
+	if root.Kind != yaml.MappingNode {
+		return nil, nil, "", errors.New("top level must be a mapping of flag names")
+	}
+	settings := configLayer{}
+	profiles := map[string]configProfile{}
+	var defaultProfile string
+	seen := make(map[string]bool, len(root.Content)/2)
+	for index := 0; index+1 < len(root.Content); index += 2 {
+		key, value := root.Content[index].Value, root.Content[index+1]
+		if seen[key] {
+			return nil, nil, "", fmt.Errorf("%q is set twice", key)
+		}
+		seen[key] = true
+		switch key {
+		case "profile":
+			if value.Kind != yaml.ScalarNode || strings.TrimSpace(value.Value) == "" {
+				return nil, nil, "", errors.New("profile must name a profile")
+			}
+			defaultProfile = strings.TrimSpace(value.Value)
+		case "profiles":
+			if value.Kind != yaml.MappingNode {
+				return nil, nil, "", errors.New("profiles must be a mapping of profile names")
+			}
+			for profileIndex := 0; profileIndex+1 < len(value.Content); profileIndex += 2 {
+				name := strings.TrimSpace(value.Content[profileIndex].Value)
+				if _, exists := profiles[name]; exists || name == "" {
+					return nil, nil, "", fmt.Errorf("profile %q is empty or defined twice", name)
+				}
+				if _, builtin := presetChoice(name); builtin {
+					return nil, nil, "", fmt.Errorf("profile %q shadows a built-in profile", name)
+				}
+				profile, err := c.parseConfigProfile(value.Content[profileIndex+1])
+				if err != nil {
+					return nil, nil, "", fmt.Errorf("profile %s: %w", name, err)
+				}
+				profiles[name] = profile
+			}
+		default:
+			if err := c.addConfigSetting(settings, key, value); err != nil {
+				return nil, nil, "", err
+			}
+		}
+	}
+	return settings, profiles, defaultProfile, nil
+}
+
+func (c *Config) parseConfigProfile(node *yaml.Node) (configProfile, error) {
+	profile := configProfile{settings: configLayer{}}
+	if node.Kind != yaml.MappingNode {
+		return profile, errors.New("must be a mapping of flag names")
+	}
+	for index := 0; index+1 < len(node.Content); index += 2 {
+		key, value := node.Content[index].Value, node.Content[index+1]
+		if key != "preset" {
+			if err := c.addConfigSetting(profile.settings, key, value); err != nil {
+				return profile, err
+			}
+			continue
+		}
+		choice, ok := presetChoice(strings.TrimSpace(value.Value))
+		if value.Kind != yaml.ScalarNode || !ok || profile.preset != "" {
+			return profile, fmt.Errorf("preset must be set once to one of %s", strings.Join(builtinProfileNames(), ", "))
+		}
+		profile.preset = choice
+	}
+	return profile, nil
+}
+
+func (c *Config) addConfigSetting(settings configLayer, key string, value *yaml.Node) error {
+	name := canonicalFlagName(key)
+	if c.GoecsFlag.Lookup(key) == nil {
+		return fmt.Errorf("unknown flag %q", key)
+	}
+	if commandLineOnlyFlags[name] {
+		return fmt.Errorf("%q can only be set on the command line", key)
+	}
+	if _, exists := settings[name]; exists {
+		return fmt.Errorf("%q is set twice", name)
+	}
+	settings[name] = value
+	return nil
+}
+
+// setFlagFromNode sets a flag from a scalar, or a repeatable flag from a
+// sequence of scalars, so values are parsed exactly as on the command line.
+func (c *Config) setFlagFromNode(name string, node *yaml.Node) error {
+	switch node.Kind {
+	case yaml.ScalarNode:
+		if node.Tag == "!!null" {
+			return errors.New("has no value")
+		}
+		return c.GoecsFlag.Set(name, node.Value)
+	case yaml.SequenceNode:
+		if _, repeatable := c.GoecsFlag.Lookup(name).Value.(*stringListFlag); !repeatable {
+			return errors.New("takes a single value")
+		}
+		for _, item := range node.Content {
+			if item.Kind != yaml.ScalarNode {
+				return errors.New("list items must be single values")
+			}
+			if err := c.GoecsFlag.Set(name, item.Value); err != nil {
+				return err
+			}
+		}
+		return nil
+	default:
+		return errors.New("must be a single value")
+	}
+}
+
+// alreadySet reports whether name or one of its aliases was given on the
+// command line or applied from the environment.
+func (c *Config) alreadySet(name string) bool {
+	if c.UserSetFlags[name] {
+		return true
+	}
+	for alias, canonical := range flagAliases {
+		if canonical == name && c.UserSetFlags[alias] {
+			return true
+		}
+	}
+	return false
+}
+
+func resolveProfile(name string, profiles map[string]configProfile) (configProfile, error) {
+	if profile, ok := profiles[name]; ok {
+		return profile, nil
+	}
+	if choice, ok := presetChoice(name); ok {
+		return configProfile{preset: choice}, nil
+	}
+	available := builtinProfileNames()
+	for profile := range profiles {
+		available = append(available, profile)
+	}
+	sort.Strings(available)
+	return configProfile{}, fmt.Errorf("unknown profile %q (want one of %s)", name, strings.Join(available, ", "))
+}
+
+// presetChoice resolves a built-in profile name or menu number to the menu
+// choice.
+func presetChoice(name string) (string, bool) {
+	if choice, ok := builtinProfiles[strings.ToLower(name)]; ok {
+		return choice, true
+	}
+	for _, choice := range builtinProfiles {
+		if name == choice {
+			return choice, true
+		}
+	}
+	return "", false
+}
+
+func builtinProfileNames() []string {
+	names := make([]string, 0, len(builtinProfiles))
+	for name := range builtinProfiles {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func canonicalFlagName(name string) string {
+	if canonical, ok := flagAliases[name]; ok {
+		return canonical
+	}
+	return name
+}
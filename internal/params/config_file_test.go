@@ -0,0 +1,101 @@
+package params
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func writeConfigFile(t *testing.T, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "goecs.yaml")
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestConfigFileProfilesMergeBeneathCommandLine(t *testing.T) {
+	path := writeConfigFile(t, `
+lang: en
+upload: false
+spnum: 3
+cpu-method: geekbench
+profiles:
+  nightly-network:
+    preset: network-only
+    spnum: 5
+    timeout: 10m
+    tcp-target: [pop=pop.example:443, edge=edge.example:443]
+  purchase-eval:
+    preset: "3"
+    analysis: true
+`)
+	cfg := NewConfig("test")
+	cfg.ParseFlags([]string{"-config", path, "-profile=nightly-network", "-l", "zh", "-cpum", "sysbench"})
+
+	if cfg.Choice != "6" {
+		t.Fatalf("Choice = %q, want network-only preset 6", cfg.Choice)
+	}
+	if cfg.Language != "zh" || cfg.CpuTestMethod != "sysbench" {
+		t.Fatalf("command-line flags should win over the file: lang=%q cpum=%q", cfg.Language, cfg.CpuTestMethod)
+	}
+	if cfg.SpNum != 5 || cfg.MaxDuration != 10*time.Minute || cfg.EnableUpload {
+		t.Fatalf("file and profile values not applied: spnum=%d timeout=%s upload=%v", cfg.SpNum, cfg.MaxDuration, cfg.EnableUpload)
+	}
+	if strings.Join(cfg.TCPTargets, ",") != "pop=pop.example:443,edge=edge.example:443" || !cfg.TCPProbeStatus {
+		t.Fatalf("repeatable flag from a list = %v", cfg.TCPTargets)
+	}
+	saved := cfg.SaveUserSetParams()
+	if saved["spnum"] != 5 || saved["cpum"] != "sysbench" {
+		t.Fatalf("file values should be kept through a menu preset: %v", saved)
+	}
+}
+
+func TestConfigFileDefaultProfileAndBuiltinProfiles(t *testing.T) {
+	path := writeConfigFile(t, "profile: eval\nprofiles:\n  eval:\n    analysis: true\n")
+	cfg := NewConfig("test")
+	cfg.ParseFlags([]string{"-config", path})
+	if !cfg.AnalyzeResult || cfg.Choice != "" {
+		t.Fatalf("default profile: analysis=%v choice=%q", cfg.AnalyzeResult, cfg.Choice)
+	}
+
+	for name, choice := range map[string]string{"hardware-only": "8", "Standard": "3", "10": "10"} {
+		cfg := NewConfig("test")
+		cfg.ParseFlags([]string{"-menu=false", "-profile", name})
+		if cfg.Choice != choice {
+			t.Fatalf("profile %q selected choice %q, want %q", name, cfg.Choice, choice)
+		}
+	}
+}
+
+func TestConfigFileRejectsInvalidSettings(t *testing.T) {
+	for name, content := range map[string]string{
+		"unknown flag":      "cpu: false\nbogus: 1\n",
+		"alias twice":       "cpum: sysbench\ncpu-method: geekbench\n",
+		"command line only": "config: other.yaml\n",
+		"bad value":         "spnum: many\n",
+		"list for scalar":   "spnum: [1, 2]\n",
+		"shadowed builtin":  "profiles:\n  standard:\n    cpu: false\n",
+		"bad preset":        "profiles:\n  eval:\n    preset: everything\n",
+		"not a mapping":     "- cpu\n",
+		"empty":             "",
+	} {
+		t.Run(name, func(t *testing.T) {
+			cfg := NewConfig("test")
+			cfg.ParseFlags(nil)
+			cfg.ConfigFile = writeConfigFile(t, content)
+			if err := cfg.applyConfigFile(); err == nil {
+				t.Fatal("invalid config file was accepted")
+			}
+		})
+	}
+	cfg := NewConfig("test")
+	cfg.ParseFlags(nil)
+	cfg.Profile = "missing"
+	if err := cfg.applyConfigFile(); err == nil || !strings.Contains(err.Error(), "hardware-only") {
+		t.Fatalf("unknown profile error = %v", err)
+	}
+}
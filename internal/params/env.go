@@ -0,0 +1,66 @@
+package params
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+)
+
+// envPrefix starts the environment variable of every flag: -data-offline is
+// GOECS_DATA_OFFLINE and -cpum is GOECS_CPUM.
+const envPrefix = "GOECS_"
+
+// envName returns the environment variable that sets flag name.
+func envName(name string) string {
+	return envPrefix + strings.ToUpper(strings.ReplaceAll(name, "-", "_"))
+}
+
+// applyEnvironment sets every flag not given on the command line from its
+// GOECS_ variable, so the layers apply as defaults < config file <
+// environment < command line. A variable that is present but empty sets an
+// empty value. Repeatable flags take a comma-separated list. Applied flags
+// are recorded in UserSetFlags, which also keeps a -config file from
+// overriding them.
+func (c *Config) applyEnvironment() error {
+	values := make(map[string]string)
+	sources := make(map[string]string)
+	var setErr error
+	c.GoecsFlag.VisitAll(func(f *flag.Flag) {
+		value, ok := os.LookupEnv(envName(f.Name))
+		name := canonicalFlagName(f.Name)
+		if !ok || name == "help" || name == "version" || setErr != nil {
+			return
+		}
+		if previous, exists := sources[name]; exists {
+			setErr = fmt.Errorf("%s and %s both set -%s", previous, envName(f.Name), name)
+			return
+		}
+		values[name], sources[name] = value, envName(f.Name)
+	})
+	if setErr != nil {
+		return setErr
+	}
+	names := make([]string, 0, len(values))
+	for name := range values {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if c.alreadySet(name) {
+			continue
+		}
+		items := []string{values[name]}
+		if _, repeatable := c.GoecsFlag.Lookup(name).Value.(*stringListFlag); repeatable {
+			items = strings.Split(values[name], ",")
+		}
+		for _, item := range items {
+			if err := c.GoecsFlag.Set(name, item); err != nil {
+				return fmt.Errorf("%s: %w", sources[name], err)
+			}
+		}
+		c.UserSetFlags[name] = true
+	}
+	return nil
+}
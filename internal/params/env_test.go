@@ -0,0 +1,53 @@
+package params
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestEnvironmentLayerSitsBetweenConfigFileAndCommandLine(t *testing.T) {
+	path := writeConfigFile(t, "lang: en\nspnum: 3\ncpu: false\n")
+	t.Setenv("GOECS_CONFIG", path)
+	t.Setenv("GOECS_SPNUM", "7")
+	t.Setenv("GOECS_CPU", "false")
+	t.Setenv("GOECS_CPU_METHOD", "geekbench")
+	t.Setenv("GOECS_DATA_OFFLINE", "1")
+	t.Setenv("GOECS_TCP_TARGET", "a=a.example:443,b=b.example:443")
+	t.Setenv("GOECS_HELP", "true")
+	cfg := NewConfig("test")
+	cfg.ParseFlags([]string{"-cpu=true"})
+
+	if cfg.SpNum != 7 || cfg.Language != "en" || !cfg.CpuTestStatus {
+		t.Fatalf("precedence: spnum=%d lang=%q cpu=%v", cfg.SpNum, cfg.Language, cfg.CpuTestStatus)
+	}
+	if cfg.CpuTestMethod != "geekbench" || !cfg.DataOffline || cfg.Help {
+		t.Fatalf("environment values: cpum=%q offline=%v help=%v", cfg.CpuTestMethod, cfg.DataOffline, cfg.Help)
+	}
+	if strings.Join(cfg.TCPTargets, " ") != "a=a.example:443 b=b.example:443" {
+		t.Fatalf("TCPTargets = %v", cfg.TCPTargets)
+	}
+	for _, name := range []string{"spnum", "cpum", "data-offline", "lang"} {
+		if !cfg.UserSetFlags[name] {
+			t.Fatalf("%s was not recorded as user-set", name)
+		}
+	}
+}
+
+func TestEnvironmentRejectsConflictingAliases(t *testing.T) {
+	cfg := NewConfig("test")
+	cfg.ParseFlags(nil)
+	t.Setenv("GOECS_CPUM", "sysbench")
+	t.Setenv("GOECS_CPU_METHOD", "geekbench")
+	if err := cfg.applyEnvironment(); err == nil || !strings.Contains(err.Error(), "-cpum") {
+		t.Fatalf("conflicting aliases error = %v", err)
+	}
+}
+
+func TestEnvironmentRejectsInvalidValues(t *testing.T) {
+	cfg := NewConfig("test")
+	cfg.ParseFlags(nil)
+	t.Setenv("GOECS_SPNUM", "many")
+	if err := cfg.applyEnvironment(); err == nil || !strings.Contains(err.Error(), "GOECS_SPNUM") {
+		t.Fatalf("invalid value error = %v", err)
+	}
+}
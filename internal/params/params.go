@@ -3,9 +3,12 @@ package params
 import (
 	"flag"
 	"fmt"
+	"net/url"
 	"os"
 	"strings"
 	"time"
+
+	datarepo "github.com/oneclickvirt/ecs/internal/data"
 )
 
 // Config holds all configuration parameters
@@ -41,13 +44,25 @@ type Config struct {
 	PingTestStatus        bool
 	TgdcTestStatus        bool
 	WebTestStatus         bool
+	QUICTestStatus        bool
 	AutoChangeDiskMethod  bool
 	FilePath              string
 	EnableUpload          bool
+	UploadEndpoint        string
+	UploadToken           string
+	UploadResultField     string
+	UploadFormat          string
 	AnalyzeResult         bool
 	DeepMode              bool
 	PrivacyMode           bool
 	TCPProbeStatus        bool
+	TLSProbeStatus        bool
+	TCPTargetsFile        string
+	TCPTargets            []string
+	TCPAttempts           int
+	TCPTimeout            time.Duration
+	TCPConcurrency        int
+	TCPInterval           time.Duration
 	MaxDuration           time.Duration
 	HardwareBudget        time.Duration
 	DeepDiskPaths         string
@@ -55,8 +70,17 @@ type Config struct {
 	DeepBurnDuration      time.Duration
 	DeepGPUDevice         string
 	JSONPath              string
+	ComparePath           string
+	CompareJSONPath       string
+	MetricsPath           string
+	RepeatInterval        time.Duration
+	HistoryDir            string
+	HistoryMaxMB          int
 	DataCDNBase           string
 	DataOffline           bool
+	DataCacheDir          string
+	DataCacheMaxAge       time.Duration
+	DataOverlayDir        string
 	OnlyIpInfoCheck       bool
 	UnlockTestRegion      string
 	UnlockTestShowIP      bool
@@ -66,6 +90,13 @@ type Config struct {
 	UnlockTestHTTPProxy   string
 	UnlockTestSOCKSProxy  string
 	UnlockTestConcurrency int
+	ConfigFile            string
+	Profile               string
+	Strict                bool
+	FailOn                string
+	Assertions            []string
+	AssertPreset          string
+	ConfigWarnings        ConfigIssues
 	Help                  bool
 	Finish                bool
 	UserSetFlags          map[string]bool
@@ -106,24 +137,51 @@ func NewConfig(version string) *Config {
 		PingTestStatus:        false,
 		TgdcTestStatus:        false,
 		WebTestStatus:         false,
+		QUICTestStatus:        false,
 		AutoChangeDiskMethod:  true,
 		FilePath:              "goecs.txt",
 		EnableUpload:          true,
+		UploadEndpoint:        "",
+		UploadToken:           "",
+		UploadResultField:     "",
+		UploadFormat:          "text",
 		AnalyzeResult:         false,
 		DeepMode:              false,
 		PrivacyMode:           false,
 		TCPProbeStatus:        false,
+		TLSProbeStatus:        false,
+		TCPTargetsFile:        "",
+		TCPTargets:            nil,
+		TCPAttempts:           0,
+		TCPTimeout:            3 * time.Second,
+		TCPConcurrency:        16,
+		TCPInterval:           0,
 		MaxDuration:           15 * time.Minute,
 		HardwareBudget:        2 * time.Minute,
 		DeepBurnDuration:      0,
 		JSONPath:              "",
+		ComparePath:           "",
+		CompareJSONPath:       "",
+		MetricsPath:           "",
+		RepeatInterval:        0,
+		HistoryDir:            "",
+		HistoryMaxMB:          256,
 		DataCDNBase:           "https://cdn.spiritlhl.net/https://raw.githubusercontent.com/oneclickvirt/ecs/master/internal/data/snapshot",
 		DataOffline:           false,
+		DataCacheDir:          datarepo.DefaultCacheDir(),
+		DataCacheMaxAge:       datarepo.DefaultCacheMaxAge,
+		DataOverlayDir:        "",
 		OnlyIpInfoCheck:       false,
 		UnlockTestRegion:      "0",
 		UnlockTestShowIP:      false,
 		UnlockTestIPVersion:   "auto",
 		UnlockTestConcurrency: 20,
+		ConfigFile:            "",
+		Profile:               "",
+		Strict:                false,
+		FailOn:                "timeout",
+		Assertions:            nil,
+		AssertPreset:          "",
 		Help:                  false,
 		Finish:                false,
 		UserSetFlags:          make(map[string]bool),
@@ -131,6 +189,21 @@ func NewConfig(version string) *Config {
 	}
 }
 
+// stringListFlag collects every occurrence of a repeatable string flag.
+type stringListFlag []string
+
+func (s *stringListFlag) String() string {
+	if s == nil {
+		return ""
+	}
+	return strings.Join(*s, ",")
+}
+
+func (s *stringListFlag) Set(value string) error {
+	*s = append(*s, value)
+	return nil
+}
+
 // normalizeBoolArgs preprocesses args so that bool flags written as
 // "-flag true" or "-flag false" (space-separated) are converted to
 // "-flag=true" / "-flag=false" that the standard flag package understands.
@@ -143,10 +216,10 @@ func normalizeBoolArgs(args []string) []string {
 		"menu": true, "basic": true, "cpu": true, "memory": true,
 		"disk": true, "ut": true, "security": true, "email": true,
 		"backtrace": true, "nt3": true, "speed": true, "ping": true,
-		"tgdc": true, "web": true, "log": true, "upload": true,
+		"tgdc": true, "web": true, "quic": true, "log": true, "upload": true,
 		"analysis": true, "analyze": true,
-		"deep": true, "privacy": true, "tcp": true,
-		"diskmc": true, "utshowip": true,
+		"deep": true, "privacy": true, "tcp": true, "tls": true,
+		"diskmc": true, "utshowip": true, "strict": true,
 	}
 
 	out := make([]string, 0, len(args))
@@ -220,6 +293,7 @@ func (c *Config) ParseFlags(args []string) {
 	c.GoecsFlag.BoolVar(&c.PingTestStatus, "ping", false, "Enable/Disable ping test")
 	c.GoecsFlag.BoolVar(&c.TgdcTestStatus, "tgdc", false, "Enable/Disable Telegram DC test")
 	c.GoecsFlag.BoolVar(&c.WebTestStatus, "web", false, "Enable/Disable popular websites test")
+	c.GoecsFlag.BoolVar(&c.QUICTestStatus, "quic", false, "Enable/Disable HTTP/3 (QUIC) reachability test against popular websites")
 	c.GoecsFlag.StringVar(&c.CpuTestMethod, "cpum", "sysbench", "Set CPU test method (supported: sysbench, geekbench, winsat)")
 	c.GoecsFlag.StringVar(&c.CpuTestMethod, "cpu-method", "sysbench", "Set CPU test method (supported: sysbench, geekbench, winsat)")
 	c.GoecsFlag.StringVar(&c.CpuTestThreadMode, "cput", "multi", "Set CPU test thread mode (supported: single, multi)")
@@ -245,11 +319,23 @@ func (c *Config) ParseFlags(args []string) {
 	c.GoecsFlag.IntVar(&c.UnlockTestConcurrency, "ut-concurrency", 20, "Maximum concurrent unlock tests")
 	c.GoecsFlag.BoolVar(&c.EnableLogger, "log", false, "Enable/Disable logging in the current path")
 	c.GoecsFlag.BoolVar(&c.EnableUpload, "upload", true, "Enable/Disable upload the result")
+	c.GoecsFlag.StringVar(&c.UploadEndpoint, "upload-url", "", "POST results to this HTTP(S) collector instead of the public paste service")
+	c.GoecsFlag.StringVar(&c.UploadToken, "upload-token", "", "Bearer token sent to the -upload-url collector")
+	c.GoecsFlag.StringVar(&c.UploadResultField, "upload-result-field", "", "Dotted JSON path of the result URL in the collector response (default: whole body)")
+	c.GoecsFlag.StringVar(&c.UploadFormat, "upload-format", "text", "Report uploaded to -upload-url (supported: text, json, both)")
 	c.GoecsFlag.BoolVar(&c.AnalyzeResult, "analysis", false, "Enable/Disable post-test concise summary analysis")
 	c.GoecsFlag.BoolVar(&c.AnalyzeResult, "analyze", false, "Enable/Disable post-test concise summary analysis")
 	c.GoecsFlag.BoolVar(&c.DeepMode, "deep", false, "Enable deep test matrix within the global deadline")
 	c.GoecsFlag.BoolVar(&c.PrivacyMode, "privacy", false, "Disable result sharing and hide sensitive hardware identifiers")
 	c.GoecsFlag.BoolVar(&c.TCPProbeStatus, "tcp", false, "Enable/Disable the additional TCP handshake probe section")
+	c.GoecsFlag.BoolVar(&c.TLSProbeStatus, "tls", false, "Add TLS handshake and HTTP first-byte probes for port 443 TCP targets (enables -tcp)")
+	c.GoecsFlag.StringVar(&c.TCPTargetsFile, "tcp-targets", "", "JSON file of custom TCP probe targets (enables -tcp)")
+	c.TCPTargets = nil
+	c.GoecsFlag.Var((*stringListFlag)(&c.TCPTargets), "tcp-target", "Custom TCP probe target as name=host:port, repeatable (enables -tcp)")
+	c.GoecsFlag.IntVar(&c.TCPAttempts, "tcp-attempts", 0, "TCP handshakes per target (0 selects 3, or 20 with -deep)")
+	c.GoecsFlag.DurationVar(&c.TCPTimeout, "tcp-timeout", 3*time.Second, "Timeout for each TCP handshake")
+	c.GoecsFlag.IntVar(&c.TCPConcurrency, "tcp-concurrency", 16, "Maximum TCP targets probed concurrently")
+	c.GoecsFlag.DurationVar(&c.TCPInterval, "tcp-interval", 0, "Pause between handshakes to the same target")
 	c.GoecsFlag.DurationVar(&c.MaxDuration, "timeout", 15*time.Minute, "Set the global test deadline")
 	c.GoecsFlag.DurationVar(&c.HardwareBudget, "hardware-budget", 2*time.Minute, "Set the standard hardware test budget")
 	c.GoecsFlag.StringVar(&c.DeepDiskPaths, "deep-disk-paths", "", "Comma-separated mounted directories for the explicit deep multi-disk matrix")
@@ -257,8 +343,24 @@ func (c *Config) ParseFlags(args []string) {
 	c.GoecsFlag.DurationVar(&c.DeepBurnDuration, "deep-burn-duration", 0, "Explicit deep CPU burn duration (disabled when zero)")
 	c.GoecsFlag.StringVar(&c.DeepGPUDevice, "deep-gpu-device", "", "Explicit GPU device selector for deep compute")
 	c.GoecsFlag.StringVar(&c.JSONPath, "json", "", "Write the versioned JSON report to this path, or '-' for stdout")
+	c.GoecsFlag.StringVar(&c.ComparePath, "compare", "", "Compare this run against a previously written JSON report")
+	c.GoecsFlag.StringVar(&c.CompareJSONPath, "compare-json", "", "Write the versioned comparison report to this path, or '-' for stdout")
+	c.GoecsFlag.StringVar(&c.MetricsPath, "metrics", "", "Write OpenMetrics gauges for the structured report to this path")
+	c.GoecsFlag.DurationVar(&c.RepeatInterval, "repeat", 0, "Re-run the selected tests on this interval until interrupted (disables upload)")
+	c.GoecsFlag.StringVar(&c.HistoryDir, "history-dir", "", "Append each structured report to daily JSONL files in this directory")
+	c.GoecsFlag.IntVar(&c.HistoryMaxMB, "history-max-mb", 256, "Maximum size of the history directory in MiB")
 	c.GoecsFlag.StringVar(&c.DataCDNBase, "data-cdn", c.DataCDNBase, "Set the Go ECS snapshot CDN base URL")
 	c.GoecsFlag.BoolVar(&c.DataOffline, "data-offline", false, "Force the embedded Go ECS snapshot without remote requests")
+	c.GoecsFlag.StringVar(&c.DataCacheDir, "data-cache", datarepo.DefaultCacheDir(), "Cache verified Go ECS snapshot files in this directory (empty disables)")
+	c.GoecsFlag.DurationVar(&c.DataCacheMaxAge, "data-cache-max-age", datarepo.DefaultCacheMaxAge, "Reuse cached snapshot files this long before revalidating (0 revalidates every run)")
+	c.GoecsFlag.StringVar(&c.DataOverlayDir, "data-dir", "", "Directory with its own manifest.json whose listed snapshot files replace the built-in ones")
+	c.GoecsFlag.StringVar(&c.ConfigFile, "config", "", "Read flag values and named profiles from this YAML or JSON file; GOECS_<FLAG> variables and command-line flags take precedence")
+	c.GoecsFlag.StringVar(&c.Profile, "profile", "", "Apply a profile from -config or a built-in preset: "+strings.Join(builtinProfileNames(), ", ")+" (or menu number 1-10)")
+	c.Assertions = nil
+	c.GoecsFlag.Var((*stringListFlag)(&c.Assertions), "assert", "Fail the run unless this check of the structured report holds, e.g. memorytest.bandwidth_mbps>=10240, disktest.randread_4k_iops>=5000, disktest[4k-q1-read].iops>=5000 or tcp[github].p95_ms<150; derived fields are memorytest.bandwidth_mbps, disktest.read_mbps and disktest.randread_4k_iops; repeatable")
+	c.GoecsFlag.StringVar(&c.AssertPreset, "assert-preset", "", "Add a built-in set of -assert checks (supported: baseline)")
+	c.GoecsFlag.StringVar(&c.FailOn, "fail-on", "timeout", "Lowest run status that exits non-zero (supported: partial, timeout, error); partial and error run the structured report, whose section statuses they need")
+	c.GoecsFlag.BoolVar(&c.Strict, "strict", false, "Exit with status 2 when a flag value is invalid or out of range instead of correcting it")
 	if err := c.GoecsFlag.Parse(args); err != nil {
 		fmt.Fprintln(os.Stderr, err)
 		os.Exit(2)
@@ -267,7 +369,24 @@ func (c *Config) ParseFlags(args []string) {
 	c.GoecsFlag.Visit(func(f *flag.Flag) {
 		c.UserSetFlags[f.Name] = true
 	})
-	c.ValidateParams()
+	// The environment is applied before the config file it may name, and
+	// each layer only fills flags the layers above it left unset.
+	if err := c.applyEnvironment(); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
+	if c.ConfigFile != "" || c.Profile != "" {
+		if err := c.applyConfigFile(); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(2)
+		}
+	}
+	c.ConfigWarnings = nil
+	issues := c.ValidateParams()
+	issues.Print(os.Stderr, c.Language)
+	if c.Strict && len(issues) > 0 {
+		os.Exit(2)
+	}
 }
 
 // HandleHelpAndVersion handles help and version flags
@@ -328,9 +447,15 @@ func (c *Config) SaveUserSetParams() map[string]interface{} {
 	if c.UserSetFlags["web"] {
 		saved["web"] = c.WebTestStatus
 	}
+	if c.UserSetFlags["quic"] {
+		saved["quic"] = c.QUICTestStatus
+	}
 	if c.UserSetFlags["tcp"] {
 		saved["tcp"] = c.TCPProbeStatus
 	}
+	if c.UserSetFlags["tls"] {
+		saved["tls"] = c.TLSProbeStatus
+	}
 	if c.UserSetFlags["cpum"] || c.UserSetFlags["cpu-method"] {
 		saved["cpum"] = c.CpuTestMethod
 	}
@@ -394,6 +519,16 @@ func (c *Config) SaveUserSetParams() map[string]interface{} {
 	if c.UserSetFlags["deep-burn-duration"] {
 		saved["deep-burn-duration"] = c.DeepBurnDuration
 	}
+	for flagName, value := range map[string]int{"tcp-attempts": c.TCPAttempts, "tcp-concurrency": c.TCPConcurrency} {
+		if c.UserSetFlags[flagName] {
+			saved[flagName] = value
+		}
+	}
+	for flagName, value := range map[string]time.Duration{"tcp-timeout": c.TCPTimeout, "tcp-interval": c.TCPInterval} {
+		if c.UserSetFlags[flagName] {
+			saved[flagName] = value
+		}
+	}
 
 	return saved
 }
@@ -465,11 +600,21 @@ func (c *Config) RestoreUserSetParams(saved map[string]interface{}) {
 			c.WebTestStatus = boolVal
 		}
 	}
+	if val, ok := saved["quic"]; ok {
+		if boolVal, ok := val.(bool); ok {
+			c.QUICTestStatus = boolVal
+		}
+	}
 	if val, ok := saved["tcp"]; ok {
 		if boolVal, ok := val.(bool); ok {
 			c.TCPProbeStatus = boolVal
 		}
 	}
+	if val, ok := saved["tls"]; ok {
+		if boolVal, ok := val.(bool); ok {
+			c.TLSProbeStatus = boolVal
+		}
+	}
 	if val, ok := saved["cpum"]; ok {
 		if strVal, ok := val.(string); ok {
 			c.CpuTestMethod = strVal
@@ -569,12 +714,29 @@ func (c *Config) RestoreUserSetParams(saved map[string]interface{}) {
 			c.DeepBurnDuration = duration
 		}
 	}
+	for key, target := range map[string]*int{"tcp-attempts": &c.TCPAttempts, "tcp-concurrency": &c.TCPConcurrency} {
+		if val, ok := saved[key]; ok {
+			if intValue, valid := val.(int); valid {
+				*target = intValue
+			}
+		}
+	}
+	for key, target := range map[string]*time.Duration{"tcp-timeout": &c.TCPTimeout, "tcp-interval": &c.TCPInterval} {
+		if val, ok := saved[key]; ok {
+			if duration, valid := val.(time.Duration); valid {
+				*target = duration
+			}
+		}
+	}
 
 	c.ValidateParams()
 }
 
-// ValidateParams validates parameter values
-func (c *Config) ValidateParams() {
+// ValidateParams normalizes parameter values and replaces invalid ones with
+// their defaults. It returns the corrections it made, which are also
+// appended to ConfigWarnings; it prints nothing.
+func (c *Config) ValidateParams() ConfigIssues {
+	var issues ConfigIssues
 	c.Language = strings.ToLower(strings.TrimSpace(c.Language))
 	c.CpuTestMethod = strings.ToLower(strings.TrimSpace(c.CpuTestMethod))
 	c.CpuTestThreadMode = strings.ToLower(strings.TrimSpace(c.CpuTestThreadMode))
@@ -589,11 +751,80 @@ func (c *Config) ValidateParams() {
 	c.UnlockTestHTTPProxy = strings.TrimSpace(c.UnlockTestHTTPProxy)
 	c.UnlockTestSOCKSProxy = strings.TrimSpace(c.UnlockTestSOCKSProxy)
 	c.JSONPath = strings.TrimSpace(c.JSONPath)
+	c.ComparePath = strings.TrimSpace(c.ComparePath)
+	c.CompareJSONPath = strings.TrimSpace(c.CompareJSONPath)
+	c.MetricsPath = strings.TrimSpace(c.MetricsPath)
+	c.HistoryDir = strings.TrimSpace(c.HistoryDir)
+	c.TCPTargetsFile = strings.TrimSpace(c.TCPTargetsFile)
+	tcpTargets := make([]string, 0, len(c.TCPTargets))
+	for _, target := range c.TCPTargets {
+		if target = strings.TrimSpace(target); target != "" {
+			tcpTargets = append(tcpTargets, target)
+		}
+	}
+	c.TCPTargets = tcpTargets
+	if c.TCPTargetsFile != "" || len(c.TCPTargets) > 0 || c.TLSProbeStatus {
+		c.TCPProbeStatus = true
+	}
+	// Zero attempts stays zero so the deep-mode default can still apply when
+	// -deep is set after the TCP options.
+	if c.TCPAttempts < 0 {
+		issues.reject("tcp-attempts", c.TCPAttempts, "0",
+			fmt.Sprintf("Invalid TCP attempts '%d', using the default", c.TCPAttempts),
+			fmt.Sprintf("TCP握手次数 '%d' 无效，使用默认值", c.TCPAttempts))
+		c.TCPAttempts = 0
+	} else if c.TCPAttempts > 1000 {
+		issues.adjust("tcp-attempts", c.TCPAttempts, "1000",
+			fmt.Sprintf("TCP attempts '%d' is too high, using maximum 1000", c.TCPAttempts),
+			fmt.Sprintf("TCP握手次数 '%d' 过多，使用最大值 1000", c.TCPAttempts))
+		c.TCPAttempts = 1000
+	}
+	if c.TCPTimeout <= 0 {
+		c.TCPTimeout = 3 * time.Second
+	} else if c.TCPTimeout > 30*time.Second {
+		issues.adjust("tcp-timeout", c.TCPTimeout, "30s",
+			fmt.Sprintf("TCP timeout '%s' is too long, using maximum 30s", c.TCPTimeout),
+			fmt.Sprintf("TCP超时 '%s' 过长，使用最大值 30s", c.TCPTimeout))
+		c.TCPTimeout = 30 * time.Second
+	}
+	if c.TCPConcurrency <= 0 {
+		c.TCPConcurrency = 16
+	} else if c.TCPConcurrency > 256 {
+		issues.adjust("tcp-concurrency", c.TCPConcurrency, "256",
+			fmt.Sprintf("TCP concurrency '%d' is too high, using maximum 256", c.TCPConcurrency),
+			fmt.Sprintf("TCP并发数 '%d' 过高，使用最大值 256", c.TCPConcurrency))
+		c.TCPConcurrency = 256
+	}
+	if c.TCPInterval < 0 {
+		issues.reject("tcp-interval", c.TCPInterval, "0s",
+			fmt.Sprintf("Invalid TCP interval '%s', using 0s", c.TCPInterval),
+			fmt.Sprintf("TCP间隔 '%s' 无效，使用 0s", c.TCPInterval))
+		c.TCPInterval = 0
+	}
 	c.DataCDNBase = strings.TrimRight(strings.TrimSpace(c.DataCDNBase), "/")
+	c.DataCacheDir = strings.TrimSpace(c.DataCacheDir)
+	c.DataOverlayDir = strings.TrimSpace(c.DataOverlayDir)
+	// Zero is valid and revalidates the cache on every run.
+	if c.DataCacheMaxAge < 0 {
+		issues.reject("data-cache-max-age", c.DataCacheMaxAge, "0s",
+			fmt.Sprintf("Invalid data cache max age '%s', using 0s", c.DataCacheMaxAge),
+			fmt.Sprintf("数据缓存有效期 '%s' 无效，使用 0s", c.DataCacheMaxAge))
+		c.DataCacheMaxAge = 0
+	}
 	c.DeepDiskPaths = strings.TrimSpace(c.DeepDiskPaths)
 	c.DeepSMARTDevices = strings.TrimSpace(c.DeepSMARTDevices)
 	c.DeepGPUDevice = strings.TrimSpace(c.DeepGPUDevice)
-	if c.MaxDuration <= 0 || c.MaxDuration > 15*time.Minute {
+	if c.MaxDuration < 0 {
+		issues.reject("timeout", c.MaxDuration, "15m0s",
+			fmt.Sprintf("Invalid global timeout '%s', using maximum 15m", c.MaxDuration),
+			fmt.Sprintf("全局超时 '%s' 无效，使用最大值 15m", c.MaxDuration))
+		c.MaxDuration = 15 * time.Minute
+	} else if c.MaxDuration > 15*time.Minute {
+		issues.adjust("timeout", c.MaxDuration, "15m0s",
+			fmt.Sprintf("Global timeout '%s' is too long, using maximum 15m", c.MaxDuration),
+			fmt.Sprintf("全局超时 '%s' 过长，使用最大值 15m", c.MaxDuration))
+		c.MaxDuration = 15 * time.Minute
+	} else if c.MaxDuration == 0 {
 		c.MaxDuration = 15 * time.Minute
 	}
 	standardHardwareBudget := min(2*time.Minute, c.MaxDuration)
@@ -601,93 +832,153 @@ func (c *Config) ValidateParams() {
 	if c.DeepMode {
 		hardwareBudgetLimit = c.MaxDuration
 	}
-	if c.HardwareBudget <= 0 || c.HardwareBudget > hardwareBudgetLimit {
+	switch {
+	case c.HardwareBudget < 0:
+		issues.reject("hardware-budget", c.HardwareBudget, hardwareBudgetLimit.String(),
+			fmt.Sprintf("Invalid hardware budget '%s', using %s", c.HardwareBudget, hardwareBudgetLimit),
+			fmt.Sprintf("硬件测试预算 '%s' 无效，使用 %s", c.HardwareBudget, hardwareBudgetLimit))
+		c.HardwareBudget = hardwareBudgetLimit
+	case c.HardwareBudget > hardwareBudgetLimit:
+		// The 2m default silently follows a shorter -timeout; only a budget
+		// the caller chose is worth a warning.
+		if c.HardwareBudget != 2*time.Minute {
+			issues.adjust("hardware-budget", c.HardwareBudget, hardwareBudgetLimit.String(),
+				fmt.Sprintf("Hardware budget '%s' exceeds the limit, using %s", c.HardwareBudget, hardwareBudgetLimit),
+				fmt.Sprintf("硬件测试预算 '%s' 超过上限，使用 %s", c.HardwareBudget, hardwareBudgetLimit))
+		}
+		c.HardwareBudget = hardwareBudgetLimit
+	case c.HardwareBudget == 0:
 		c.HardwareBudget = hardwareBudgetLimit
 	}
 	if !c.DeepMode {
+		if c.DeepBurnDuration != 0 {
+			issues.adjust("deep-burn-duration", c.DeepBurnDuration, "0s",
+				fmt.Sprintf("Deep burn duration '%s' requires -deep, burn disabled", c.DeepBurnDuration),
+				fmt.Sprintf("深度烤机时长 '%s' 需要 -deep，已禁用烤机", c.DeepBurnDuration))
+		}
 		c.DeepDiskPaths = ""
 		c.DeepSMARTDevices = ""
 		c.DeepBurnDuration = 0
 		c.DeepGPUDevice = ""
-	} else if c.DeepBurnDuration < 0 || c.DeepBurnDuration > c.HardwareBudget {
+	} else if c.DeepBurnDuration < 0 {
+		issues.reject("deep-burn-duration", c.DeepBurnDuration, c.HardwareBudget.String(),
+			fmt.Sprintf("Invalid deep burn duration '%s', using %s", c.DeepBurnDuration, c.HardwareBudget),
+			fmt.Sprintf("深度烤机时长 '%s' 无效，使用 %s", c.DeepBurnDuration, c.HardwareBudget))
+		c.DeepBurnDuration = c.HardwareBudget
+	} else if c.DeepBurnDuration > c.HardwareBudget {
+		issues.adjust("deep-burn-duration", c.DeepBurnDuration, c.HardwareBudget.String(),
+			fmt.Sprintf("Deep burn duration '%s' exceeds the hardware budget, using %s", c.DeepBurnDuration, c.HardwareBudget),
+			fmt.Sprintf("深度烤机时长 '%s' 超过硬件测试预算，使用 %s", c.DeepBurnDuration, c.HardwareBudget))
 		c.DeepBurnDuration = c.HardwareBudget
 	}
+	c.UploadEndpoint = strings.TrimSpace(c.UploadEndpoint)
+	c.UploadResultField = strings.TrimSpace(c.UploadResultField)
+	c.UploadFormat = strings.ToLower(strings.TrimSpace(c.UploadFormat))
+	// The endpoint is kept when upload is disabled, so it is only checked
+	// while it would still be used and each correction is reported once.
+	if c.UploadEndpoint != "" && c.EnableUpload {
+		if endpoint, err := url.Parse(c.UploadEndpoint); err != nil || (endpoint.Scheme != "http" && endpoint.Scheme != "https") || endpoint.Host == "" {
+			// Disable sharing rather than fall back to the public paste
+			// service the collector was configured to replace.
+			issues.reject("upload-url", c.UploadEndpoint, "",
+				fmt.Sprintf("Invalid upload URL '%s', upload disabled", c.UploadEndpoint),
+				fmt.Sprintf("上传地址 '%s' 无效，已禁用上传", c.UploadEndpoint))
+			c.EnableUpload = false
+		}
+	}
+	validUploadFormats := map[string]bool{"text": true, "json": true, "both": true}
+	if !validUploadFormats[c.UploadFormat] {
+		issues.reject("upload-format", c.UploadFormat, "text",
+			fmt.Sprintf("Invalid upload format '%s', using default 'text'", c.UploadFormat),
+			fmt.Sprintf("上传格式 '%s' 无效，使用默认值 'text'", c.UploadFormat))
+		c.UploadFormat = "text"
+	} else if c.UploadFormat != "text" && c.UploadEndpoint == "" {
+		issues.adjust("upload-format", c.UploadFormat, "text",
+			fmt.Sprintf("Upload format '%s' requires -upload-url, using 'text'", c.UploadFormat),
+			fmt.Sprintf("上传格式 '%s' 需要 -upload-url，使用 'text'", c.UploadFormat))
+		c.UploadFormat = "text"
+	}
 	if c.PrivacyMode {
 		c.EnableUpload = false
 	}
+	if c.RepeatInterval < 0 {
+		c.RepeatInterval = 0
+	}
+	if c.RepeatInterval > 0 {
+		c.EnableUpload = false
+		if c.RepeatInterval < time.Minute {
+			issues.adjust("repeat", c.RepeatInterval, "1m0s",
+				fmt.Sprintf("Repeat interval '%s' is too short, using minimum 1m", c.RepeatInterval),
+				fmt.Sprintf("重复间隔 '%s' 过短，使用最小值 1m", c.RepeatInterval))
+			c.RepeatInterval = time.Minute
+		}
+	}
+	if c.HistoryMaxMB <= 0 {
+		issues.reject("history-max-mb", c.HistoryMaxMB, "256",
+			fmt.Sprintf("Invalid history size limit '%d', using default 256", c.HistoryMaxMB),
+			fmt.Sprintf("历史记录大小上限 '%d' 无效，使用默认值 256", c.HistoryMaxMB))
+		c.HistoryMaxMB = 256
+	}
 
 	validCpuMethods := map[string]bool{"sysbench": true, "geekbench": true, "winsat": true}
 	if !validCpuMethods[c.CpuTestMethod] {
-		if c.Language == "zh" {
-			fmt.Printf("警告: CPU测试方法 '%s' 无效，使用默认值 'sysbench'\n", c.CpuTestMethod)
-		} else {
-			fmt.Printf("Warning: Invalid CPU test method '%s', using default 'sysbench'\n", c.CpuTestMethod)
-		}
+		issues.reject("cpum", c.CpuTestMethod, "sysbench",
+			fmt.Sprintf("Invalid CPU test method '%s', using default 'sysbench'", c.CpuTestMethod),
+			fmt.Sprintf("CPU测试方法 '%s' 无效，使用默认值 'sysbench'", c.CpuTestMethod))
 		c.CpuTestMethod = "sysbench"
 	}
 
 	validThreadModes := map[string]bool{"single": true, "multi": true}
 	if !validThreadModes[c.CpuTestThreadMode] {
-		if c.Language == "zh" {
-			fmt.Printf("警告: CPU线程模式 '%s' 无效，使用默认值 'multi'\n", c.CpuTestThreadMode)
-		} else {
-			fmt.Printf("Warning: Invalid CPU thread mode '%s', using default 'multi'\n", c.CpuTestThreadMode)
-		}
+		issues.reject("cput", c.CpuTestThreadMode, "multi",
+			fmt.Sprintf("Invalid CPU thread mode '%s', using default 'multi'", c.CpuTestThreadMode),
+			fmt.Sprintf("CPU线程模式 '%s' 无效，使用默认值 'multi'", c.CpuTestThreadMode))
 		c.CpuTestThreadMode = "multi"
 	}
 
 	validMemoryMethods := map[string]bool{"stream": true, "sysbench": true, "dd": true, "winsat": true, "auto": true}
 	if !validMemoryMethods[c.MemoryTestMethod] {
-		if c.Language == "zh" {
-			fmt.Printf("警告: 内存测试方法 '%s' 无效，使用默认值 'stream'\n", c.MemoryTestMethod)
-		} else {
-			fmt.Printf("Warning: Invalid memory test method '%s', using default 'stream'\n", c.MemoryTestMethod)
-		}
+		issues.reject("memorym", c.MemoryTestMethod, "stream",
+			fmt.Sprintf("Invalid memory test method '%s', using default 'stream'", c.MemoryTestMethod),
+			fmt.Sprintf("内存测试方法 '%s' 无效，使用默认值 'stream'", c.MemoryTestMethod))
 		c.MemoryTestMethod = "stream"
 	}
 
 	validDiskMethods := map[string]bool{"fio": true, "dd": true, "winsat": true}
 	if !validDiskMethods[c.DiskTestMethod] {
-		if c.Language == "zh" {
-			fmt.Printf("警告: 磁盘测试方法 '%s' 无效，使用默认值 'fio'\n", c.DiskTestMethod)
-		} else {
-			fmt.Printf("Warning: Invalid disk test method '%s', using default 'fio'\n", c.DiskTestMethod)
-		}
+		issues.reject("diskm", c.DiskTestMethod, "fio",
+			fmt.Sprintf("Invalid disk test method '%s', using default 'fio'", c.DiskTestMethod),
+			fmt.Sprintf("磁盘测试方法 '%s' 无效，使用默认值 'fio'", c.DiskTestMethod))
 		c.DiskTestMethod = "fio"
 	}
 
 	validNt3Locations := map[string]bool{"GZ": true, "SH": true, "BJ": true, "CD": true, "ALL": true}
 	if !validNt3Locations[c.Nt3Location] {
-		if c.Language == "zh" {
-			fmt.Printf("警告: NT3测试位置 '%s' 无效，使用默认值 'GZ'\n", c.Nt3Location)
-		} else {
-			fmt.Printf("Warning: Invalid NT3 location '%s', using default 'GZ'\n", c.Nt3Location)
-		}
+		issues.reject("nt3loc", c.Nt3Location, "GZ",
+			fmt.Sprintf("Invalid NT3 location '%s', using default 'GZ'", c.Nt3Location),
+			fmt.Sprintf("NT3测试位置 '%s' 无效，使用默认值 'GZ'", c.Nt3Location))
 		c.Nt3Location = "GZ"
 	}
 
 	validNt3Types := map[string]bool{"both": true, "ipv4": true, "ipv6": true}
 	if !validNt3Types[c.Nt3CheckType] {
-		if c.Language == "zh" {
-			fmt.Printf("警告: NT3测试类型 '%s' 无效，使用默认值 'ipv4'\n", c.Nt3CheckType)
-		} else {
-			fmt.Printf("Warning: Invalid NT3 check type '%s', using default 'ipv4'\n", c.Nt3CheckType)
-		}
+		issues.reject("nt3t", c.Nt3CheckType, "ipv4",
+			fmt.Sprintf("Invalid NT3 check type '%s', using default 'ipv4'", c.Nt3CheckType),
+			fmt.Sprintf("NT3测试类型 '%s' 无效，使用默认值 'ipv4'", c.Nt3CheckType))
 		c.Nt3CheckType = "ipv4"
 	}
 
 	if c.SpNum <= 0 {
-		if c.Language == "zh" {
-			fmt.Printf("警告: 测速节点数量 '%d' 无效，使用默认值 2\n", c.SpNum)
-		} else {
-			fmt.Printf("Warning: Invalid speed test node count '%d', using default 2\n", c.SpNum)
-		}
+		issues.reject("spnum", c.SpNum, "2",
+			fmt.Sprintf("Invalid speed test node count '%d', using default 2", c.SpNum),
+			fmt.Sprintf("测速节点数量 '%d' 无效，使用默认值 2", c.SpNum))
 		c.SpNum = 2
 	}
 
 	validLanguages := map[string]bool{"zh": true, "en": true}
 	if !validLanguages[c.Language] {
-		fmt.Printf("Warning: Invalid language '%s', using default 'zh'\n", c.Language)
+		issues.reject("lang", c.Language, "zh",
+			fmt.Sprintf("Invalid language '%s', using default 'zh'", c.Language), "")
 		c.Language = "zh"
 	}
 
@@ -699,27 +990,44 @@ func (c *Config) ValidateParams() {
 		"20": true, "21": true,
 	}
 	if !validUnlockRegions[c.UnlockTestRegion] {
-		if c.Language == "zh" {
-			fmt.Printf("警告: 解锁测试地区 '%s' 无效，使用默认值 '0'\n", c.UnlockTestRegion)
-		} else {
-			fmt.Printf("Warning: Invalid unlock test region '%s', using default '0'\n", c.UnlockTestRegion)
-		}
+		issues.reject("utregion", c.UnlockTestRegion, "0",
+			fmt.Sprintf("Invalid unlock test region '%s', using default '0'", c.UnlockTestRegion),
+			fmt.Sprintf("解锁测试地区 '%s' 无效，使用默认值 '0'", c.UnlockTestRegion))
 		c.UnlockTestRegion = "0"
 	}
 
 	validIPVersions := map[string]bool{"auto": true, "ipv4": true, "ipv6": true}
 	if !validIPVersions[c.UnlockTestIPVersion] {
-		if c.Language == "zh" {
-			fmt.Printf("警告: 解锁测试IP版本 '%s' 无效，使用默认值 'auto'\n", c.UnlockTestIPVersion)
-		} else {
-			fmt.Printf("Warning: Invalid unlock test IP version '%s', using default 'auto'\n", c.UnlockTestIPVersion)
-		}
+		issues.reject("utipver", c.UnlockTestIPVersion, "auto",
+			fmt.Sprintf("Invalid unlock test IP version '%s', using default 'auto'", c.UnlockTestIPVersion),
+			fmt.Sprintf("解锁测试IP版本 '%s' 无效，使用默认值 'auto'", c.UnlockTestIPVersion))
 		c.UnlockTestIPVersion = "auto"
 	}
 	if c.UnlockTestConcurrency <= 0 {
 		c.UnlockTestConcurrency = 20
 	}
 	if c.UnlockTestConcurrency > 100 {
+		issues.adjust("ut-concurrency", c.UnlockTestConcurrency, "100",
+			fmt.Sprintf("Unlock test concurrency '%d' is too high, using maximum 100", c.UnlockTestConcurrency),
+			fmt.Sprintf("解锁测试并发数 '%d' 过高，使用最大值 100", c.UnlockTestConcurrency))
 		c.UnlockTestConcurrency = 100
 	}
+	c.FailOn = strings.ToLower(strings.TrimSpace(c.FailOn))
+	validFailOn := map[string]bool{"partial": true, "timeout": true, "error": true}
+	if !validFailOn[c.FailOn] {
+		issues.reject("fail-on", c.FailOn, "timeout",
+			fmt.Sprintf("Invalid fail-on status '%s', using default 'timeout'", c.FailOn),
+			fmt.Sprintf("失败阈值 '%s' 无效，使用默认值 'timeout'", c.FailOn))
+		c.FailOn = "timeout"
+	}
+	c.AssertPreset = strings.ToLower(strings.TrimSpace(c.AssertPreset))
+	validAssertPresets := map[string]bool{"": true, "baseline": true}
+	if !validAssertPresets[c.AssertPreset] {
+		issues.reject("assert-preset", c.AssertPreset, "",
+			fmt.Sprintf("Invalid assert preset '%s', no preset applied", c.AssertPreset),
+			fmt.Sprintf("断言预设 '%s' 无效，不使用预设", c.AssertPreset))
+		c.AssertPreset = ""
+	}
+	c.ConfigWarnings = append(c.ConfigWarnings, issues...)
+	return issues
 }
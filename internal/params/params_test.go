@@ -163,3 +163,92 @@ func TestParseFlagsAcceptsExplicitDeepTargets(t *testing.T) {
 		t.Fatalf("explicit deep flags not retained: %+v", cfg)
 	}
 }
+
+func TestRepeatModeDisablesUploadAndEnforcesMinimumInterval(t *testing.T) {
+	cfg := NewConfig("test")
+	cfg.ParseFlags([]string{"-l", "en", "-repeat", "10s", "-history-dir", " history ", "-history-max-mb", "0"})
+	if cfg.RepeatInterval != time.Minute || cfg.EnableUpload {
+		t.Fatalf("repeat mode = %s upload=%v, want 1m without upload", cfg.RepeatInterval, cfg.EnableUpload)
+	}
+	if cfg.HistoryDir != "history" || cfg.HistoryMaxMB != 256 {
+		t.Fatalf("history settings = %q %d", cfg.HistoryDir, cfg.HistoryMaxMB)
+	}
+}
+
+func TestCustomTCPTargetsEnableTCPSection(t *testing.T) {
+	cfg := NewConfig("test")
+	cfg.ParseFlags([]string{"-tcp-target", " db=db.test:5432 ", "-tcp-target", "cache=cache.test:6379"})
+	if !cfg.TCPProbeStatus || len(cfg.TCPTargets) != 2 || cfg.TCPTargets[0] != "db=db.test:5432" {
+		t.Fatalf("custom TCP targets = %q enabled=%v", cfg.TCPTargets, cfg.TCPProbeStatus)
+	}
+	cfg.ParseFlags([]string{"-tcp-targets", " targets.json "})
+	if len(cfg.TCPTargets) != 0 || cfg.TCPTargetsFile != "targets.json" || !cfg.TCPProbeStatus {
+		t.Fatalf("repeat parse kept inline targets or dropped the file: %q %q", cfg.TCPTargets, cfg.TCPTargetsFile)
+	}
+}
+
+func TestTCPProbeSettingsAreBounded(t *testing.T) {
+	cfg := NewConfig("test")
+	cfg.ParseFlags([]string{"-l", "en", "-tcp-attempts", "5000", "-tcp-timeout", "0", "-tcp-concurrency", "-1", "-tcp-interval", "-1s"})
+	if cfg.TCPAttempts != 1000 || cfg.TCPTimeout != 3*time.Second || cfg.TCPConcurrency != 16 || cfg.TCPInterval != 0 {
+		t.Fatalf("TCP settings = %d %s %d %s", cfg.TCPAttempts, cfg.TCPTimeout, cfg.TCPConcurrency, cfg.TCPInterval)
+	}
+	cfg.ParseFlags([]string{"-tcp-attempts", "10", "-tcp-timeout", "1m", "-tcp-concurrency", "4", "-tcp-interval", "200ms"})
+	if cfg.TCPAttempts != 10 || cfg.TCPTimeout != 30*time.Second || cfg.TCPConcurrency != 4 || cfg.TCPInterval != 200*time.Millisecond {
+		t.Fatalf("TCP settings = %d %s %d %s", cfg.TCPAttempts, cfg.TCPTimeout, cfg.TCPConcurrency, cfg.TCPInterval)
+	}
+}
+
+func TestTLSProbeEnablesTCPSection(t *testing.T) {
+	cfg := NewConfig("test")
+	cfg.ParseFlags([]string{"-tls", "true"})
+	if !cfg.TLSProbeStatus || !cfg.TCPProbeStatus {
+		t.Fatalf("-tls = %v, TCP = %v", cfg.TLSProbeStatus, cfg.TCPProbeStatus)
+	}
+}
+
+func TestQUICTestIsExplicit(t *testing.T) {
+	cfg := NewConfig("test")
+	if cfg.QUICTestStatus {
+		t.Fatal("QUIC reachability changed the established default suite")
+	}
+	cfg.ParseFlags([]string{"-quic"})
+	if !cfg.QUICTestStatus {
+		t.Fatal("explicit -quic did not enable the QUIC section")
+	}
+}
+
+func TestUploadEndpointSettingsAreValidated(t *testing.T) {
+	cfg := NewConfig("test")
+	cfg.ParseFlags([]string{"-l", "en", "-upload-url", " https://collector.test/api/results ", "-upload-format", "BOTH", "-upload-result-field", " data.url "})
+	if !cfg.EnableUpload || cfg.UploadEndpoint != "https://collector.test/api/results" || cfg.UploadFormat != "both" || cfg.UploadResultField != "data.url" {
+		t.Fatalf("upload settings = %v %q %q %q", cfg.EnableUpload, cfg.UploadEndpoint, cfg.UploadFormat, cfg.UploadResultField)
+	}
+	cfg.ParseFlags([]string{"-l", "en", "-upload-url", "collector.test/results"})
+	if cfg.EnableUpload {
+		t.Fatal("invalid collector URL must disable upload instead of using the public paste service")
+	}
+	cfg.ParseFlags([]string{"-l", "en", "-upload-format", "json"})
+	if cfg.UploadFormat != "text" {
+		t.Fatalf("JSON upload without a collector = %q, want text", cfg.UploadFormat)
+	}
+	cfg.ParseFlags([]string{"-l", "en", "-upload-url", "http://collector.test", "-upload-format", "yaml"})
+	if cfg.UploadFormat != "text" {
+		t.Fatalf("invalid upload format = %q, want text", cfg.UploadFormat)
+	}
+}
+
+func TestDataCacheSettings(t *testing.T) {
+	cfg := NewConfig("test")
+	if cfg.DataCacheMaxAge <= 0 {
+		t.Fatalf("default data cache max-age = %s", cfg.DataCacheMaxAge)
+	}
+	cfg.ParseFlags([]string{"-data-cache", " /var/cache/goecs ", "-data-cache-max-age", "-1m"})
+	if cfg.DataCacheDir != "/var/cache/goecs" || cfg.DataCacheMaxAge != 0 {
+		t.Fatalf("data cache = %q %s", cfg.DataCacheDir, cfg.DataCacheMaxAge)
+	}
+	cfg.ParseFlags([]string{"-data-cache="})
+	if cfg.DataCacheDir != "" {
+		t.Fatalf("empty -data-cache did not disable the cache: %q", cfg.DataCacheDir)
+	}
+}
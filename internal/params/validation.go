@@ -0,0 +1,72 @@
+package params
+
+import (
+	"fmt"
+	"io"
+	"strings"
+)
+
+// Severities of a ConfigIssue. An error is a value that was rejected and
+// replaced; a warning is a valid value that was adjusted to a limit or to
+// the other settings.
+const (
+	IssueError   = "error"
+	IssueWarning = "warning"
+)
+
+// ConfigIssue is one correction ValidateParams applied to a value.
+type ConfigIssue struct {
+	Flag     string `json:"flag"`
+	Value    string `json:"value"`
+	Applied  string `json:"applied"`
+	Severity string `json:"severity"`
+	Message  string `json:"message"`
+	// messageZH is the Chinese message printed for -lang zh.
+	messageZH string
+}
+
+// ConfigIssues lists the corrections of one or more validations. As an
+// error it reads as the joined English messages.
+type ConfigIssues []ConfigIssue
+
+func (issues ConfigIssues) Error() string {
+	messages := make([]string, len(issues))
+	for index, issue := range issues {
+		messages[index] = issue.Message
+	}
+	return "invalid configuration: " + strings.Join(messages, "; ")
+}
+
+// Err returns issues as an error, or nil when there are none.
+func (issues ConfigIssues) Err() error {
+	if len(issues) == 0 {
+		return nil
+	}
+	return issues
+}
+
+// Print writes one warning line per issue in lang.
+func (issues ConfigIssues) Print(w io.Writer, lang string) {
+	for _, issue := range issues {
+		if lang == "zh" && issue.messageZH != "" {
+			fmt.Fprintf(w, "警告: %s\n", issue.messageZH)
+		} else {
+			fmt.Fprintf(w, "Warning: %s\n", issue.Message)
+		}
+	}
+}
+
+func (issues *ConfigIssues) reject(flag string, value any, applied string, message, messageZH string) {
+	issues.add(IssueError, flag, value, applied, message, messageZH)
+}
+
+func (issues *ConfigIssues) adjust(flag string, value any, applied string, message, messageZH string) {
+	issues.add(IssueWarning, flag, value, applied, message, messageZH)
+}
+
+func (issues *ConfigIssues) add(severity, flag string, value any, applied string, message, messageZH string) {
+	*issues = append(*issues, ConfigIssue{
+		Flag: flag, Value: fmt.Sprint(value), Applied: applied, Severity: severity,
+		Message: message, messageZH: messageZH,
+	})
+}
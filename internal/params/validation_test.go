@@ -0,0 +1,86 @@
+package params
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestValidateParamsReturnsCorrections(t *testing.T) {
+	cfg := NewConfig("test")
+	cfg.CpuTestMethod, cfg.TCPAttempts, cfg.RepeatInterval = "bogus", 5000, time.Second
+	cfg.FailOn = "sometimes"
+	issues := cfg.ValidateParams()
+	if len(issues) != 4 || issues.Err() == nil {
+		t.Fatalf("unexpected issues: %#v", issues)
+	}
+	want := []ConfigIssue{
+		{Flag: "tcp-attempts", Value: "5000", Applied: "1000", Severity: IssueWarning},
+		{Flag: "repeat", Value: "1s", Applied: "1m0s", Severity: IssueWarning},
+		{Flag: "cpum", Value: "bogus", Applied: "sysbench", Severity: IssueError},
+		{Flag: "fail-on", Value: "sometimes", Applied: "timeout", Severity: IssueError},
+	}
+	for index, issue := range issues {
+		issue.Message, issue.messageZH = "", ""
+		if issue != want[index] {
+			t.Fatalf("issue %d = %#v, want %#v", index, issue, want[index])
+		}
+	}
+	if len(cfg.ConfigWarnings) != 4 || cfg.ValidateParams().Err() != nil {
+		t.Fatalf("corrections were not recorded once: %#v", cfg.ConfigWarnings)
+	}
+
+	var zh, en bytes.Buffer
+	issues[2:3].Print(&zh, "zh")
+	issues[2:3].Print(&en, "en")
+	if zh.String() != "警告: CPU测试方法 'bogus' 无效，使用默认值 'sysbench'\n" || en.String() != "Warning: Invalid CPU test method 'bogus', using default 'sysbench'\n" {
+		t.Fatalf("unexpected output: %q %q", zh.String(), en.String())
+	}
+	if got := issues[2:3].Error(); got != "invalid configuration: Invalid CPU test method 'bogus', using default 'sysbench'" {
+		t.Fatalf("unexpected error text: %q", got)
+	}
+}
+
+func TestValidateParamsReportsClampedLimits(t *testing.T) {
+	cfg := NewConfig("test")
+	cfg.TCPTimeout, cfg.TCPConcurrency, cfg.TCPInterval = time.Minute, 1000, -time.Second
+	cfg.DataCacheMaxAge, cfg.MaxDuration, cfg.HardwareBudget = -time.Hour, time.Hour, 10*time.Minute
+	cfg.DeepBurnDuration, cfg.HistoryMaxMB, cfg.UnlockTestConcurrency = 30*time.Second, 0, 500
+	issues := cfg.ValidateParams()
+	want := []ConfigIssue{
+		{Flag: "tcp-timeout", Value: "1m0s", Applied: "30s", Severity: IssueWarning},
+		{Flag: "tcp-concurrency", Value: "1000", Applied: "256", Severity: IssueWarning},
+		{Flag: "tcp-interval", Value: "-1s", Applied: "0s", Severity: IssueError},
+		{Flag: "data-cache-max-age", Value: "-1h0m0s", Applied: "0s", Severity: IssueError},
+		{Flag: "timeout", Value: "1h0m0s", Applied: "15m0s", Severity: IssueWarning},
+		{Flag: "hardware-budget", Value: "10m0s", Applied: "2m0s", Severity: IssueWarning},
+		{Flag: "deep-burn-duration", Value: "30s", Applied: "0s", Severity: IssueWarning},
+		{Flag: "history-max-mb", Value: "0", Applied: "256", Severity: IssueError},
+		{Flag: "ut-concurrency", Value: "500", Applied: "100", Severity: IssueWarning},
+	}
+	if len(issues) != len(want) {
+		t.Fatalf("unexpected issues: %#v", issues)
+	}
+	for index, issue := range issues {
+		issue.Message, issue.messageZH = "", ""
+		if issue != want[index] {
+			t.Fatalf("issue %d = %#v, want %#v", index, issue, want[index])
+		}
+	}
+	if again := cfg.ValidateParams(); len(again) != 0 {
+		t.Fatalf("clamped values were reported again: %#v", again)
+	}
+
+	deep := NewConfig("test")
+	deep.DeepMode, deep.TCPAttempts = true, -1
+	deep.HardwareBudget, deep.DeepBurnDuration = -time.Minute, time.Hour
+	issues = deep.ValidateParams()
+	flags := make([]string, len(issues))
+	for index, issue := range issues {
+		flags[index] = issue.Flag + "/" + issue.Severity + "/" + issue.Applied
+	}
+	if got := strings.Join(flags, " "); got != "tcp-attempts/error/0 hardware-budget/error/15m0s deep-burn-duration/warning/15m0s" {
+		t.Fatalf("unexpected deep issues: %s", got)
+	}
+}
@@ -10,7 +10,6 @@ import (
 	"sync"
 	"time"
 
-	"github.com/oneclickvirt/ecs/internal/analysis"
 	"github.com/oneclickvirt/ecs/internal/params"
 	"github.com/oneclickvirt/ecs/internal/tests"
 	"github.com/oneclickvirt/ecs/utils"
@@ -583,13 +582,12 @@ func AppendTimeInfo(config *params.Config, output, tempOutput string, startTime
 	}, tempOutput, output)
 }
 
-// AppendAnalysisSummary appends a concise bilingual summary for easier interpretation.
-func AppendAnalysisSummary(config *params.Config, output, tempOutput string, outputMutex *sync.Mutex) string {
+// AppendAnalysisSummary prints and appends a concise summary produced by
+// internal/analysis.
+func AppendAnalysisSummary(summary, output, tempOutput string, outputMutex *sync.Mutex) string {
 	outputMutex.Lock()
 	defer outputMutex.Unlock()
-	finalOutput := output
 	return utils.PrintAndCapture(func() {
-		summary := analysis.GenerateSummary(config, finalOutput)
 		if strings.TrimSpace(summary) == "" {
 			return
 		}
@@ -618,7 +616,9 @@ func printTimeInfo(config *params.Config, minutes, seconds int, currentTime stri
 // Second Ctrl+C (or if cleanup takes > 30 s) → kill the process group so that
 // any child subprocess (stream, fio, dd, sysbench, geekbench …) is also
 // terminated immediately, then os.Exit(1).
-func HandleSignalInterrupt(ctx context.Context, cancel context.CancelFunc, sig chan os.Signal, config *params.Config, startTime *time.Time, output *string, tempOutput string, uploadDone chan bool, outputMutex *sync.Mutex) {
+//
+// A gracefully interrupted run exits with interruptCode.
+func HandleSignalInterrupt(ctx context.Context, cancel context.CancelFunc, sig chan os.Signal, config *params.Config, startTime *time.Time, output *string, tempOutput string, uploadDone chan bool, outputMutex *sync.Mutex, interruptCode int) {
 	select {
 	case <-sig:
 		// ── First Ctrl+C ────────────────────────────────────────────────────────
@@ -726,7 +726,7 @@ func HandleSignalInterrupt(ctx context.Context, cancel context.CancelFunc, sig c
 			fmt.Println("Press Enter to exit...")
 			fmt.Scanln()
 		}
-		os.Exit(0)
+		os.Exit(interruptCode)
 	case <-ctx.Done():
 		return
 	}
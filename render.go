@@ -0,0 +1,61 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	ecsapi "github.com/oneclickvirt/ecs/api"
+)
+
+// runRenderCommand implements "goecs render". It re-renders a saved JSON
+// report without running any test and returns the process exit code.
+func runRenderCommand(args []string) int {
+	flags := flag.NewFlagSet("goecs render", flag.ContinueOnError)
+	input := flags.String("in", "", "JSON report written by -json, or '-' for stdin")
+	format := flags.String("format", ecsapi.RenderFormatText, "Output format (supported: text, markdown, html)")
+	language := flags.String("lang", "zh", "Set language (supported: en, zh)")
+	output := flags.String("out", "", "Write the rendered report to this path instead of stdout")
+	if err := flags.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return 0
+		}
+		return 2
+	}
+	if *input == "" {
+		fmt.Fprintln(os.Stderr, "render: -in is required")
+		return 2
+	}
+	var report *ecsapi.StructuredReport
+	var err error
+	if *input == "-" {
+		var data []byte
+		if data, err = io.ReadAll(os.Stdin); err == nil {
+			report, err = ecsapi.DecodeStructuredReport(data)
+		}
+	} else {
+		report, err = ecsapi.LoadStructuredReport(*input)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "render: %v\n", err)
+		return 1
+	}
+	config := ecsapi.NewConfig(ecsVersion)
+	config.Language = *language
+	text, err := ecsapi.RenderStructuredReport(config, report, *format)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "render: %v\n", err)
+		return 2
+	}
+	if *output == "" {
+		fmt.Print(text)
+		return 0
+	}
+	if err := os.WriteFile(*output, []byte(text), 0o644); err != nil {
+		fmt.Fprintf(os.Stderr, "render: %v\n", err)
+		return 1
+	}
+	return 0
+}
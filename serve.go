@@ -0,0 +1,53 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	ecsapi "github.com/oneclickvirt/ecs/api"
+	"github.com/oneclickvirt/ecs/utils"
+)
+
+// runServeCommand implements "goecs serve". It returns the process exit code.
+func runServeCommand(args []string) int {
+	flags := flag.NewFlagSet("goecs serve", flag.ContinueOnError)
+	listen := flags.String("listen", "127.0.0.1:8080", "Address for the local REST and SSE API")
+	if err := flags.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return 0
+		}
+		return 2
+	}
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	preCheck := utils.CheckPublicAccess(3 * time.Second)
+	server := ecsapi.NewServer(preCheck, ecsVersion)
+	httpServer := &http.Server{Addr: *listen, Handler: server, ReadHeaderTimeout: 10 * time.Second}
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- httpServer.ListenAndServe()
+	}()
+	fmt.Fprintf(os.Stderr, "goecs API listening on http://%s\n", *listen)
+	select {
+	case err := <-errCh:
+		server.Close()
+		fmt.Fprintf(os.Stderr, "serve: %v\n", err)
+		return 1
+	case <-ctx.Done():
+	}
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	server.Close()
+	if err := httpServer.Shutdown(shutdownCtx); err != nil {
+		fmt.Fprintf(os.Stderr, "serve: %v\n", err)
+		return 1
+	}
+	return 0
+}